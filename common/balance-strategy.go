@@ -0,0 +1,53 @@
+package common
+
+import (
+	"encoding/json"
+	"one-api/common/logger"
+)
+
+const (
+	BalanceStrategyWeight   = "weight"   // 按渠道权重随机
+	BalanceStrategyAdaptive = "adaptive" // 根据渠道近期的成功率、首字时间自动调整权重
+)
+
+// BalanceStrategy 渠道负载均衡策略，优先级：模型 > 分组 > 默认
+type BalanceStrategy struct {
+	Default string            `json:"default"`
+	Groups  map[string]string `json:"groups,omitempty"`
+	Models  map[string]string `json:"models,omitempty"`
+}
+
+var ChannelBalanceStrategy = BalanceStrategy{
+	Default: BalanceStrategyWeight,
+}
+
+func ChannelBalanceStrategy2JSONString() string {
+	jsonBytes, err := json.Marshal(ChannelBalanceStrategy)
+	if err != nil {
+		logger.SysError("error marshalling channel balance strategy: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateChannelBalanceStrategyByJSONString(jsonStr string) error {
+	strategy := BalanceStrategy{}
+	if err := json.Unmarshal([]byte(jsonStr), &strategy); err != nil {
+		return err
+	}
+	ChannelBalanceStrategy = strategy
+	return nil
+}
+
+func GetBalanceStrategy(group, modelName string) string {
+	strategy := ChannelBalanceStrategy
+	if s, ok := strategy.Models[modelName]; ok && s != "" {
+		return s
+	}
+	if s, ok := strategy.Groups[group]; ok && s != "" {
+		return s
+	}
+	if strategy.Default == "" {
+		return BalanceStrategyWeight
+	}
+	return strategy.Default
+}
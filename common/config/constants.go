@@ -45,6 +45,11 @@ var RegisterEnabled = true
 var ChatCacheEnabled = false
 var ChatCacheExpireMinute = 5 // 5 Minute
 
+// 语义缓存，令牌需要同时开启缓存并设置相似度阈值
+var ChatCacheSemanticEnabled = false
+var ChatCacheEmbeddingModel = "text-embedding-3-small"
+var ChatCacheEmbeddingChannelId = 0 // 为 0 时按照用户分组选择渠道
+
 // mj
 var MjNotifyEnabled = false
 
@@ -99,6 +104,22 @@ var DisableTokenEncoders = false
 var RetryTimes = 0
 var DefaultChannelWeight = uint(1)
 var RetryCooldownSeconds = 5
+var ChannelQueueTimeoutSeconds = 30 // 渠道并发全部已满时排队等待的最长时间，0 为不排队
+
+// 批处理
+var BatchDiscountRatio = 1.0 // 批处理请求的计费倍率，1 为不打折
+var BatchMaxRequests = 1000  // 单个批处理任务最多包含的请求数
+
+// 渠道熔断
+var CircuitBreakerEnabled = false
+var CircuitBreakerFailureThreshold = 5  // 连续失败次数达到阈值后熔断
+var CircuitBreakerOpenSeconds = 60      // 熔断持续时间，之后进入半开状态
+var CircuitBreakerHalfOpenRatio = 0.1   // 半开状态下放行的流量比例
+var CircuitBreakerHalfOpenSuccesses = 3 // 半开状态下连续成功次数达到后恢复
+
+// 请求审计
+var AuditLogEnabled = false
+var AuditLogRetentionDays = 30
 
 var CFWorkerImageUrl = ""
 var CFWorkerImageKey = ""
@@ -252,6 +273,7 @@ const (
 	RelayModeAudioTranscription
 	RelayModeAudioTranslation
 	RelayModeSuno
+	RelayModeBatch
 )
 
 type ContextKey string
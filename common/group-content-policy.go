@@ -0,0 +1,95 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common/logger"
+	"regexp"
+	"strings"
+)
+
+const DefaultModerationModel = "text-moderation-latest"
+
+// ContentPolicy 分组的内容策略，请求发送到上游前检查
+type ContentPolicy struct {
+	Keywords            []string `json:"keywords,omitempty"`              // 关键词，不区分大小写
+	Patterns            []string `json:"patterns,omitempty"`              // 正则表达式
+	Moderation          bool     `json:"moderation,omitempty"`            // 调用审核接口检查
+	ModerationModel     string   `json:"moderation_model,omitempty"`      // 审核模型，为空时使用 text-moderation-latest
+	ModerationChannelId int      `json:"moderation_channel_id,omitempty"` // 审核渠道，为 0 时从分组中选择
+	FailClosed          bool     `json:"fail_closed,omitempty"`           // 审核接口出错时拒绝请求，默认放行
+
+	patterns []*regexp.Regexp
+}
+
+// GroupContentPolicy 分组的内容策略，未设置的分组不检查
+var GroupContentPolicy = map[string]*ContentPolicy{}
+
+func GroupContentPolicy2JSONString() string {
+	jsonBytes, err := json.Marshal(GroupContentPolicy)
+	if err != nil {
+		logger.SysError("error marshalling group content policy: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateGroupContentPolicyByJSONString(jsonStr string) error {
+	policies := make(map[string]*ContentPolicy)
+	if err := json.Unmarshal([]byte(jsonStr), &policies); err != nil {
+		return err
+	}
+
+	for group, policy := range policies {
+		if policy == nil {
+			delete(policies, group)
+			continue
+		}
+		if err := policy.compile(); err != nil {
+			return fmt.Errorf("分组 %s 的内容策略无效：%s", group, err.Error())
+		}
+	}
+
+	GroupContentPolicy = policies
+	return nil
+}
+
+func GetGroupContentPolicy(name string) *ContentPolicy {
+	return GroupContentPolicy[name]
+}
+
+func (p *ContentPolicy) compile() error {
+	p.patterns = make([]*regexp.Regexp, 0, len(p.Patterns))
+	for _, pattern := range p.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		p.patterns = append(p.patterns, re)
+	}
+	return nil
+}
+
+// MatchBlocklist 检查文本是否命中关键词或正则，返回命中的规则
+func (p *ContentPolicy) MatchBlocklist(text string) (string, bool) {
+	lowerText := strings.ToLower(text)
+	for _, keyword := range p.Keywords {
+		if keyword != "" && strings.Contains(lowerText, strings.ToLower(keyword)) {
+			return keyword, true
+		}
+	}
+
+	for _, re := range p.patterns {
+		if re.MatchString(text) {
+			return re.String(), true
+		}
+	}
+
+	return "", false
+}
+
+func (p *ContentPolicy) GetModerationModel() string {
+	if p.ModerationModel == "" {
+		return DefaultModerationModel
+	}
+	return p.ModerationModel
+}
@@ -0,0 +1,26 @@
+package common
+
+import (
+	"encoding/json"
+	"one-api/common/logger"
+)
+
+// GroupHedgeDelay 分组的对冲请求等待时间，单位毫秒，未设置或为 0 表示不开启
+var GroupHedgeDelay = map[string]int{}
+
+func GroupHedgeDelay2JSONString() string {
+	jsonBytes, err := json.Marshal(GroupHedgeDelay)
+	if err != nil {
+		logger.SysError("error marshalling group hedge delay: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateGroupHedgeDelayByJSONString(jsonStr string) error {
+	GroupHedgeDelay = make(map[string]int)
+	return json.Unmarshal([]byte(jsonStr), &GroupHedgeDelay)
+}
+
+func GetGroupHedgeDelay(name string) int {
+	return GroupHedgeDelay[name]
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"encoding/json"
+	"one-api/common/logger"
+)
+
+// GroupModelFallback 分组的模型降级链，group -> model -> 按顺序尝试的模型
+// 请求的模型在所有渠道都失败后，依次使用降级链中的模型
+var GroupModelFallback = map[string]map[string][]string{}
+
+func GroupModelFallback2JSONString() string {
+	jsonBytes, err := json.Marshal(GroupModelFallback)
+	if err != nil {
+		logger.SysError("error marshalling group model fallback: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateGroupModelFallbackByJSONString(jsonStr string) error {
+	GroupModelFallback = make(map[string]map[string][]string)
+	return json.Unmarshal([]byte(jsonStr), &GroupModelFallback)
+}
+
+// GetModelFallbacks 获取模型的降级链，不包含模型本身和重复的模型
+func GetModelFallbacks(group, modelName string) []string {
+	chain := GroupModelFallback[group][modelName]
+	if len(chain) == 0 {
+		return nil
+	}
+
+	seen := map[string]bool{modelName: true}
+	fallbacks := make([]string, 0, len(chain))
+	for _, fallback := range chain {
+		if fallback == "" || seen[fallback] {
+			continue
+		}
+		seen[fallback] = true
+		fallbacks = append(fallbacks, fallback)
+	}
+
+	return fallbacks
+}
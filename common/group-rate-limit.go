@@ -0,0 +1,33 @@
+package common
+
+import (
+	"encoding/json"
+	"one-api/common/logger"
+)
+
+// RelayRateLimit 中继请求的速率限制，0 表示不限制
+type RelayRateLimit struct {
+	RPM     int `json:"rpm"`      // 分组内所有用户每分钟的请求数合计
+	TPM     int `json:"tpm"`      // 分组内所有用户每分钟的 tokens 合计
+	UserRPM int `json:"user_rpm"` // 分组内单个用户每分钟的请求数
+	UserTPM int `json:"user_tpm"` // 分组内单个用户每分钟的 tokens
+}
+
+var GroupRateLimit = map[string]RelayRateLimit{}
+
+func GroupRateLimit2JSONString() string {
+	jsonBytes, err := json.Marshal(GroupRateLimit)
+	if err != nil {
+		logger.SysError("error marshalling group rate limit: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateGroupRateLimitByJSONString(jsonStr string) error {
+	GroupRateLimit = make(map[string]RelayRateLimit)
+	return json.Unmarshal([]byte(jsonStr), &GroupRateLimit)
+}
+
+func GetGroupRateLimit(name string) RelayRateLimit {
+	return GroupRateLimit[name]
+}
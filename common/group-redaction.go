@@ -0,0 +1,126 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common/logger"
+	"regexp"
+)
+
+// 内置的脱敏类型
+const (
+	RedactionEmail  = "email"
+	RedactionPhone  = "phone"
+	RedactionIdCard = "id_card"
+	RedactionAPIKey = "api_key"
+)
+
+var RedactionEntities = []string{RedactionAPIKey, RedactionEmail, RedactionIdCard, RedactionPhone}
+
+var redactionRuleNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// RedactionSetting 请求内容脱敏设置，命中的内容替换为占位符后再发送到上游
+type RedactionSetting struct {
+	Entities []string        `json:"entities,omitempty"` // 内置的脱敏类型：email、phone、id_card、api_key
+	Rules    []RedactionRule `json:"rules,omitempty"`    // 自定义正则规则
+	Restore  bool            `json:"restore,omitempty"`  // 在响应中还原占位符
+}
+
+type RedactionRule struct {
+	Name    string `json:"name"` // 占位符名称，只能包含字母、数字和下划线
+	Pattern string `json:"pattern"`
+}
+
+// GroupRedaction 分组的脱敏设置，令牌设置只能在其基础上增加脱敏内容
+var GroupRedaction = map[string]*RedactionSetting{}
+
+func GroupRedaction2JSONString() string {
+	jsonBytes, err := json.Marshal(GroupRedaction)
+	if err != nil {
+		logger.SysError("error marshalling group redaction: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateGroupRedactionByJSONString(jsonStr string) error {
+	settings := make(map[string]*RedactionSetting)
+	if err := json.Unmarshal([]byte(jsonStr), &settings); err != nil {
+		return err
+	}
+
+	for group, setting := range settings {
+		if err := setting.Validate(); err != nil {
+			return fmt.Errorf("分组 %s 的脱敏设置无效：%s", group, err.Error())
+		}
+	}
+
+	GroupRedaction = settings
+	return nil
+}
+
+func GetGroupRedaction(name string) *RedactionSetting {
+	return GroupRedaction[name]
+}
+
+// MergeRedactionSetting 合并分组和令牌的脱敏设置，分组的脱敏类型和规则始终生效
+// 是否还原占位符以令牌设置为准
+func MergeRedactionSetting(group, token *RedactionSetting) *RedactionSetting {
+	if !token.Enabled() {
+		return group
+	}
+	if !group.Enabled() {
+		return token
+	}
+
+	merged := &RedactionSetting{
+		Rules:   append(append([]RedactionRule{}, group.Rules...), token.Rules...),
+		Restore: token.Restore,
+	}
+	for _, entity := range RedactionEntities {
+		if hasRedactionEntity(group.Entities, entity) || hasRedactionEntity(token.Entities, entity) {
+			merged.Entities = append(merged.Entities, entity)
+		}
+	}
+
+	return merged
+}
+
+func hasRedactionEntity(entities []string, entity string) bool {
+	for _, item := range entities {
+		if item == entity {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *RedactionSetting) Enabled() bool {
+	return s != nil && (len(s.Entities) > 0 || len(s.Rules) > 0)
+}
+
+func (s *RedactionSetting) Validate() error {
+	if s == nil {
+		return nil
+	}
+
+	for _, entity := range s.Entities {
+		if !isRedactionEntity(entity) {
+			return fmt.Errorf("不支持的脱敏类型 %s", entity)
+		}
+	}
+
+	for _, rule := range s.Rules {
+		if !redactionRuleNameRegex.MatchString(rule.Name) {
+			return fmt.Errorf("脱敏规则名称 %s 无效，只能包含字母、数字和下划线", rule.Name)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("脱敏规则 %s 的正则表达式无效：%s", rule.Name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func isRedactionEntity(entity string) bool {
+	return hasRedactionEntity(RedactionEntities, entity)
+}
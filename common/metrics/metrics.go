@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/viper"
+)
+
+const namespace = "one_hub"
+
+var (
+	relayRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_requests_total",
+		Help:      "Upstream relay requests by group, model, channel and status code.",
+	}, []string{"group", "model", "channel", "status_code"})
+
+	upstreamLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_upstream_latency_seconds",
+		Help:      "Upstream request latency in seconds.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"model", "channel"})
+
+	firstTokenLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_first_token_seconds",
+		Help:      "Time to first token of upstream responses in seconds.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 10, 20, 30, 60},
+	}, []string{"model", "channel"})
+
+	relayRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_retries_total",
+		Help:      "Relay retries after upstream errors by group and model.",
+	}, []string{"group", "model"})
+
+	relayHedges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_hedges_total",
+		Help:      "Hedged relay requests by group, model and the winning attempt.",
+	}, []string{"group", "model", "winner"})
+
+	relayFallbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_fallbacks_total",
+		Help:      "Relay fallbacks to another model by group, requested model and fallback model.",
+	}, []string{"group", "model", "fallback_model"})
+
+	quotaConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quota_consumed_total",
+		Help:      "Quota consumed by group and model.",
+	}, []string{"group", "model"})
+
+	channelDisabled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "channel_disabled_total",
+		Help:      "Channel disable events by channel and action.",
+	}, []string{"channel", "action"})
+
+	cacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chat_cache_hits_total",
+		Help:      "Chat cache hits by model.",
+	}, []string{"model"})
+)
+
+func init() {
+	prometheus.MustRegister(relayRequests, upstreamLatency, firstTokenLatency, relayRetries, relayHedges, relayFallbacks, quotaConsumed, channelDisabled, cacheHits)
+}
+
+// 渠道被处理的方式
+const (
+	ChannelActionDisable     = "disable"      // 自动禁用渠道
+	ChannelActionDisableKey  = "disable_key"  // 禁用多密钥渠道中的密钥
+	ChannelActionCircuitOpen = "circuit_open" // 渠道熔断
+)
+
+// RecordRelayRequest 记录一次上游请求，statusCode 为 0 时表示成功
+func RecordRelayRequest(group, model string, channelId, statusCode int, latency, ttft time.Duration) {
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	channel := strconv.Itoa(channelId)
+	relayRequests.WithLabelValues(group, model, channel, strconv.Itoa(statusCode)).Inc()
+	upstreamLatency.WithLabelValues(model, channel).Observe(latency.Seconds())
+	if statusCode == http.StatusOK {
+		firstTokenLatency.WithLabelValues(model, channel).Observe(ttft.Seconds())
+	}
+}
+
+func RecordRelayRetry(group, model string) {
+	relayRetries.WithLabelValues(group, model).Inc()
+}
+
+// 对冲请求的胜出方
+const (
+	HedgeWinnerPrimary = "primary" // 首个渠道先响应
+	HedgeWinnerHedge   = "hedge"   // 对冲渠道先响应
+	HedgeWinnerNone    = "none"    // 全部失败
+)
+
+func RecordRelayHedge(group, model, winner string) {
+	relayHedges.WithLabelValues(group, model, winner).Inc()
+}
+
+func RecordRelayFallback(group, model, fallbackModel string) {
+	relayFallbacks.WithLabelValues(group, model, fallbackModel).Inc()
+}
+
+func RecordQuotaConsumed(group, model string, quota int) {
+	if quota <= 0 {
+		return
+	}
+	quotaConsumed.WithLabelValues(group, model).Add(float64(quota))
+}
+
+func RecordChannelDisabled(channelId int, action string) {
+	channelDisabled.WithLabelValues(strconv.Itoa(channelId), action).Inc()
+}
+
+func RecordCacheHit(model string) {
+	cacheHits.WithLabelValues(model).Inc()
+}
+
+// Handler 暴露 Prometheus 指标，配置了 metrics.token 时需要通过 Bearer Token 访问
+func Handler() gin.HandlerFunc {
+	handler := promhttp.Handler()
+	return func(c *gin.Context) {
+		token := viper.GetString("metrics.token")
+		if token != "" && c.GetHeader("Authorization") != "Bearer "+token {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
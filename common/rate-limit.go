@@ -7,15 +7,22 @@ import (
 
 type InMemoryRateLimiter struct {
 	store              map[string]*[]int64
+	counters           map[string]*rateCounter
 	mutex              sync.Mutex
 	expirationDuration time.Duration
 }
 
+type rateCounter struct {
+	windowStart int64
+	count       int64
+}
+
 func (l *InMemoryRateLimiter) Init(expirationDuration time.Duration) {
 	if l.store == nil {
 		l.mutex.Lock()
 		if l.store == nil {
 			l.store = make(map[string]*[]int64)
+			l.counters = make(map[string]*rateCounter)
 			l.expirationDuration = expirationDuration
 			if expirationDuration > 0 {
 				go l.clearExpiredItems()
@@ -37,6 +44,11 @@ func (l *InMemoryRateLimiter) clearExpiredItems() {
 				delete(l.store, key)
 			}
 		}
+		for key, counter := range l.counters {
+			if now-counter.windowStart > int64(l.expirationDuration.Seconds()) {
+				delete(l.counters, key)
+			}
+		}
 		l.mutex.Unlock()
 	}
 }
@@ -68,3 +80,33 @@ func (l *InMemoryRateLimiter) Request(key string, maxRequestNum int, duration in
 	}
 	return true
 }
+
+// Incr 固定窗口计数，返回累加后当前窗口内的计数以及窗口开始时间，duration 单位为秒
+func (l *InMemoryRateLimiter) Incr(key string, amount int64, duration int64) (count int64, windowStart int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	counter := l.getCounter(key, duration)
+	counter.count += amount
+	return counter.count, counter.windowStart
+}
+
+// Count 获取当前窗口内的计数以及窗口开始时间，duration 单位为秒
+func (l *InMemoryRateLimiter) Count(key string, duration int64) (count int64, windowStart int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	counter := l.getCounter(key, duration)
+	return counter.count, counter.windowStart
+}
+
+func (l *InMemoryRateLimiter) getCounter(key string, duration int64) *rateCounter {
+	windowStart := time.Now().Unix() / duration * duration
+	counter, ok := l.counters[key]
+	if !ok || counter.windowStart != windowStart {
+		counter = &rateCounter{windowStart: windowStart}
+		l.counters[key] = counter
+	}
+
+	return counter
+}
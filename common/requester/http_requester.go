@@ -9,12 +9,15 @@ import (
 	"io"
 	"net/http"
 	"one-api/common"
+	"one-api/common/tracing"
 	"one-api/common/utils"
 	"one-api/types"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type HttpErrorHandler func(*http.Response) *types.OpenAIError
@@ -73,7 +76,12 @@ func (r *HTTPRequester) NewRequest(method, url string, setters ...requestOption)
 }
 
 // 发送请求
-func (r *HTTPRequester) SendRequest(req *http.Request, response any, outputResp bool) (*http.Response, *types.OpenAIErrorWithStatusCode) {
+func (r *HTTPRequester) SendRequest(req *http.Request, response any, outputResp bool) (resp *http.Response, errWithCode *types.OpenAIErrorWithStatusCode) {
+	span := startRequestSpan(req, "HTTPRequester.SendRequest")
+	defer func() {
+		endRequestSpan(span, resp, errWithCode)
+	}()
+
 	resp, err := HTTPClient.Do(req)
 	if err != nil {
 		return nil, common.ErrorWrapper(err, "http_request_failed", http.StatusInternalServerError)
@@ -112,7 +120,12 @@ func (r *HTTPRequester) SendRequest(req *http.Request, response any, outputResp
 }
 
 // 发送请求 RAW
-func (r *HTTPRequester) SendRequestRaw(req *http.Request) (*http.Response, *types.OpenAIErrorWithStatusCode) {
+func (r *HTTPRequester) SendRequestRaw(req *http.Request) (resp *http.Response, errWithCode *types.OpenAIErrorWithStatusCode) {
+	span := startRequestSpan(req, "HTTPRequester.SendRequestRaw")
+	defer func() {
+		endRequestSpan(span, resp, errWithCode)
+	}()
+
 	// 发送请求
 	resp, err := HTTPClient.Do(req)
 	if err != nil {
@@ -127,6 +140,27 @@ func (r *HTTPRequester) SendRequestRaw(req *http.Request) (*http.Response, *type
 	return resp, nil
 }
 
+// 上游请求的 span，流式请求只统计到收到响应头为止
+func startRequestSpan(req *http.Request, name string) trace.Span {
+	_, span := tracing.Start(req.Context(), name,
+		attribute.String("http.method", req.Method),
+		attribute.String("server.address", req.URL.Host),
+	)
+	return span
+}
+
+func endRequestSpan(span trace.Span, resp *http.Response, errWithCode *types.OpenAIErrorWithStatusCode) {
+	if errWithCode != nil {
+		span.SetAttributes(attribute.Int("http.status_code", errWithCode.StatusCode))
+		tracing.End(span, errWithCode)
+		return
+	}
+	if resp != nil {
+		span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	}
+	tracing.End(span, nil)
+}
+
 // 获取流式响应
 func RequestStream[T streamable](requester *HTTPRequester, resp *http.Response, handlerPrefix HandlerPrefix[T]) (*streamReader[T], *types.OpenAIErrorWithStatusCode) {
 	// 如果返回的头是json格式 说明有错误
@@ -3,6 +3,8 @@ package drives
 import (
 	"bytes"
 	"fmt"
+	"io"
+
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 )
 
@@ -54,3 +56,45 @@ func (a *AliOSSUpload) Upload(data []byte, fileName string) (string, error) {
 
 	return objectURL, nil
 }
+
+func (a *AliOSSUpload) getBucket() (*oss.Bucket, error) {
+	client, err := oss.New(a.Endpoint, a.AccessKeyId, a.AccessKeySecret)
+	if err != nil {
+		return nil, fmt.Errorf("creating OSS client: %w", err)
+	}
+
+	bucket, err := client.Bucket(a.BucketName)
+	if err != nil {
+		return nil, fmt.Errorf("getting bucket: %w", err)
+	}
+
+	return bucket, nil
+}
+
+func (a *AliOSSUpload) Download(fileName string) ([]byte, error) {
+	bucket, err := a.getBucket()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := bucket.GetObject(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("downloading file: %w", err)
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+func (a *AliOSSUpload) Delete(fileName string) error {
+	bucket, err := a.getBucket()
+	if err != nil {
+		return err
+	}
+
+	if err := bucket.DeleteObject(fileName); err != nil {
+		return fmt.Errorf("deleting file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package drives
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage 保存到本地磁盘，多节点部署时需要使用共享目录
+type LocalStorage struct {
+	Path string
+}
+
+func NewLocalStorage(path string) *LocalStorage {
+	return &LocalStorage{
+		Path: path,
+	}
+}
+
+func (l *LocalStorage) Name() string {
+	return "Local"
+}
+
+// filePath 获取文件的完整路径，文件名不能跳出存储目录
+func (l *LocalStorage) filePath(fileName string) (string, error) {
+	root, err := filepath.Abs(l.Path)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(root, filepath.FromSlash(fileName))
+	if !strings.HasPrefix(path, root+string(filepath.Separator)) {
+		return "", errors.New("invalid file name")
+	}
+
+	return path, nil
+}
+
+func (l *LocalStorage) Upload(data []byte, fileName string) (string, error) {
+	path, err := l.filePath(fileName)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("creating directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("writing file: %w", err)
+	}
+
+	return path, nil
+}
+
+func (l *LocalStorage) Download(fileName string) ([]byte, error) {
+	path, err := l.filePath(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(path)
+}
+
+func (l *LocalStorage) Delete(fileName string) error {
+	path, err := l.filePath(fileName)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNoFileStorage = errors.New("未配置文件存储")
+
+// HasFileStorage 是否配置了可以读取和删除的文件存储
+func HasFileStorage() bool {
+	return len(storageDrives.fileDrives) > 0
+}
+
+// Save 保存文件，返回实际使用的存储名称，读取和删除时需要使用同一个存储
+func Save(data []byte, fileName string) (string, error) {
+	return storageDrives.Save(data, fileName)
+}
+
+func Download(driveName, fileName string) ([]byte, error) {
+	drive, err := storageDrives.getFileDrive(driveName)
+	if err != nil {
+		return nil, err
+	}
+
+	return drive.Download(fileName)
+}
+
+func Delete(driveName, fileName string) error {
+	drive, err := storageDrives.getFileDrive(driveName)
+	if err != nil {
+		return err
+	}
+
+	return drive.Delete(fileName)
+}
+
+func (s *Storage) Save(data []byte, fileName string) (string, error) {
+	if len(s.fileDrives) == 0 {
+		return "", ErrNoFileStorage
+	}
+
+	var err error
+	for _, drive := range s.fileDrives {
+		if _, err = drive.Upload(data, fileName); err == nil {
+			return drive.Name(), nil
+		}
+		err = fmt.Errorf("%s: %w", drive.Name(), err)
+	}
+
+	return "", err
+}
+
+func (s *Storage) getFileDrive(driveName string) (FileStorageDrive, error) {
+	for _, drive := range s.fileDrives {
+		if drive.Name() == driveName {
+			return drive, nil
+		}
+	}
+
+	return nil, fmt.Errorf("文件存储 %s 不可用", driveName)
+}
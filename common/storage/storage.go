@@ -7,13 +7,15 @@ import (
 )
 
 type Storage struct {
-	drives map[string]StorageDrive
+	drives     map[string]StorageDrive
+	fileDrives []FileStorageDrive
 }
 
 func InitStorage() {
 	InitImgurStorage()
 	InitSMStorage()
 	InitALIOSSStorage()
+	InitLocalStorage()
 }
 
 func InitALIOSSStorage() {
@@ -37,6 +39,17 @@ func InitALIOSSStorage() {
 
 	aliUpload := drives.NewAliOSSUpload(endpoint, accessKeyId, accessKeySecret, bucketName)
 	AddStorageDrive(aliUpload)
+	AddFileStorageDrive(aliUpload)
+}
+
+// InitLocalStorage 本地磁盘只保存上传的文件，不用于图片
+func InitLocalStorage() {
+	path := viper.GetString("storage.local.path")
+	if path == "" {
+		return
+	}
+
+	AddFileStorageDrive(drives.NewLocalStorage(path))
 }
 
 func InitSMStorage() {
@@ -7,6 +7,13 @@ type StorageDrive interface {
 	Name() string
 }
 
+// FileStorageDrive 支持读取和删除的存储，用于保存 /v1/files 上传的文件
+type FileStorageDrive interface {
+	StorageDrive
+	Download(fileName string) ([]byte, error)
+	Delete(fileName string) error
+}
+
 func New() *Storage {
 	storageDrive := &Storage{
 		drives: make(map[string]StorageDrive, 0),
@@ -15,6 +22,25 @@ func New() *Storage {
 	return storageDrive
 }
 
+// AddFileStorageDrive 添加文件存储，先添加的优先使用
+func AddFileStorageDrive(drives ...FileStorageDrive) {
+	for _, d := range drives {
+		storageDrives.addFileDrive(d)
+	}
+}
+
+func (s *Storage) addFileDrive(drive FileStorageDrive) {
+	if drive == nil {
+		return
+	}
+	for _, d := range s.fileDrives {
+		if d.Name() == drive.Name() {
+			return
+		}
+	}
+	s.fileDrives = append(s.fileDrives, drive)
+}
+
 func AddStorageDrive(drives ...StorageDrive) {
 	storageDrives.addDrives(drives...)
 }
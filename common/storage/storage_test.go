@@ -71,3 +71,21 @@ func TestImgurUpload(t *testing.T) {
 	fmt.Println(err)
 	assert.Nil(t, err)
 }
+
+func TestLocalStorage(t *testing.T) {
+	local := drives.NewLocalStorage(t.TempDir())
+
+	_, err := local.Upload([]byte("hello"), "files/file-abc")
+	assert.Nil(t, err)
+
+	data, err := local.Download("files/file-abc")
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	assert.Nil(t, local.Delete("files/file-abc"))
+	_, err = local.Download("files/file-abc")
+	assert.NotNil(t, err)
+
+	_, err = local.Upload([]byte("hello"), "../outside")
+	assert.NotNil(t, err)
+}
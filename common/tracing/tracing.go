@@ -0,0 +1,156 @@
+package tracing
+
+import (
+	"context"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName = "one-api"
+
+	AttrRequestId = attribute.Key("request_id")
+)
+
+var (
+	Enabled  = false
+	provider *sdktrace.TracerProvider
+)
+
+// InitTracing 根据配置初始化 OTLP 导出器，未开启时使用 otel 默认的空实现
+func InitTracing() {
+	if !viper.GetBool("tracing.enabled") {
+		return
+	}
+
+	exporter, err := newExporter()
+	if err != nil {
+		logger.SysError("failed to create tracing exporter: " + err.Error())
+		return
+	}
+
+	serviceName := viper.GetString("tracing.service_name")
+	if serviceName == "" {
+		serviceName = "one-hub"
+	}
+	res := resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(config.Version),
+	)
+
+	sampleRatio := 1.0
+	if viper.IsSet("tracing.sample_ratio") {
+		sampleRatio = viper.GetFloat64("tracing.sample_ratio")
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	Enabled = true
+	logger.SysLog("tracing enabled, exporting to " + viper.GetString("tracing.endpoint"))
+}
+
+func newExporter() (*otlptrace.Exporter, error) {
+	endpoint := viper.GetString("tracing.endpoint")
+	insecure := viper.GetBool("tracing.insecure")
+	headers := viper.GetStringMapString("tracing.headers")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if viper.GetString("tracing.protocol") == "grpc" {
+		options := []otlptracegrpc.Option{otlptracegrpc.WithHeaders(headers)}
+		if endpoint != "" {
+			options = append(options, otlptracegrpc.WithEndpoint(endpoint))
+		}
+		if insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, options...)
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithHeaders(headers)}
+	if endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpoint(endpoint))
+	}
+	if urlPath := viper.GetString("tracing.url_path"); urlPath != "" {
+		options = append(options, otlptracehttp.WithURLPath(urlPath))
+	}
+	if insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(ctx, options...)
+}
+
+// Shutdown 导出剩余的 span
+func Shutdown() {
+	if provider == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		logger.SysError("failed to shutdown tracing: " + err.Error())
+	}
+}
+
+// Start 创建一个子 span，并带上上下文中的请求 ID
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if requestId, ok := ctx.Value(logger.RequestIdKey).(string); ok && requestId != "" {
+		attrs = append(attrs, AttrRequestId.String(requestId))
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartGin 在 gin 的请求上下文中创建 span，在 end 之前的调用都会作为它的子 span
+// end 会恢复原来的请求上下文，err 不为空时标记 span 为失败
+func StartGin(c *gin.Context, name string, attrs ...attribute.KeyValue) (span trace.Span, end func(err error)) {
+	parent := c.Request.Context()
+	ctx, span := Start(parent, name, attrs...)
+	c.Request = c.Request.WithContext(ctx)
+
+	return span, func(err error) {
+		End(span, err)
+		c.Request = c.Request.WithContext(parent)
+	}
+}
+
+// End 结束 span，err 不为空时记录错误
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// WithSpan 将 from 中的 span 关联到 ctx 上，用于不继承请求上下文的上游请求
+func WithSpan(ctx context.Context, from context.Context) context.Context {
+	span := trace.SpanFromContext(from)
+	if !span.SpanContext().IsValid() {
+		return ctx
+	}
+	return trace.ContextWithSpan(ctx, span)
+}
@@ -0,0 +1,44 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"one-api/common/logger"
+	"one-api/common/tracing"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartGin(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), logger.RequestIdKey, "req-1"))
+	parent := c.Request.Context()
+
+	attempt, end := tracing.StartGin(c, "relay.attempt")
+	_, child := tracing.Start(c.Request.Context(), "ChannelGroup.Next")
+	tracing.End(child, nil)
+	end(errors.New("upstream error"))
+
+	assert.Equal(t, parent, c.Request.Context(), "end 之后应恢复原来的请求上下文")
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "ChannelGroup.Next", spans[0].Name)
+	assert.Equal(t, attempt.SpanContext().SpanID(), spans[0].Parent.SpanID())
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+
+	for _, span := range spans {
+		assert.Contains(t, span.Attributes, tracing.AttrRequestId.String("req-1"))
+	}
+}
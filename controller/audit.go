@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetAuditLogs(c *gin.Context) {
+	requestId := c.Param("request_id")
+	if requestId == "" {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("请求 ID 不能为空"))
+		return
+	}
+
+	logs, err := model.GetAuditLogsByRequestId(requestId)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if len(logs) == 0 {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("未找到该请求的审计日志"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    logs,
+	})
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/notify"
+	"one-api/model"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const circuitBreakerProbeInterval = 10 * time.Second
+
+func GetCircuitBreakers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    model.ChannelCircuitBreakers.List(),
+	})
+}
+
+func ResetCircuitBreaker(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	model.ChannelCircuitBreakers.Reset(id)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// TripChannel 熔断渠道，代替自动禁用，恢复由半开探测完成
+func TripChannel(channelId int, channelName string, reason string) {
+	model.ChannelCircuitBreakers.Trip(channelId)
+
+	subject := fmt.Sprintf("通道「%s」（#%d）已熔断", channelName, channelId)
+	content := fmt.Sprintf("通道「%s」（#%d）已熔断，%d 秒后开始探测恢复，原因：%s", channelName, channelId, config.CircuitBreakerOpenSeconds, reason)
+	notify.Send(subject, content)
+}
+
+// AutomaticallyProbeCircuitBreakers 定期对半开状态的渠道发送测试请求，成功后自动恢复
+func AutomaticallyProbeCircuitBreakers() {
+	for {
+		time.Sleep(circuitBreakerProbeInterval)
+		if !config.CircuitBreakerEnabled {
+			continue
+		}
+
+		for _, probe := range model.ChannelCircuitBreakers.StartProbes() {
+			probeCircuitBreaker(probe)
+		}
+	}
+}
+
+func probeCircuitBreaker(probe model.CircuitBreakerStatus) {
+	channel := model.ChannelGroup.GetChannel(probe.ChannelId)
+	if channel == nil {
+		// 渠道已被删除或禁用
+		model.ChannelCircuitBreakers.Reset(probe.ChannelId)
+		return
+	}
+
+	err, openaiErr := testChannel(channel, probe.Model)
+	if err == nil {
+		model.ChannelCircuitBreakers.FinishProbe(probe.ChannelId, probe.Model, true)
+		logger.SysLog(fmt.Sprintf("channel #%d(%s) model %s circuit breaker probe succeeded, closed", channel.Id, channel.Name, probe.Model))
+		if probe.Model == "" {
+			subject := fmt.Sprintf("通道「%s」（#%d）已恢复", channel.Name, channel.Id)
+			notify.Send(subject, subject)
+		}
+		return
+	}
+
+	// 渠道级别使用测速模型探测，失败说明渠道仍不可用，重新熔断
+	// 模型级别的探测固定使用对话接口，非对话模型可能误判，失败时交给实际流量判断
+	if probe.Model == "" && openaiErr != nil {
+		model.ChannelCircuitBreakers.FinishProbe(probe.ChannelId, probe.Model, false)
+	} else {
+		model.ChannelCircuitBreakers.CancelProbe(probe.ChannelId, probe.Model)
+	}
+	logger.SysLog(fmt.Sprintf("channel #%d(%s) model %s circuit breaker probe failed: %s", channel.Id, channel.Name, probe.Model, err.Error()))
+}
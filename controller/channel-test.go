@@ -66,6 +66,11 @@ func testChannel(channel *model.Channel, testModel string) (err error, openaiErr
 	response, openAIErrorWithStatusCode := chatProvider.CreateChatCompletion(request)
 
 	if openAIErrorWithStatusCode != nil {
+		// 多密钥渠道只禁用出错的密钥，仍有可用密钥时不影响渠道状态
+		keyChannel := provider.GetChannel()
+		if keyChannel.IsMultiKey() && ShouldDisableChannel(keyChannel.Type, openAIErrorWithStatusCode) && !DisableChannelKey(keyChannel, openAIErrorWithStatusCode) {
+			return errors.New(openAIErrorWithStatusCode.Message), nil
+		}
 		return errors.New(openAIErrorWithStatusCode.Message), openAIErrorWithStatusCode
 	}
 
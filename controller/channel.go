@@ -65,8 +65,16 @@ func AddChannel(c *gin.Context) {
 		})
 		return
 	}
+	if err := validateMultiKeyMode(channel.MultiKeyMode); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
 	channel.CreatedTime = utils.GetTimestamp()
 	keys := strings.Split(channel.Key, "\n")
+	// 多密钥渠道不拆分，所有密钥保存在同一个渠道中
+	if channel.IsMultiKey() {
+		keys = []string{strings.TrimSpace(channel.Key)}
+	}
 
 	baseUrls := []string{}
 	if channel.BaseURL != nil && *channel.BaseURL != "" {
@@ -164,6 +172,10 @@ func UpdateChannel(c *gin.Context) {
 		})
 		return
 	}
+	if err := validateMultiKeyMode(channel.MultiKeyMode); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
 	if channel.Models == "" {
 		err = channel.Update(false)
 	} else {
@@ -233,3 +245,58 @@ func BatchDelModelChannels(c *gin.Context) {
 		"message": "更新成功",
 	})
 }
+
+func validateMultiKeyMode(mode string) error {
+	switch mode {
+	case "", model.ChannelMultiKeyRoundRobin, model.ChannelMultiKeyRandom:
+		return nil
+	}
+	return errors.New("不支持的多密钥轮换方式")
+}
+
+func GetChannelKeys(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	channel, err := model.GetChannelById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    channel.GetKeyInfos(),
+	})
+}
+
+// ResetChannelKeys 恢复被禁用的密钥，未指定 index 时恢复全部密钥
+func ResetChannelKeys(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	index := -1
+	if c.Query("index") != "" {
+		index, err = strconv.Atoi(c.Query("index"))
+		if err != nil || index < 0 {
+			common.APIRespondWithError(c, http.StatusOK, errors.New("无效的密钥序号"))
+			return
+		}
+	}
+
+	if err := model.ResetChannelKeyStatus(id, index); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
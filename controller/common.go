@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/metrics"
 	"one-api/common/notify"
 	"one-api/model"
 	"one-api/types"
@@ -97,6 +99,7 @@ func ShouldDisableChannel(channelType int, err *types.OpenAIErrorWithStatusCode)
 // disable & notify
 func DisableChannel(channelId int, channelName string, reason string, sendNotify bool) {
 	model.UpdateChannelStatusById(channelId, config.ChannelStatusAutoDisabled)
+	metrics.RecordChannelDisabled(channelId, metrics.ChannelActionDisable)
 	if !sendNotify {
 		return
 	}
@@ -106,6 +109,27 @@ func DisableChannel(channelId int, channelName string, reason string, sendNotify
 	notify.Send(subject, content)
 }
 
+// DisableChannelKey 禁用多密钥渠道中出错的密钥，返回渠道是否已经没有可用的密钥
+func DisableChannelKey(channel *model.Channel, err *types.OpenAIErrorWithStatusCode) bool {
+	status := model.ChannelKeyStatusExhausted
+	if err.StatusCode == http.StatusUnauthorized || err.OpenAIError.Code == "invalid_api_key" || err.OpenAIError.Code == "account_deactivated" {
+		status = model.ChannelKeyStatusInvalid
+	}
+
+	allDisabled, disableErr := model.DisableChannelKey(channel.Id, channel.Key, status, err.Message)
+	if disableErr != nil {
+		logger.SysError(fmt.Sprintf("failed to disable channel #%d key #%d: %s", channel.Id, channel.KeyIndex, disableErr.Error()))
+		return false
+	}
+	metrics.RecordChannelDisabled(channel.Id, metrics.ChannelActionDisableKey)
+
+	subject := fmt.Sprintf("通道「%s」（#%d）的第 %d 个密钥已被禁用", channel.Name, channel.Id, channel.KeyIndex+1)
+	content := fmt.Sprintf("通道「%s」（#%d）的第 %d 个密钥已被禁用，原因：%s", channel.Name, channel.Id, channel.KeyIndex+1, err.Message)
+	notify.Send(subject, content)
+
+	return allDisabled
+}
+
 // enable & notify
 func EnableChannel(channelId int, channelName string, sendNotify bool) {
 	model.UpdateChannelStatusById(channelId, config.ChannelStatusEnabled)
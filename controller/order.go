@@ -3,8 +3,10 @@ package controller
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 
 	"one-api/common"
@@ -20,7 +22,8 @@ import (
 
 type OrderRequest struct {
 	UUID   string `json:"uuid" binding:"required"`
-	Amount int    `json:"amount" binding:"required"`
+	Amount int    `json:"amount"`
+	PlanId int    `json:"plan_id"`
 }
 
 type OrderResponse struct {
@@ -37,7 +40,22 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
-	if orderReq.Amount <= 0 || orderReq.Amount < config.PaymentMinAmount {
+	// 订阅套餐按套餐价格支付，不参与充值优惠
+	rechargeDiscount := 0.0
+	quota := 0
+	if orderReq.PlanId > 0 {
+		plan, err := model.GetSubscriptionPlanByID(orderReq.PlanId)
+		if err != nil || plan.Enable == nil || !*plan.Enable {
+			common.APIRespondWithError(c, http.StatusOK, errors.New("套餐不存在或已下架"))
+			return
+		}
+		orderReq.Amount = plan.Amount
+		rechargeDiscount = 1
+	} else {
+		quota = orderReq.Amount * int(config.QuotaPerUnit)
+	}
+
+	if orderReq.Amount <= 0 || (orderReq.PlanId == 0 && orderReq.Amount < config.PaymentMinAmount) {
 		common.APIRespondWithError(c, http.StatusOK, fmt.Errorf("金额必须大于等于 %d", config.PaymentMinAmount))
 
 		return
@@ -53,7 +71,7 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 	// 获取手续费和支付金额
-	discount, fee, payMoney := calculateOrderAmount(paymentService.Payment, orderReq.Amount)
+	discount, fee, payMoney := calculateOrderAmount(paymentService.Payment, orderReq.Amount, rechargeDiscount)
 
 	// 开始支付
 	tradeNo := utils.GenerateTradeNo()
@@ -74,7 +92,8 @@ func CreateOrder(c *gin.Context) {
 		Fee:           fee,
 		Discount:      discount,
 		Status:        model.OrderStatusPending,
-		Quota:         orderReq.Amount * int(config.QuotaPerUnit),
+		Quota:         quota,
+		PlanId:        orderReq.PlanId,
 	}
 
 	err = order.Insert()
@@ -135,36 +154,61 @@ func PaymentCallback(c *gin.Context) {
 		return
 	}
 
+	err = handlePayNotify(payNotify)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("gateway callback failed, trade_no: %s, error: %s", payNotify.TradeNo, err.Error()))
+	}
+	paymentService.RespondCallback(c, err)
+}
+
+// handlePayNotify 处理支付成功的订单，订单已处理过时直接返回成功
+func handlePayNotify(payNotify *types.PayNotify) error {
 	LockOrder(payNotify.GatewayNo)
 	defer UnlockOrder(payNotify.GatewayNo)
 
 	order, err := model.GetOrderByTradeNo(payNotify.TradeNo)
 	if err != nil {
-		logger.SysError(fmt.Sprintf("gateway callback failed to find order, trade_no: %s,", payNotify.TradeNo))
-		return
+		return errors.New("failed to find order")
 	}
-	fmt.Println(order.Status, order.Status != model.OrderStatusPending)
 
 	if order.Status != model.OrderStatusPending {
-		return
+		return nil
 	}
 
-	order.GatewayNo = payNotify.GatewayNo
-	order.Status = model.OrderStatusSuccess
-	err = order.Update()
-	if err != nil {
-		logger.SysError(fmt.Sprintf("gateway callback failed to update order, trade_no: %s,", payNotify.TradeNo))
-		return
+	if err := checkPayNotifyAmount(order, payNotify); err != nil {
+		return err
 	}
 
-	err = model.IncreaseUserQuota(order.UserId, order.Quota)
+	fulfilled, err := model.FulfillOrder(order, payNotify.GatewayNo)
 	if err != nil {
-		logger.SysError(fmt.Sprintf("gateway callback failed to increase user quota, trade_no: %s,", payNotify.TradeNo))
-		return
+		return fmt.Errorf("failed to fulfill order: %s", err.Error())
+	}
+	if !fulfilled {
+		return nil
+	}
+
+	if order.PlanId > 0 {
+		model.RecordLog(order.UserId, model.LogTypeTopup, fmt.Sprintf("在线购买套餐成功，支付金额：%.2f %s", order.OrderAmount, order.OrderCurrency))
+		return nil
 	}
 
 	model.RecordLog(order.UserId, model.LogTypeTopup, fmt.Sprintf("在线充值成功，充值quota: %d，支付金额：%.2f %s", order.Quota, order.OrderAmount, order.OrderCurrency))
+	return nil
+}
 
+// checkPayNotifyAmount 网关回调中带有实付金额和币种时，校验与订单一致
+func checkPayNotifyAmount(order *model.Order, payNotify *types.PayNotify) error {
+	if payNotify.Currency == "" {
+		return nil
+	}
+	if !strings.EqualFold(payNotify.Currency, string(order.OrderCurrency)) {
+		return fmt.Errorf("currency mismatch, order: %s, paid: %s", order.OrderCurrency, payNotify.Currency)
+	}
+	if math.Abs(payNotify.Amount-order.OrderAmount) >= 0.01 {
+		return fmt.Errorf("amount mismatch, order: %.2f, paid: %.2f", order.OrderAmount, payNotify.Amount)
+	}
+
+	return nil
 }
 
 func CheckOrderStatus(c *gin.Context) {
@@ -187,10 +231,12 @@ func CheckOrderStatus(c *gin.Context) {
 	})
 }
 
-// discountMoney优惠金额 fee手续费，payMoney实付金额
-func calculateOrderAmount(payment *model.Payment, amount int) (discountMoney, fee, payMoney float64) {
+// discountMoney优惠金额 fee手续费，payMoney实付金额，discount 为 0 时使用充值优惠
+func calculateOrderAmount(payment *model.Payment, amount int, discount float64) (discountMoney, fee, payMoney float64) {
 	// 获取折扣
-	discount := common.GetRechargeDiscount(strconv.Itoa(amount))
+	if discount == 0 {
+		discount = common.GetRechargeDiscount(strconv.Itoa(amount))
+	}
 	newMoney := float64(amount) * discount // 折后价值
 	oldTotal := float64(amount)            //原价值
 	if payment.PercentFee > 0 {
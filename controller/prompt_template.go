@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetPromptTemplateList(c *gin.Context) {
+	var params model.GenericParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	templates, err := model.GetPromptTemplateList(&params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    templates,
+	})
+}
+
+func GetPromptTemplate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	template, err := model.GetPromptTemplateByID(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    template,
+	})
+}
+
+func AddPromptTemplate(c *gin.Context) {
+	template := model.PromptTemplate{}
+	if err := c.ShouldBindJSON(&template); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := validatePromptTemplate(&template); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := template.Insert(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    template,
+	})
+}
+
+func UpdatePromptTemplate(c *gin.Context) {
+	template := model.PromptTemplate{}
+	if err := c.ShouldBindJSON(&template); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if template.ID <= 0 {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("无效的模板 ID"))
+		return
+	}
+
+	if err := validatePromptTemplate(&template); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := template.Update(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    template,
+	})
+}
+
+func DeletePromptTemplate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	template := model.PromptTemplate{ID: id}
+	if err := template.Delete(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func validatePromptTemplate(template *model.PromptTemplate) error {
+	if template.Name == "" {
+		return errors.New("模板名称不能为空")
+	}
+	if template.Mode == "" {
+		template.Mode = model.PromptTemplateModePrepend
+	}
+	switch template.Mode {
+	case model.PromptTemplateModePrepend, model.PromptTemplateModeAppend, model.PromptTemplateModeReplace:
+	default:
+		return errors.New("无效的注入方式，可选 prepend、append、replace")
+	}
+	if strings.TrimSpace(template.Content) == "" {
+		return errors.New("模板内容不能为空")
+	}
+
+	groups := strings.Split(template.Groups, ",")
+	cleaned := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if group = strings.TrimSpace(group); group != "" {
+			cleaned = append(cleaned, group)
+		}
+	}
+	template.Groups = strings.Join(cleaned, ",")
+	return nil
+}
@@ -1,14 +1,19 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/utils"
 	"one-api/model"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
 )
 
 func GetUserTokensList(c *gin.Context) {
@@ -107,6 +112,14 @@ func AddToken(c *gin.Context) {
 		})
 		return
 	}
+	setting, err := cleanTokenSetting(c, token.Setting, nil)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	cleanToken := model.Token{
 		UserId:         c.GetInt("id"),
 		Name:           token.Name,
@@ -117,6 +130,7 @@ func AddToken(c *gin.Context) {
 		RemainQuota:    token.RemainQuota,
 		UnlimitedQuota: token.UnlimitedQuota,
 		ChatCache:      token.ChatCache,
+		Setting:        setting,
 	}
 	err = cleanToken.Insert()
 	if err != nil {
@@ -201,6 +215,14 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.RemainQuota = token.RemainQuota
 		cleanToken.UnlimitedQuota = token.UnlimitedQuota
 		cleanToken.ChatCache = token.ChatCache
+		cleanToken.Setting, err = cleanTokenSetting(c, token.Setting, cleanToken.Setting)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
 	}
 	err = cleanToken.Update()
 	if err != nil {
@@ -216,3 +238,82 @@ func UpdateToken(c *gin.Context) {
 		"data":    cleanToken,
 	})
 }
+
+// 去除空白的配置项并校验 IP 格式，避免误配置导致令牌不可用
+// 审计等由管理员控制的配置，普通用户只能沿用令牌原有的值
+func cleanTokenSetting(c *gin.Context, setting, originSetting *datatypes.JSONType[model.TokenSetting]) (*datatypes.JSONType[model.TokenSetting], error) {
+	if setting == nil {
+		if originSetting == nil || c.GetInt("role") >= config.RoleAdminUser {
+			return nil, nil
+		}
+		setting = &datatypes.JSONType[model.TokenSetting]{}
+	}
+
+	data := setting.Data()
+	if c.GetInt("role") < config.RoleAdminUser {
+		var origin model.TokenSetting
+		if originSetting != nil {
+			origin = originSetting.Data()
+		}
+		// 审计和提示词模板由管理员设置
+		data.Audit = origin.Audit
+		data.PromptTemplateId = origin.PromptTemplateId
+	}
+	data.Models = cleanStringList(data.Models)
+	data.DeniedModels = cleanStringList(data.DeniedModels)
+	data.AllowIPs = cleanStringList(data.AllowIPs)
+
+	if data.RPM < 0 || data.TPM < 0 {
+		return nil, errors.New("速率限制不能为负数")
+	}
+
+	if data.SemanticCacheThreshold < 0 || data.SemanticCacheThreshold > 1 {
+		return nil, errors.New("语义缓存的相似度阈值需要在 0 到 1 之间")
+	}
+
+	if data.HedgeDelay < 0 {
+		return nil, errors.New("对冲请求的等待时间不能为负数")
+	}
+
+	if err := data.Redaction.Validate(); err != nil {
+		return nil, err
+	}
+
+	if data.PromptTemplateId < 0 {
+		return nil, errors.New("无效的提示词模板")
+	}
+	// 普通用户保留原有的模板，模板被删除后也不影响修改令牌
+	if data.PromptTemplateId > 0 && c.GetInt("role") >= config.RoleAdminUser {
+		if _, err := model.GetPromptTemplateByID(data.PromptTemplateId); err != nil {
+			return nil, errors.New("提示词模板不存在")
+		}
+	}
+
+	for _, allowIP := range data.AllowIPs {
+		if strings.Contains(allowIP, "/") {
+			if _, _, err := net.ParseCIDR(allowIP); err != nil {
+				return nil, fmt.Errorf("无效的 CIDR：%s", allowIP)
+			}
+			continue
+		}
+		if net.ParseIP(allowIP) == nil {
+			return nil, fmt.Errorf("无效的 IP：%s", allowIP)
+		}
+	}
+
+	cleanSetting := datatypes.NewJSONType(data)
+	return &cleanSetting, nil
+}
+
+func cleanStringList(list []string) []string {
+	cleanList := make([]string, 0, len(list))
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		cleanList = append(cleanList, item)
+	}
+
+	return cleanList
+}
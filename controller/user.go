@@ -386,6 +386,9 @@ func UpdateUser(c *gin.Context) {
 		})
 		return
 	}
+	if updatedUser.AuditEnabled != nil {
+		model.CacheDeleteUserAudit(originUser.Id)
+	}
 	if originUser.Quota != updatedUser.Quota {
 		model.RecordLog(originUser.Id, model.LogTypeManage, fmt.Sprintf("管理员将用户额度从 %s修改为 %s", common.LogQuota(originUser.Quota), common.LogQuota(updatedUser.Quota)))
 	}
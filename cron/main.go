@@ -1,8 +1,11 @@
 package cron
 
 import (
+	"fmt"
+	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/model"
+	"time"
 
 	"github.com/go-co-op/gocron/v2"
 )
@@ -23,6 +26,7 @@ func InitCron() {
 			)),
 		gocron.NewTask(func() {
 			model.RemoveChatCache()
+			model.RemoveChatCacheVector()
 			logger.SysLog("删除过期缓存数据")
 		}),
 	)
@@ -32,5 +36,41 @@ func InitCron() {
 		return
 	}
 
+	// 删除超过保留天数的审计日志
+	_, err = scheduler.NewJob(
+		gocron.DailyJob(
+			1,
+			gocron.NewAtTimes(
+				gocron.NewAtTime(0, 10, 0),
+			)),
+		gocron.NewTask(func() {
+			count, err := model.DeleteExpiredAuditLogs(config.AuditLogRetentionDays)
+			if err != nil {
+				logger.SysError("删除过期审计日志失败: " + err.Error())
+				return
+			}
+			logger.SysLog(fmt.Sprintf("删除过期审计日志 %d 条", count))
+		}),
+	)
+
+	if err != nil {
+		logger.SysError("Cron job error: " + err.Error())
+		return
+	}
+
+	// 发放订阅额度并处理到期的订阅
+	_, err = scheduler.NewJob(
+		gocron.DurationJob(time.Hour),
+		gocron.NewTask(func() {
+			model.ProcessSubscriptions()
+			logger.SysLog("处理订阅套餐")
+		}),
+	)
+
+	if err != nil {
+		logger.SysError("Cron job error: " + err.Error())
+		return
+	}
+
 	scheduler.Start()
 }
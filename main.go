@@ -13,6 +13,7 @@ import (
 	"one-api/common/requester"
 	"one-api/common/storage"
 	"one-api/common/telegram"
+	"one-api/common/tracing"
 	"one-api/controller"
 	"one-api/cron"
 	"one-api/middleware"
@@ -52,6 +53,7 @@ func main() {
 	// Initialize options
 	model.InitOptionMap()
 	relay_util.NewPricing()
+	model.PromptTemplates.Load()
 	initMemoryCache()
 	initSync()
 
@@ -65,6 +67,8 @@ func main() {
 	notify.InitNotifier()
 	cron.InitCron()
 	storage.InitStorage()
+	tracing.InitTracing()
+	defer tracing.Shutdown()
 
 	initHttpServer()
 }
@@ -90,6 +94,8 @@ func initMemoryCache() {
 func initSync() {
 	// go controller.AutomaticallyUpdateChannels(viper.GetInt("channel.update_frequency"))
 	go controller.AutomaticallyTestChannels(viper.GetInt("channel.test_frequency"))
+	go model.SyncChannelStats()
+	go controller.AutomaticallyProbeCircuitBreakers()
 }
 
 func initHttpServer() {
@@ -100,8 +106,13 @@ func initHttpServer() {
 	server := gin.New()
 	server.Use(gin.Recovery())
 	server.Use(middleware.RequestId())
+	server.Use(middleware.Tracing())
 	middleware.SetUpLogger(server)
 
+	// 未配置可信代理时不信任 X-Forwarded-For 等头部，避免伪造 IP 绕过令牌白名单
+	if err := server.SetTrustedProxies(viper.GetStringSlice("trusted_proxies")); err != nil {
+		logger.FatalLog("invalid trusted_proxies: " + err.Error())
+	}
 	trustedHeader := viper.GetString("trusted_header")
 	if trustedHeader != "" {
 		server.TrustedPlatform = trustedHeader
@@ -130,5 +141,6 @@ func SyncChannelCache(frequency int) {
 		logger.SysLog("syncing channels from database")
 		model.ChannelGroup.Load()
 		relay_util.PricingInstance.Init()
+		model.PromptTemplates.Load()
 	}
 }
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 单个请求或响应最多记录的字节数
+const auditLogMaxBodySize = 1 << 20
+
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *auditResponseWriter) capture(b []byte) {
+	if remain := auditLogMaxBodySize - w.body.Len(); remain > 0 {
+		if len(b) > remain {
+			b = b[:remain]
+		}
+		w.body.Write(b)
+	}
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Audit 对开启审计的用户或令牌，记录完整的请求与响应
+func Audit() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !shouldAudit(c) {
+			c.Next()
+			return
+		}
+
+		startTime := time.Now()
+		requestBody := readAuditRequestBody(c)
+		writer := &auditResponseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+
+		c.Next()
+
+		auditLog := &model.AuditLog{
+			RequestId:   c.GetString(logger.RequestIdKey),
+			UserId:      c.GetInt("id"),
+			TokenId:     c.GetInt("token_id"),
+			TokenName:   c.GetString("token_name"),
+			ChannelId:   c.GetInt("channel_id"),
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			StatusCode:  writer.Status(),
+			Request:     requestBody,
+			Response:    formatAuditResponse(writer.Header().Get("Content-Type"), writer.body),
+			RequestTime: int(time.Since(startTime).Milliseconds()),
+		}
+
+		go func() {
+			if err := auditLog.Insert(); err != nil {
+				logger.SysError("failed to record audit log: " + err.Error())
+			}
+		}()
+	}
+}
+
+func shouldAudit(c *gin.Context) bool {
+	if !config.AuditLogEnabled {
+		return false
+	}
+
+	if tokenSetting, ok := c.Get("token_setting"); ok {
+		if setting, ok := tokenSetting.(*model.TokenSetting); ok && setting.Audit {
+			return true
+		}
+	}
+
+	enabled, err := model.CacheIsUserAuditEnabled(c.GetInt("id"))
+	if err != nil {
+		logger.LogError(c.Request.Context(), "failed to get user audit setting: "+err.Error())
+		return false
+	}
+	return enabled
+}
+
+func readAuditRequestBody(c *gin.Context) string {
+	contentType := c.GetHeader("Content-Type")
+	if c.Request.Body == nil || c.Request.Method == "GET" {
+		return ""
+	}
+	if !isAuditTextContent(contentType) {
+		return fmt.Sprintf("[%s 请求体未记录]", contentType)
+	}
+
+	requestBody, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return ""
+	}
+	c.Request.Body.Close()
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+
+	return truncateAuditBody(requestBody)
+}
+
+func formatAuditResponse(contentType string, body *bytes.Buffer) string {
+	if !isAuditTextContent(contentType) {
+		return fmt.Sprintf("[%s 响应体未记录]", contentType)
+	}
+
+	// 流式响应合并为完整的响应，无法合并时保留原始内容
+	if strings.Contains(contentType, "text/event-stream") && body.Len() < auditLogMaxBodySize {
+		if response, ok := relay_util.ReassembleStreamResponse(body.String()); ok {
+			return response
+		}
+	}
+
+	return truncateAuditBody(body.Bytes())
+}
+
+func isAuditTextContent(contentType string) bool {
+	return contentType == "" ||
+		strings.Contains(contentType, "json") ||
+		strings.HasPrefix(contentType, "text/")
+}
+
+func truncateAuditBody(body []byte) string {
+	if len(body) >= auditLogMaxBodySize {
+		return strings.ToValidUTF8(string(body[:auditLogMaxBodySize]), "") + "\n[内容过长，已截断]"
+	}
+	return strings.ToValidUTF8(string(body), "")
+}
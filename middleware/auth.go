@@ -1,14 +1,19 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 	"one-api/common/config"
+	"one-api/common/tracing"
 	"one-api/common/utils"
 	"one-api/model"
+	"one-api/relay/relay_util"
 	"strings"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 func authHelper(c *gin.Context, minRole int) {
@@ -85,12 +90,27 @@ func RootAuth() func(c *gin.Context) {
 }
 
 func tokenAuth(c *gin.Context, key string) {
+	_, span := tracing.Start(c.Request.Context(), "tokenAuth")
+	ok := validateToken(c, key)
+	span.SetAttributes(attribute.Int("user.id", c.GetInt("id")), attribute.Int("token.id", c.GetInt("token_id")))
+	if !ok {
+		span.SetStatus(codes.Error, "unauthorized")
+	}
+	span.End()
+
+	if ok {
+		c.Next()
+	}
+}
+
+// validateToken 校验令牌，失败时已经写入了错误响应
+func validateToken(c *gin.Context, key string) bool {
 	key = strings.TrimPrefix(key, "Bearer ")
 	key = strings.TrimPrefix(key, "sk-")
 
 	if len(key) < 48 {
 		abortWithMessage(c, http.StatusUnauthorized, "无效的令牌")
-		return
+		return false
 	}
 
 	parts := strings.Split(key, "-")
@@ -98,21 +118,36 @@ func tokenAuth(c *gin.Context, key string) {
 	token, err := model.ValidateUserToken(key)
 	if err != nil {
 		abortWithMessage(c, http.StatusUnauthorized, err.Error())
-		return
+		return false
 	}
 	userEnabled, err := model.CacheIsUserEnabled(token.UserId)
 	if err != nil {
 		abortWithMessage(c, http.StatusInternalServerError, err.Error())
-		return
+		return false
 	}
 	if !userEnabled {
 		abortWithMessage(c, http.StatusForbidden, "用户已被封禁")
-		return
+		return false
 	}
 	c.Set("id", token.UserId)
 	c.Set("token_id", token.Id)
 	c.Set("token_name", token.Name)
 	c.Set("chat_cache", token.ChatCache)
+
+	tokenSetting := token.GetSetting()
+	c.Set("token_setting", tokenSetting)
+	if clientIP := c.ClientIP(); !tokenSetting.IsIPAllowed(clientIP) {
+		model.RecordLog(token.UserId, model.LogTypeSystem, fmt.Sprintf("令牌 %s 拒绝了来自非白名单 IP %s 的请求", token.Name, clientIP))
+		abortWithCode(c, http.StatusForbidden, "ip_not_allowed", fmt.Sprintf("IP %s 不在该令牌的白名单内", clientIP))
+		return false
+	}
+	// 无法从请求中获取模型时，Midjourney、Suno 等接口在处理请求时检查，透传接口拒绝没有模型的请求
+	if tokenSetting.HasModelLimit() {
+		if modelName := getRequestModel(c); modelName != "" && !tokenSetting.IsModelAllowed(modelName) {
+			abortWithMessage(c, http.StatusForbidden, fmt.Sprintf("该令牌无权使用模型 %s", modelName))
+			return false
+		}
+	}
 	if len(parts) > 1 {
 		if model.IsAdmin(token.UserId) {
 			if strings.HasPrefix(parts[1], "!") {
@@ -122,7 +157,7 @@ func tokenAuth(c *gin.Context, key string) {
 				channelId := utils.String2Int(parts[1])
 				if channelId == 0 {
 					abortWithMessage(c, http.StatusForbidden, "无效的渠道 Id")
-					return
+					return false
 				}
 				c.Set("specific_channel_id", channelId)
 				if len(parts) == 3 && parts[2] == "ignore" {
@@ -131,10 +166,10 @@ func tokenAuth(c *gin.Context, key string) {
 			}
 		} else {
 			abortWithMessage(c, http.StatusForbidden, "普通用户不支持指定渠道")
-			return
+			return false
 		}
 	}
-	c.Next()
+	return true
 }
 
 func OpenaiAuth() func(c *gin.Context) {
@@ -151,6 +186,16 @@ func ClaudeAuth() func(c *gin.Context) {
 	}
 }
 
+func GeminiAuth() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		key := c.Request.Header.Get("x-goog-api-key")
+		if key == "" {
+			key = c.Query("key")
+		}
+		tokenAuth(c, key)
+	}
+}
+
 func MjAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		key := c.Request.Header.Get("mj-api-secret")
@@ -170,3 +215,15 @@ func SpecifiedChannel() func(c *gin.Context) {
 		c.Next()
 	}
 }
+
+// RelayObjectChannel 将对象的请求转发到创建它的渠道，只有创建者可以访问
+// 创建对象时从分组中选择渠道，并在响应后记录对象所在的渠道
+func RelayObjectChannel() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if statusCode, err := relay_util.SetRelayObjectChannel(c); err != nil {
+			abortWithMessage(c, statusCode, err.Error())
+			return
+		}
+		c.Next()
+	}
+}
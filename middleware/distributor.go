@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"one-api/model"
+	"one-api/relay/relay_util"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,6 +12,8 @@ func Distribute() func(c *gin.Context) {
 		userId := c.GetInt("id")
 		userGroup, _ := model.CacheGetUserGroup(userId)
 		c.Set("group", userGroup)
+		// 请求结束后释放占用的渠道并发
+		defer relay_util.ReleaseChannel(c)
 		c.Next()
 	}
 }
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"net/url"
 	"one-api/common/logger"
 	"time"
 
@@ -20,7 +21,7 @@ func GinzapWithConfig() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
+		query := redactQuery(c.Request.URL.RawQuery)
 		c.Next()
 		end := time.Now()
 		latency := end.Sub(start)
@@ -48,3 +49,17 @@ func GinzapWithConfig() gin.HandlerFunc {
 		}
 	}
 }
+
+// redactQuery 隐藏查询参数中的密钥，Gemini 接口允许通过 ?key= 传递令牌
+func redactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+	// 解析出错时仍返回已解析的部分，同样需要隐藏
+	values, _ := url.ParseQuery(rawQuery)
+	if !values.Has("key") {
+		return rawQuery
+	}
+	values.Set("key", "***")
+	return values.Encode()
+}
@@ -2,11 +2,14 @@ package middleware
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/redis"
 	"one-api/common/utils"
+	"one-api/relay/relay_util"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -120,3 +123,36 @@ func DownloadRateLimit() func(c *gin.Context) {
 func UploadRateLimit() func(c *gin.Context) {
 	return rateLimitFactory(UploadRateLimitNum, UploadRateLimitDuration, "UP")
 }
+
+// RelayRateLimit 按令牌、用户、分组限制中继请求的 RPM/TPM
+func RelayRateLimit() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		rules := relay_util.GetRateLimitRules(c)
+		if len(rules) == 0 {
+			c.Next()
+			return
+		}
+
+		status := relay_util.CheckRateLimit(c.Request.Context(), rules)
+		setRateLimitHeaders(c, status)
+		if status.Exceeded != "" {
+			abortWithCode(c, http.StatusTooManyRequests, "rate_limit_exceeded", fmt.Sprintf("Rate limit reached for %s, please try again later", status.Exceeded))
+			return
+		}
+		c.Next()
+	}
+}
+
+// 与 OpenAI 的 x-ratelimit-* 响应头保持一致
+func setRateLimitHeaders(c *gin.Context, status *relay_util.RateLimitStatus) {
+	if status.LimitRequests > 0 {
+		c.Header("x-ratelimit-limit-requests", strconv.Itoa(status.LimitRequests))
+		c.Header("x-ratelimit-remaining-requests", strconv.Itoa(status.RemainingRequests))
+		c.Header("x-ratelimit-reset-requests", status.ResetRequests.String())
+	}
+	if status.LimitTokens > 0 {
+		c.Header("x-ratelimit-limit-tokens", strconv.Itoa(status.LimitTokens))
+		c.Header("x-ratelimit-remaining-tokens", strconv.Itoa(status.RemainingTokens))
+		c.Header("x-ratelimit-reset-tokens", status.ResetTokens.String())
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"one-api/common/logger"
+	"one-api/common/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing 为每个请求创建根 span，需要放在 RequestId 之后，以便 span 带上请求 ID
+func Tracing() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !tracing.Enabled {
+			c.Next()
+			return
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := otel.Tracer("one-api").Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("client.address", c.ClientIP()),
+				tracing.AttrRequestId.String(c.GetString(logger.RequestIdKey)),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(
+			attribute.Int("http.status_code", status),
+			attribute.Int("user.id", c.GetInt("id")),
+			attribute.Int("token.id", c.GetInt("token_id")),
+			attribute.String("group", c.GetString("group")),
+			attribute.Int("channel.id", c.GetInt("channel_id")),
+		)
+		if status >= 500 {
+			span.SetStatus(codes.Error, "")
+		}
+	}
+}
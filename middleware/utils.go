@@ -1,8 +1,11 @@
 package middleware
 
 import (
+	"net/http"
+	"one-api/common"
 	"one-api/common/logger"
 	"one-api/common/utils"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,3 +20,38 @@ func abortWithMessage(c *gin.Context, statusCode int, message string) {
 	c.Abort()
 	logger.LogError(c.Request.Context(), message)
 }
+
+func abortWithCode(c *gin.Context, statusCode int, code string, message string) {
+	c.JSON(statusCode, gin.H{
+		"error": gin.H{
+			"message": utils.MessageWithRequestId(message, c.GetString(logger.RequestIdKey)),
+			"type":    "one_api_error",
+			"code":    code,
+		},
+	})
+	c.Abort()
+	logger.LogError(c.Request.Context(), message)
+}
+
+type requestModel struct {
+	Model string `json:"model" form:"model"`
+}
+
+// getRequestModel 获取请求中的模型名称，获取失败时返回空字符串
+func getRequestModel(c *gin.Context) string {
+	if c.Request.Method != http.MethodPost {
+		return c.Param("model")
+	}
+
+	// Gemini 接口的模型名称在路径中，例如 gemini-pro:generateContent
+	if modelName, _, ok := strings.Cut(c.Param("model"), ":"); ok {
+		return modelName
+	}
+
+	var request requestModel
+	if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+		return ""
+	}
+
+	return request.Model
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"one-api/common/utils"
+)
+
+// AuditLog 开启审计的用户或令牌，记录完整的请求与响应内容
+type AuditLog struct {
+	Id          int    `json:"id"`
+	RequestId   string `json:"request_id" gorm:"type:varchar(64);index"`
+	UserId      int    `json:"user_id" gorm:"index"`
+	TokenId     int    `json:"token_id"`
+	TokenName   string `json:"token_name" gorm:"default:''"`
+	ChannelId   int    `json:"channel_id"`
+	Method      string `json:"method" gorm:"type:varchar(16)"`
+	Path        string `json:"path" gorm:"type:varchar(255)"`
+	StatusCode  int    `json:"status_code"`
+	Request     string `json:"request"`
+	Response    string `json:"response"`
+	RequestTime int    `json:"request_time" gorm:"default:0"` // 毫秒
+	CreatedAt   int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+func (log *AuditLog) Insert() error {
+	if log.CreatedAt == 0 {
+		log.CreatedAt = utils.GetTimestamp()
+	}
+	return DB.Create(log).Error
+}
+
+func GetAuditLogsByRequestId(requestId string) ([]*AuditLog, error) {
+	var logs []*AuditLog
+	err := DB.Where("request_id = ?", requestId).Order("id asc").Find(&logs).Error
+	return logs, err
+}
+
+// DeleteExpiredAuditLogs 删除超过保留天数的审计日志
+func DeleteExpiredAuditLogs(retentionDays int) (int64, error) {
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+
+	expiredTime := utils.GetTimestamp() - int64(retentionDays)*24*3600
+	result := DB.Where("created_at < ?", expiredTime).Delete(&AuditLog{})
+	return result.RowsAffected, result.Error
+}
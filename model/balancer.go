@@ -3,12 +3,15 @@ package model
 import (
 	"errors"
 	"math/rand"
+	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/common/utils"
 	"strings"
 	"sync"
 	"time"
+
+	"gorm.io/datatypes"
 )
 
 type ChannelChoice struct {
@@ -72,6 +75,20 @@ func (cc *ChannelsChooser) Enable(channelId int) {
 	cc.Channels[channelId].Disable = false
 }
 
+// SetKeyStatus 更新缓存中渠道的密钥状态
+func (cc *ChannelsChooser) SetKeyStatus(channelId int, status *datatypes.JSONType[ChannelKeyStatus]) {
+	cc.Lock()
+	defer cc.Unlock()
+	choice, ok := cc.Channels[channelId]
+	if !ok {
+		return
+	}
+
+	channel := *choice.Channel
+	channel.KeyStatus = status
+	choice.Channel = &channel
+}
+
 func (cc *ChannelsChooser) ChangeStatus(channelId int, status bool) {
 	if status {
 		cc.Enable(channelId)
@@ -80,9 +97,9 @@ func (cc *ChannelsChooser) ChangeStatus(channelId int, status bool) {
 	}
 }
 
-func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFunc) *Channel {
+// balancer 从同一优先级的渠道中选择一个，saturated 表示是否有渠道因为并发已满被跳过
+func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFunc, modelName, strategy string) (channel *Channel, saturated bool) {
 	nowTime := time.Now().Unix()
-	totalWeight := 0
 
 	validChannels := make([]*ChannelChoice, 0, len(channelIds))
 	for _, channelId := range channelIds {
@@ -91,6 +108,14 @@ func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFu
 			continue
 		}
 
+		if !ChannelCircuitBreakers.Allow(channelId, modelName) {
+			continue
+		}
+
+		if !choice.Channel.HasAvailableKey() {
+			continue
+		}
+
 		isSkip := false
 		for _, filter := range filters {
 			if filter(channelId, choice) {
@@ -102,29 +127,46 @@ func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFu
 			continue
 		}
 
-		weight := int(*choice.Channel.Weight)
-		totalWeight += weight
+		if ChannelConcurrencies.Saturated(choice.Channel) {
+			saturated = true
+			continue
+		}
+
 		validChannels = append(validChannels, choice)
 	}
 
 	if len(validChannels) == 0 {
-		return nil
+		return nil, saturated
 	}
 
 	if len(validChannels) == 1 {
-		return validChannels[0].Channel
+		return validChannels[0].Channel, false
+	}
+
+	var weights []float64
+	if strategy == common.BalanceStrategyAdaptive {
+		weights = ChannelHealthStats.AdaptiveWeights(validChannels)
+	} else {
+		weights = make([]float64, len(validChannels))
+		for i, choice := range validChannels {
+			weights[i] = float64(*choice.Channel.Weight)
+		}
 	}
 
-	choiceWeight := rand.Intn(totalWeight)
-	for _, choice := range validChannels {
-		weight := int(*choice.Channel.Weight)
-		choiceWeight -= weight
+	totalWeight := 0.0
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+
+	choiceWeight := rand.Float64() * totalWeight
+	for i, choice := range validChannels {
+		choiceWeight -= weights[i]
 		if choiceWeight < 0 {
-			return choice.Channel
+			return choice.Channel, false
 		}
 	}
 
-	return nil
+	return validChannels[len(validChannels)-1].Channel, false
 }
 
 func (cc *ChannelsChooser) Next(group, modelName string, filters ...ChannelsFilterFunc) (*Channel, error) {
@@ -147,11 +189,53 @@ func (cc *ChannelsChooser) Next(group, modelName string, filters ...ChannelsFilt
 		return nil, errors.New("channel not found")
 	}
 
+	strategy := common.GetBalanceStrategy(group, modelName)
+	saturated := false
 	for _, priority := range channelsPriority {
-		channel := cc.balancer(priority, filters)
+		channel, prioritySaturated := cc.balancer(priority, filters, modelName, strategy)
 		if channel != nil {
 			return channel, nil
 		}
+		saturated = saturated || prioritySaturated
+	}
+
+	// 所有优先级都没有空闲渠道，且存在并发已满的渠道时，调用方可以排队等待
+	if saturated {
+		return nil, ErrChannelSaturated
+	}
+
+	return nil, errors.New("channel not found")
+}
+
+// NextByTypes 从分组的所有渠道中选择指定类型的渠道，用于 assistants、files 等不区分模型的透传请求
+func (cc *ChannelsChooser) NextByTypes(group string, channelTypes []int, filters ...ChannelsFilterFunc) (*Channel, error) {
+	cc.RLock()
+	defer cc.RUnlock()
+	if _, ok := cc.Rule[group]; !ok {
+		return nil, errors.New("group not found")
+	}
+
+	seen := make(map[int]bool)
+	var channelIds []int
+	for _, channelsPriority := range cc.Rule[group] {
+		for _, priority := range channelsPriority {
+			for _, channelId := range priority {
+				choice, ok := cc.Channels[channelId]
+				if !ok || seen[channelId] || !utils.Contains(choice.Channel.Type, channelTypes) {
+					continue
+				}
+				seen[channelId] = true
+				channelIds = append(channelIds, channelId)
+			}
+		}
+	}
+
+	channel, saturated := cc.balancer(channelIds, filters, "", common.GetBalanceStrategy(group, ""))
+	if channel != nil {
+		return channel, nil
+	}
+	if saturated {
+		return nil, ErrChannelSaturated
 	}
 
 	return nil, errors.New("channel not found")
@@ -173,6 +257,18 @@ func (cc *ChannelsChooser) GetGroupModels(group string) ([]string, error) {
 	return models, nil
 }
 
+func (cc *ChannelsChooser) GetChannelIds() []int {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	channelIds := make([]int, 0, len(cc.Channels))
+	for channelId := range cc.Channels {
+		channelIds = append(channelIds, channelId)
+	}
+
+	return channelIds
+}
+
 func (cc *ChannelsChooser) GetChannel(channelId int) *Channel {
 	cc.RLock()
 	defer cc.RUnlock()
@@ -184,6 +280,19 @@ func (cc *ChannelsChooser) GetChannel(channelId int) *Channel {
 	return nil
 }
 
+// GetAvailableChannel 获取未禁用、不在冷却中的渠道，用于内部请求指定渠道时
+func (cc *ChannelsChooser) GetAvailableChannel(channelId int) *Channel {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	choice, ok := cc.Channels[channelId]
+	if !ok || choice.Disable || choice.CooldownsTime >= time.Now().Unix() {
+		return nil
+	}
+
+	return choice.Channel
+}
+
 var ChannelGroup = ChannelsChooser{}
 
 func (cc *ChannelsChooser) Load() {
@@ -63,6 +63,16 @@ func CacheGetUserGroup(id int) (group string, err error) {
 	return group, err
 }
 
+func CacheDeleteUserGroup(id int) {
+	if !config.RedisEnabled {
+		return
+	}
+	err := redis.RedisDel(fmt.Sprintf("user_group:%d", id))
+	if err != nil {
+		logger.SysError("Redis delete user group error: " + err.Error())
+	}
+}
+
 func CacheGetUserQuota(id int) (quota int, err error) {
 	if !config.RedisEnabled {
 		return GetUserQuota(id)
@@ -103,6 +113,40 @@ func CacheDecreaseUserQuota(id int, quota int) error {
 	return err
 }
 
+func CacheIsUserAuditEnabled(userId int) (bool, error) {
+	if !config.RedisEnabled {
+		return IsUserAuditEnabled(userId)
+	}
+	enabled, err := redis.RedisGet(fmt.Sprintf("user_audit:%d", userId))
+	if err == nil {
+		return enabled == "1", nil
+	}
+
+	auditEnabled, err := IsUserAuditEnabled(userId)
+	if err != nil {
+		return false, err
+	}
+	enabled = "0"
+	if auditEnabled {
+		enabled = "1"
+	}
+	err = redis.RedisSet(fmt.Sprintf("user_audit:%d", userId), enabled, time.Duration(TokenCacheSeconds)*time.Second)
+	if err != nil {
+		logger.SysError("Redis set user audit error: " + err.Error())
+	}
+	return auditEnabled, err
+}
+
+func CacheDeleteUserAudit(id int) {
+	if !config.RedisEnabled {
+		return
+	}
+	err := redis.RedisDel(fmt.Sprintf("user_audit:%d", id))
+	if err != nil {
+		logger.SysError("Redis delete user audit error: " + err.Error())
+	}
+}
+
 func CacheIsUserEnabled(userId int) (bool, error) {
 	if !config.RedisEnabled {
 		return IsUserEnabled(userId)
@@ -34,8 +34,13 @@ type Channel struct {
 	TestModel          string  `json:"test_model" form:"test_model" gorm:"type:varchar(50);default:''"`
 	OnlyChat           bool    `json:"only_chat" form:"only_chat" gorm:"default:false"`
 	PreCost            int     `json:"pre_cost" form:"pre_cost" gorm:"default:1"`
+	MaxConcurrency     int     `json:"max_concurrency" form:"max_concurrency" gorm:"default:0"` // 最大并发数，0 为不限制
 
 	Plugin *datatypes.JSONType[PluginType] `json:"plugin" form:"plugin" gorm:"type:json"`
+
+	MultiKeyMode string                                `json:"multi_key_mode" form:"multi_key_mode" gorm:"type:varchar(16);default:''"`
+	KeyStatus    *datatypes.JSONType[ChannelKeyStatus] `json:"key_status" gorm:"type:json"`
+	KeyIndex     int                                   `json:"-" gorm:"-"` // 多密钥渠道本次使用的密钥序号
 }
 
 type PluginType map[string]map[string]interface{}
@@ -245,9 +250,9 @@ func (channel *Channel) UpdateRaw(overwrite bool) error {
 	var err error
 
 	if overwrite {
-		err = DB.Model(channel).Select("*").Omit("UsedQuota").Updates(channel).Error
+		err = DB.Model(channel).Select("*").Omit("UsedQuota", "KeyStatus").Updates(channel).Error
 	} else {
-		err = DB.Model(channel).Omit("UsedQuota").Updates(channel).Error
+		err = DB.Model(channel).Omit("UsedQuota", "KeyStatus").Updates(channel).Error
 	}
 	if err != nil {
 		return err
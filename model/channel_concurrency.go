@@ -0,0 +1,84 @@
+package model
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrChannelSaturated 所有可用渠道的并发数都已达到上限
+var ErrChannelSaturated = errors.New("channel saturated")
+
+// ChannelConcurrency 渠道当前处理中的请求数，只统计本节点
+type ChannelConcurrency struct {
+	sync.Mutex
+	inflight map[int]int
+	changed  chan struct{} // 有渠道释放并发时关闭，用于唤醒排队中的请求
+}
+
+var ChannelConcurrencies = NewChannelConcurrency()
+
+func NewChannelConcurrency() *ChannelConcurrency {
+	return &ChannelConcurrency{
+		inflight: make(map[int]int),
+		changed:  make(chan struct{}),
+	}
+}
+
+// Saturated 判断渠道的并发数是否已达到上限，MaxConcurrency 为 0 时不限制
+func (cc *ChannelConcurrency) Saturated(channel *Channel) bool {
+	if channel.MaxConcurrency <= 0 {
+		return false
+	}
+
+	cc.Lock()
+	defer cc.Unlock()
+	return cc.inflight[channel.Id] >= channel.MaxConcurrency
+}
+
+// Acquire 占用渠道的一个并发，成功时返回释放函数，释放函数可以重复调用
+func (cc *ChannelConcurrency) Acquire(channel *Channel) (func(), bool) {
+	if channel.MaxConcurrency <= 0 {
+		return func() {}, true
+	}
+
+	cc.Lock()
+	defer cc.Unlock()
+	if cc.inflight[channel.Id] >= channel.MaxConcurrency {
+		return nil, false
+	}
+	cc.inflight[channel.Id]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cc.release(channel.Id)
+		})
+	}, true
+}
+
+func (cc *ChannelConcurrency) release(channelId int) {
+	cc.Lock()
+	defer cc.Unlock()
+
+	cc.inflight[channelId]--
+	if cc.inflight[channelId] <= 0 {
+		delete(cc.inflight, channelId)
+	}
+
+	close(cc.changed)
+	cc.changed = make(chan struct{})
+}
+
+// Changed 返回在下一次有渠道释放并发时关闭的 channel
+func (cc *ChannelConcurrency) Changed() <-chan struct{} {
+	cc.Lock()
+	defer cc.Unlock()
+	return cc.changed
+}
+
+// Inflight 获取渠道当前处理中的请求数
+func (cc *ChannelConcurrency) Inflight(channelId int) int {
+	cc.Lock()
+	defer cc.Unlock()
+	return cc.inflight[channelId]
+}
@@ -0,0 +1,40 @@
+package model_test
+
+import (
+	"one-api/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelConcurrency(t *testing.T) {
+	concurrency := model.NewChannelConcurrency()
+	channel := &model.Channel{Id: 1, MaxConcurrency: 2}
+
+	release1, ok := concurrency.Acquire(channel)
+	assert.True(t, ok)
+	_, ok = concurrency.Acquire(channel)
+	assert.True(t, ok)
+	assert.True(t, concurrency.Saturated(channel))
+
+	_, ok = concurrency.Acquire(channel)
+	assert.False(t, ok)
+
+	// 释放时唤醒等待中的请求，重复释放不会多减
+	changed := concurrency.Changed()
+	release1()
+	release1()
+	assert.Equal(t, 1, concurrency.Inflight(1))
+	assert.False(t, concurrency.Saturated(channel))
+	select {
+	case <-changed:
+	default:
+		t.Fatal("changed channel should be closed after release")
+	}
+
+	// 不限制并发的渠道不计数
+	unlimited := &model.Channel{Id: 2}
+	_, ok = concurrency.Acquire(unlimited)
+	assert.True(t, ok)
+	assert.Equal(t, 0, concurrency.Inflight(2))
+}
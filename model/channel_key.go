@@ -0,0 +1,313 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"one-api/common/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 多密钥渠道的轮换方式，为空表示单密钥渠道
+const (
+	ChannelMultiKeyRoundRobin = "round_robin"
+	ChannelMultiKeyRandom     = "random"
+)
+
+const (
+	ChannelKeyStatusEnabled   = "enabled"
+	ChannelKeyStatusExhausted = "exhausted" // 额度用尽
+	ChannelKeyStatusInvalid   = "invalid"   // 密钥失效
+)
+
+type ChannelKeyState struct {
+	Status    string `json:"status"`
+	Reason    string `json:"reason"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// ChannelKeyStatus 密钥指纹 -> 状态，只记录被禁用的密钥
+type ChannelKeyStatus map[string]ChannelKeyState
+
+type ChannelKeyInfo struct {
+	Index     int    `json:"index"`
+	Key       string `json:"key"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason"`
+	UpdatedAt int64  `json:"updated_at"`
+	Cooldown  bool   `json:"cooldown"`
+}
+
+func ChannelKeyFingerprint(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:8])
+}
+
+func (channel *Channel) IsMultiKey() bool {
+	return channel.MultiKeyMode != ""
+}
+
+func (channel *Channel) GetKeys() []string {
+	if !channel.IsMultiKey() {
+		return []string{channel.Key}
+	}
+
+	keys := make([]string, 0)
+	for _, key := range strings.Split(channel.Key, "\n") {
+		key = strings.TrimSpace(key)
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (channel *Channel) GetKeyStatus() ChannelKeyStatus {
+	if channel.KeyStatus == nil {
+		return ChannelKeyStatus{}
+	}
+
+	status := channel.KeyStatus.Data()
+	if status == nil {
+		return ChannelKeyStatus{}
+	}
+	return status
+}
+
+func (channel *Channel) GetKeyInfos() []ChannelKeyInfo {
+	status := channel.GetKeyStatus()
+	keys := channel.GetKeys()
+
+	infos := make([]ChannelKeyInfo, 0, len(keys))
+	for index, key := range keys {
+		info := ChannelKeyInfo{
+			Index:    index,
+			Key:      maskChannelKey(key),
+			Status:   ChannelKeyStatusEnabled,
+			Cooldown: ChannelKeySelector.isCooldown(channel.Id, key),
+		}
+		if state, ok := status[ChannelKeyFingerprint(key)]; ok {
+			info.Status = state.Status
+			info.Reason = state.Reason
+			info.UpdatedAt = state.UpdatedAt
+		}
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+func maskChannelKey(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:4] + strings.Repeat("*", 8) + key[len(key)-4:]
+}
+
+// SelectKey 从多密钥渠道中选择一个可用的密钥，返回只包含该密钥的渠道副本
+func (channel *Channel) SelectKey() (*Channel, error) {
+	if !channel.IsMultiKey() {
+		return channel, nil
+	}
+
+	keys := channel.GetKeys()
+	index := ChannelKeySelector.next(channel, keys)
+	if index < 0 {
+		return nil, errors.New("渠道没有可用的密钥")
+	}
+
+	keyChannel := *channel
+	keyChannel.Key = keys[index]
+	keyChannel.KeyIndex = index
+	return &keyChannel, nil
+}
+
+// HasAvailableKey 多密钥渠道是否还有未禁用且不在冷却中的密钥，用于选择渠道时跳过密钥耗尽的渠道
+func (channel *Channel) HasAvailableKey() bool {
+	if !channel.IsMultiKey() {
+		return true
+	}
+
+	status := channel.GetKeyStatus()
+	for _, key := range channel.GetKeys() {
+		if _, ok := status[ChannelKeyFingerprint(key)]; ok {
+			continue
+		}
+		if !ChannelKeySelector.isCooldown(channel.Id, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type channelKeySelector struct {
+	sync.Mutex
+	counters  map[int]int
+	cooldowns map[string]int64 // channelId:fingerprint -> 冷却结束时间
+}
+
+var ChannelKeySelector = &channelKeySelector{
+	counters:  make(map[int]int),
+	cooldowns: make(map[string]int64),
+}
+
+func channelKeyCooldownKey(channelId int, key string) string {
+	return fmt.Sprintf("%d:%s", channelId, ChannelKeyFingerprint(key))
+}
+
+func (s *channelKeySelector) next(channel *Channel, keys []string) int {
+	if len(keys) == 0 {
+		return -1
+	}
+
+	status := channel.GetKeyStatus()
+	now := time.Now().Unix()
+
+	s.Lock()
+	defer s.Unlock()
+
+	start := 0
+	if channel.MultiKeyMode == ChannelMultiKeyRandom {
+		start = rand.Intn(len(keys))
+	} else {
+		start = s.counters[channel.Id] % len(keys)
+	}
+
+	for i := 0; i < len(keys); i++ {
+		index := (start + i) % len(keys)
+		if _, ok := status[ChannelKeyFingerprint(keys[index])]; ok {
+			continue
+		}
+		if s.cooldowns[channelKeyCooldownKey(channel.Id, keys[index])] > now {
+			continue
+		}
+
+		s.counters[channel.Id] = index + 1
+		return index
+	}
+
+	return -1
+}
+
+func (s *channelKeySelector) isCooldown(channelId int, key string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.cooldowns[channelKeyCooldownKey(channelId, key)] > time.Now().Unix()
+}
+
+// CooldownChannelKey 临时跳过被限流的密钥
+func CooldownChannelKey(channelId int, key string, seconds int) {
+	if seconds <= 0 {
+		return
+	}
+
+	ChannelKeySelector.Lock()
+	defer ChannelKeySelector.Unlock()
+
+	now := time.Now().Unix()
+	for cooldownKey, until := range ChannelKeySelector.cooldowns {
+		if until <= now {
+			delete(ChannelKeySelector.cooldowns, cooldownKey)
+		}
+	}
+	ChannelKeySelector.cooldowns[channelKeyCooldownKey(channelId, key)] = now + int64(seconds)
+}
+
+// DisableChannelKey 标记密钥不可用，返回渠道是否已经没有可用的密钥
+func DisableChannelKey(channelId int, key string, status string, reason string) (bool, error) {
+	channel, keyStatus, err := updateChannelKeyStatus(channelId, func(channel *Channel, keyStatus ChannelKeyStatus) error {
+		keyStatus[ChannelKeyFingerprint(key)] = ChannelKeyState{
+			Status:    status,
+			Reason:    reason,
+			UpdatedAt: utils.GetTimestamp(),
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, k := range channel.GetKeys() {
+		if _, ok := keyStatus[ChannelKeyFingerprint(k)]; !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ResetChannelKeyStatus 恢复密钥状态，index 小于 0 时恢复全部密钥
+func ResetChannelKeyStatus(channelId int, index int) error {
+	channel, _, err := updateChannelKeyStatus(channelId, func(channel *Channel, keyStatus ChannelKeyStatus) error {
+		keys := channel.GetKeys()
+		if index < 0 {
+			for fingerprint := range keyStatus {
+				delete(keyStatus, fingerprint)
+			}
+			return nil
+		}
+		if index >= len(keys) {
+			return errors.New("密钥不存在")
+		}
+		delete(keyStatus, ChannelKeyFingerprint(keys[index]))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ChannelKeySelector.Lock()
+	defer ChannelKeySelector.Unlock()
+	for i, key := range channel.GetKeys() {
+		if index < 0 || index == i {
+			delete(ChannelKeySelector.cooldowns, channelKeyCooldownKey(channelId, key))
+		}
+	}
+	return nil
+}
+
+// updateChannelKeyStatus 在事务中锁定渠道后修改密钥状态，避免并发修改时相互覆盖
+func updateChannelKeyStatus(channelId int, update func(channel *Channel, keyStatus ChannelKeyStatus) error) (*Channel, ChannelKeyStatus, error) {
+	channel := &Channel{}
+	var keyStatus ChannelKeyStatus
+	var status datatypes.JSONType[ChannelKeyStatus]
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(channel, "id = ?", channelId).Error; err != nil {
+			return err
+		}
+
+		keyStatus = channel.GetKeyStatus()
+		if err := update(channel, keyStatus); err != nil {
+			return err
+		}
+
+		// 清理已经被删除的密钥
+		fingerprints := make(map[string]bool)
+		for _, key := range channel.GetKeys() {
+			fingerprints[ChannelKeyFingerprint(key)] = true
+		}
+		for fingerprint := range keyStatus {
+			if !fingerprints[fingerprint] {
+				delete(keyStatus, fingerprint)
+			}
+		}
+
+		status = datatypes.NewJSONType(keyStatus)
+		return tx.Model(channel).Select("key_status").Updates(Channel{KeyStatus: &status}).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ChannelGroup.SetKeyStatus(channel.Id, &status)
+	return channel, keyStatus, nil
+}
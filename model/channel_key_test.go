@@ -0,0 +1,122 @@
+package model_test
+
+import (
+	"fmt"
+	"one-api/model"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestChannelSelectKey(t *testing.T) {
+	channel := &model.Channel{Id: 1001, Key: "sk-single"}
+	keyChannel, err := channel.SelectKey()
+	assert.Nil(t, err)
+	assert.Equal(t, "sk-single", keyChannel.Key)
+
+	channel = &model.Channel{
+		Id:           1002,
+		Key:          "sk-a\nsk-b\n\n sk-c \n",
+		MultiKeyMode: model.ChannelMultiKeyRoundRobin,
+	}
+	assert.Equal(t, []string{"sk-a", "sk-b", "sk-c"}, channel.GetKeys())
+
+	var selected []string
+	for i := 0; i < 4; i++ {
+		keyChannel, err = channel.SelectKey()
+		assert.Nil(t, err)
+		selected = append(selected, keyChannel.Key)
+	}
+	assert.Equal(t, []string{"sk-a", "sk-b", "sk-c", "sk-a"}, selected)
+	assert.Equal(t, channel.Key, "sk-a\nsk-b\n\n sk-c \n")
+
+	// 跳过被禁用和冷却中的密钥
+	status := datatypes.NewJSONType(model.ChannelKeyStatus{
+		model.ChannelKeyFingerprint("sk-b"): {Status: model.ChannelKeyStatusInvalid},
+	})
+	channel.KeyStatus = &status
+	model.CooldownChannelKey(channel.Id, "sk-c", 60)
+	for i := 0; i < 3; i++ {
+		keyChannel, err = channel.SelectKey()
+		assert.Nil(t, err)
+		assert.Equal(t, "sk-a", keyChannel.Key)
+		assert.Equal(t, 0, keyChannel.KeyIndex)
+	}
+
+	infos := channel.GetKeyInfos()
+	assert.Equal(t, model.ChannelKeyStatusEnabled, infos[0].Status)
+	assert.Equal(t, model.ChannelKeyStatusInvalid, infos[1].Status)
+	assert.True(t, infos[2].Cooldown)
+
+	model.CooldownChannelKey(channel.Id, "sk-a", 60)
+	_, err = channel.SelectKey()
+	assert.NotNil(t, err)
+}
+
+func TestChannelsChooserSkipExhaustedKeys(t *testing.T) {
+	weight := uint(1)
+	exhausted := &model.Channel{Id: 1101, Key: "sk-x\nsk-y", MultiKeyMode: model.ChannelMultiKeyRoundRobin, Weight: &weight}
+	healthy := &model.Channel{Id: 1102, Key: "sk-z", Weight: &weight}
+	chooser := &model.ChannelsChooser{
+		Channels: map[int]*model.ChannelChoice{
+			exhausted.Id: {Channel: exhausted},
+			healthy.Id:   {Channel: healthy},
+		},
+		Rule: map[string]map[string][][]int{
+			"default": {"gpt-4o": {{exhausted.Id}, {healthy.Id}}},
+		},
+	}
+
+	channel, err := chooser.Next("default", "gpt-4o")
+	assert.Nil(t, err)
+	assert.Equal(t, exhausted.Id, channel.Id)
+
+	// 所有密钥都在冷却中时选择下一个渠道
+	model.CooldownChannelKey(exhausted.Id, "sk-x", 60)
+	model.CooldownChannelKey(exhausted.Id, "sk-y", 60)
+	assert.False(t, exhausted.HasAvailableKey())
+	for i := 0; i < 3; i++ {
+		channel, err = chooser.Next("default", "gpt-4o")
+		assert.Nil(t, err)
+		assert.Equal(t, healthy.Id, channel.Id)
+	}
+}
+
+func TestDisableChannelKeyConcurrent(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.Nil(t, err)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	assert.Nil(t, db.AutoMigrate(&model.Channel{}))
+	originDB := model.DB
+	model.DB = db
+	defer func() { model.DB = originDB }()
+
+	keys := make([]string, 20)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("sk-%d", i)
+	}
+	channel := &model.Channel{Id: 1, Key: strings.Join(keys, "\n"), MultiKeyMode: model.ChannelMultiKeyRandom}
+	assert.Nil(t, db.Create(channel).Error)
+
+	// 并发禁用不同密钥时不会相互覆盖
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			_, err := model.DisableChannelKey(channel.Id, key, model.ChannelKeyStatusInvalid, "test")
+			assert.Nil(t, err)
+		}(key)
+	}
+	wg.Wait()
+
+	stored, err := model.GetChannelById(channel.Id)
+	assert.Nil(t, err)
+	assert.Len(t, stored.GetKeyStatus(), len(keys))
+}
@@ -0,0 +1,247 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/redis"
+	"strconv"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	channelStatsBucketSeconds = 10
+	channelStatsBuckets       = 30 // 5 分钟的滑动窗口
+	channelStatsMinSamples    = 10 // 样本数不足时不调整权重
+	channelStatsMinFactor     = 0.05
+)
+
+type channelStatsBucket struct {
+	Time     int64
+	Requests int64
+	Failures int64
+	Latency  int64 // 累计耗时，毫秒
+	TTFT     int64 // 累计首字时间，毫秒
+}
+
+// ChannelHealth 渠道在滑动窗口内的统计
+type ChannelHealth struct {
+	Requests    int64   `json:"requests"`
+	Failures    int64   `json:"failures"`
+	SuccessRate float64 `json:"success_rate"`
+	AvgLatency  int64   `json:"avg_latency"`
+	AvgTTFT     int64   `json:"avg_ttft"`
+}
+
+type ChannelStats struct {
+	sync.RWMutex
+	buckets  map[int]*[channelStatsBuckets]channelStatsBucket
+	snapshot map[int]*ChannelHealth // 开启 Redis 时，从 Redis 汇总的多节点数据
+}
+
+var ChannelHealthStats = NewChannelStats()
+
+func NewChannelStats() *ChannelStats {
+	return &ChannelStats{
+		buckets:  make(map[int]*[channelStatsBuckets]channelStatsBucket),
+		snapshot: make(map[int]*ChannelHealth),
+	}
+}
+
+func channelStatsBucketTime(t time.Time) int64 {
+	return t.Unix() / channelStatsBucketSeconds * channelStatsBucketSeconds
+}
+
+// Record 记录一次上游请求的结果，ttft 为首字时间，非流式请求与 latency 相同
+func (cs *ChannelStats) Record(channelId int, success bool, latency, ttft time.Duration) {
+	if channelId <= 0 {
+		return
+	}
+
+	bucketTime := channelStatsBucketTime(time.Now())
+	failures := int64(0)
+	if !success {
+		failures = 1
+	}
+
+	cs.Lock()
+	buckets, ok := cs.buckets[channelId]
+	if !ok {
+		buckets = &[channelStatsBuckets]channelStatsBucket{}
+		cs.buckets[channelId] = buckets
+	}
+	bucket := &buckets[(bucketTime/channelStatsBucketSeconds)%channelStatsBuckets]
+	if bucket.Time != bucketTime {
+		*bucket = channelStatsBucket{Time: bucketTime}
+	}
+	bucket.Requests++
+	bucket.Failures += failures
+	bucket.Latency += latency.Milliseconds()
+	bucket.TTFT += ttft.Milliseconds()
+	cs.Unlock()
+
+	if config.RedisEnabled {
+		go recordChannelStatsToRedis(channelId, bucketTime, failures, latency.Milliseconds(), ttft.Milliseconds())
+	}
+}
+
+// Get 获取渠道在滑动窗口内的统计，没有数据时返回 nil
+func (cs *ChannelStats) Get(channelId int) *ChannelHealth {
+	cs.RLock()
+	defer cs.RUnlock()
+
+	if config.RedisEnabled {
+		return cs.snapshot[channelId]
+	}
+
+	buckets, ok := cs.buckets[channelId]
+	if !ok {
+		return nil
+	}
+
+	total := channelStatsBucket{}
+	windowStart := channelStatsBucketTime(time.Now()) - (channelStatsBuckets-1)*channelStatsBucketSeconds
+	for _, bucket := range buckets {
+		if bucket.Time < windowStart {
+			continue
+		}
+		total.Requests += bucket.Requests
+		total.Failures += bucket.Failures
+		total.Latency += bucket.Latency
+		total.TTFT += bucket.TTFT
+	}
+
+	return newChannelHealth(total)
+}
+
+func newChannelHealth(total channelStatsBucket) *ChannelHealth {
+	if total.Requests == 0 {
+		return nil
+	}
+
+	return &ChannelHealth{
+		Requests:    total.Requests,
+		Failures:    total.Failures,
+		SuccessRate: float64(total.Requests-total.Failures) / float64(total.Requests),
+		AvgLatency:  total.Latency / total.Requests,
+		AvgTTFT:     total.TTFT / total.Requests,
+	}
+}
+
+// AdaptiveWeights 根据渠道近期的成功率和首字时间调整权重
+// 成功率按平方衰减，首字时间相对同一优先级内最快的渠道按平方根衰减，样本不足的渠道保持原权重
+func (cs *ChannelStats) AdaptiveWeights(choices []*ChannelChoice) []float64 {
+	healths := make([]*ChannelHealth, len(choices))
+	var bestTTFT int64
+	for i, choice := range choices {
+		health := cs.Get(choice.Channel.Id)
+		if health == nil || health.Requests < channelStatsMinSamples {
+			continue
+		}
+		healths[i] = health
+		if health.AvgTTFT > 0 && (bestTTFT == 0 || health.AvgTTFT < bestTTFT) {
+			bestTTFT = health.AvgTTFT
+		}
+	}
+
+	weights := make([]float64, len(choices))
+	for i, choice := range choices {
+		weights[i] = float64(*choice.Channel.Weight)
+		health := healths[i]
+		if health == nil {
+			continue
+		}
+
+		factor := health.SuccessRate * health.SuccessRate
+		if bestTTFT > 0 && health.AvgTTFT > 0 {
+			factor *= math.Sqrt(float64(bestTTFT) / float64(health.AvgTTFT))
+		}
+		weights[i] *= math.Max(factor, channelStatsMinFactor)
+	}
+
+	return weights
+}
+
+func channelStatsRedisKey(channelId int, bucketTime int64) string {
+	return fmt.Sprintf("channel_stats:%d:%d", channelId, bucketTime)
+}
+
+func recordChannelStatsToRedis(channelId int, bucketTime, failures, latency, ttft int64) {
+	ctx := context.Background()
+	key := channelStatsRedisKey(channelId, bucketTime)
+
+	pipe := redis.RDB.TxPipeline()
+	pipe.HIncrBy(ctx, key, "requests", 1)
+	if failures > 0 {
+		pipe.HIncrBy(ctx, key, "failures", failures)
+	}
+	pipe.HIncrBy(ctx, key, "latency", latency)
+	pipe.HIncrBy(ctx, key, "ttft", ttft)
+	pipe.Expire(ctx, key, time.Duration(channelStatsBuckets*channelStatsBucketSeconds)*time.Second)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.SysError("failed to record channel stats: " + err.Error())
+	}
+}
+
+// SyncFromRedis 从 Redis 汇总所有节点的渠道统计
+func (cs *ChannelStats) SyncFromRedis(channelIds []int) {
+	ctx := context.Background()
+	windowEnd := channelStatsBucketTime(time.Now())
+
+	pipe := redis.RDB.Pipeline()
+	results := make(map[int][]*goredis.MapStringStringCmd, len(channelIds))
+	for _, channelId := range channelIds {
+		for i := 0; i < channelStatsBuckets; i++ {
+			bucketTime := windowEnd - int64(i)*channelStatsBucketSeconds
+			results[channelId] = append(results[channelId], pipe.HGetAll(ctx, channelStatsRedisKey(channelId, bucketTime)))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.SysError("failed to sync channel stats: " + err.Error())
+		return
+	}
+
+	snapshot := make(map[int]*ChannelHealth, len(channelIds))
+	for channelId, cmds := range results {
+		total := channelStatsBucket{}
+		for _, cmd := range cmds {
+			values := cmd.Val()
+			total.Requests += parseRedisInt(values["requests"])
+			total.Failures += parseRedisInt(values["failures"])
+			total.Latency += parseRedisInt(values["latency"])
+			total.TTFT += parseRedisInt(values["ttft"])
+		}
+		if health := newChannelHealth(total); health != nil {
+			snapshot[channelId] = health
+		}
+	}
+
+	cs.Lock()
+	cs.snapshot = snapshot
+	cs.Unlock()
+}
+
+func parseRedisInt(value string) int64 {
+	if value == "" {
+		return 0
+	}
+	n, _ := strconv.ParseInt(value, 10, 64)
+	return n
+}
+
+// SyncChannelStats 开启 Redis 时，定期同步多节点的渠道统计
+func SyncChannelStats() {
+	if !config.RedisEnabled {
+		return
+	}
+
+	for {
+		ChannelHealthStats.SyncFromRedis(ChannelGroup.GetChannelIds())
+		time.Sleep(channelStatsBucketSeconds * time.Second)
+	}
+}
@@ -0,0 +1,52 @@
+package model_test
+
+import (
+	"one-api/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelStatsRecord(t *testing.T) {
+	stats := model.NewChannelStats()
+	assert.Nil(t, stats.Get(1))
+
+	stats.Record(1, true, 300*time.Millisecond, 100*time.Millisecond)
+	stats.Record(1, false, 100*time.Millisecond, 100*time.Millisecond)
+
+	health := stats.Get(1)
+	assert.Equal(t, int64(2), health.Requests)
+	assert.Equal(t, int64(1), health.Failures)
+	assert.Equal(t, 0.5, health.SuccessRate)
+	assert.Equal(t, int64(200), health.AvgLatency)
+	assert.Equal(t, int64(100), health.AvgTTFT)
+}
+
+func TestChannelStatsAdaptiveWeights(t *testing.T) {
+	weight := uint(100)
+	choices := []*model.ChannelChoice{
+		{Channel: &model.Channel{Id: 1, Weight: &weight}},
+		{Channel: &model.Channel{Id: 2, Weight: &weight}},
+		{Channel: &model.Channel{Id: 3, Weight: &weight}},
+		{Channel: &model.Channel{Id: 4, Weight: &weight}},
+	}
+
+	stats := model.NewChannelStats()
+	for i := 0; i < 20; i++ {
+		// 健康渠道
+		stats.Record(1, true, time.Second, 200*time.Millisecond)
+		// 首字时间是健康渠道的 4 倍
+		stats.Record(2, true, time.Second, 800*time.Millisecond)
+		// 全部失败
+		stats.Record(3, false, time.Second, 200*time.Millisecond)
+	}
+	// 样本不足
+	stats.Record(4, false, time.Second, time.Second)
+
+	weights := stats.AdaptiveWeights(choices)
+	assert.InDelta(t, 100, weights[0], 0.001)
+	assert.InDelta(t, 50, weights[1], 0.001)
+	assert.InDelta(t, 5, weights[2], 0.001)
+	assert.InDelta(t, 100, weights[3], 0.001)
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// ChatCacheVector 语义缓存的向量，Hash 对应 ChatCache 的 Hash
+// Scope 为除最后一条用户消息外的请求内容的哈希，只有上下文完全相同的请求才会比较相似度
+type ChatCacheVector struct {
+	Hash       string `json:"hash" gorm:"type:varchar(32);primaryKey"`
+	UserId     int    `json:"user_id" gorm:"type:int;not null;index:idx_chat_cache_vector_scope,priority:1"`
+	Scope      string `json:"scope" gorm:"type:varchar(32);not null;index:idx_chat_cache_vector_scope,priority:2"`
+	Vector     string `json:"vector" gorm:"type:text;not null"`
+	Expiration int64  `json:"expiration" gorm:"type:bigint;not null;index"`
+}
+
+func (vector *ChatCacheVector) Insert() error {
+	return DB.Clauses(clause.OnConflict{
+		UpdateAll: true,
+	}).Create(vector).Error
+}
+
+// GetChatCacheVectors 获取同一上下文下未过期的向量，按照过期时间倒序
+func GetChatCacheVectors(userId int, scope string, limit int) ([]*ChatCacheVector, error) {
+	var vectors []*ChatCacheVector
+	now := time.Now().Unix()
+	err := DB.Where("user_id = ? and scope = ? and expiration > ?", userId, scope, now).
+		Order("expiration desc").Limit(limit).Find(&vectors).Error
+	return vectors, err
+}
+
+func RemoveChatCacheVector() error {
+	now := time.Now().Unix()
+	return DB.Where("expiration < ?", now).Delete(ChatCacheVector{}).Error
+}
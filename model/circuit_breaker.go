@@ -0,0 +1,283 @@
+package model
+
+import (
+	"math/rand"
+	"one-api/common/config"
+	"one-api/common/metrics"
+	"sort"
+	"sync"
+	"time"
+)
+
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+type circuitKey struct {
+	ChannelId int
+	Model     string // 为空表示整个渠道
+}
+
+type circuitBreaker struct {
+	State     CircuitState
+	Failures  int // 连续失败次数
+	Successes int // 半开状态下连续成功次数
+	OpenedAt  int64
+	Probing   bool
+}
+
+type CircuitBreakerStatus struct {
+	ChannelId int          `json:"channel_id"`
+	Model     string       `json:"model"`
+	State     CircuitState `json:"state"`
+	Failures  int          `json:"failures"`
+	OpenedAt  int64        `json:"opened_at"`
+}
+
+// CircuitBreakers 渠道熔断器，分别维护渠道级别和渠道+模型级别的状态
+// closed: 正常放行；open: 全部拒绝；half_open: 按比例放行，或由探测请求决定是否恢复
+type CircuitBreakers struct {
+	sync.Mutex
+	breakers map[circuitKey]*circuitBreaker
+}
+
+var ChannelCircuitBreakers = NewCircuitBreakers()
+
+func NewCircuitBreakers() *CircuitBreakers {
+	return &CircuitBreakers{
+		breakers: make(map[circuitKey]*circuitBreaker),
+	}
+}
+
+// 熔断时间已过的 open 状态转为 half_open，调用方需持有锁
+func (cb *CircuitBreakers) refresh(breaker *circuitBreaker, now int64) {
+	if breaker.State == CircuitOpen && now >= breaker.OpenedAt+int64(config.CircuitBreakerOpenSeconds) {
+		breaker.State = CircuitHalfOpen
+		breaker.Successes = 0
+	}
+}
+
+func (cb *CircuitBreakers) allow(key circuitKey, now int64) bool {
+	breaker, ok := cb.breakers[key]
+	if !ok {
+		return true
+	}
+
+	cb.refresh(breaker, now)
+	switch breaker.State {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		return rand.Float64() < config.CircuitBreakerHalfOpenRatio
+	}
+	return true
+}
+
+// Allow 判断渠道是否允许处理该模型的请求
+func (cb *CircuitBreakers) Allow(channelId int, modelName string) bool {
+	if !config.CircuitBreakerEnabled {
+		return true
+	}
+
+	cb.Lock()
+	defer cb.Unlock()
+
+	now := time.Now().Unix()
+	if !cb.allow(circuitKey{ChannelId: channelId}, now) {
+		return false
+	}
+
+	return modelName == "" || cb.allow(circuitKey{ChannelId: channelId, Model: modelName}, now)
+}
+
+func (cb *CircuitBreakers) success(key circuitKey) {
+	breaker, ok := cb.breakers[key]
+	if !ok {
+		return
+	}
+
+	cb.refresh(breaker, time.Now().Unix())
+	switch breaker.State {
+	case CircuitHalfOpen:
+		breaker.Successes++
+		if breaker.Successes >= config.CircuitBreakerHalfOpenSuccesses {
+			delete(cb.breakers, key)
+		}
+	case CircuitClosed:
+		delete(cb.breakers, key)
+	}
+}
+
+func (cb *CircuitBreakers) failure(key circuitKey) {
+	now := time.Now().Unix()
+	breaker, ok := cb.breakers[key]
+	if !ok {
+		breaker = &circuitBreaker{State: CircuitClosed}
+		cb.breakers[key] = breaker
+	}
+
+	cb.refresh(breaker, now)
+	switch breaker.State {
+	case CircuitHalfOpen:
+		cb.open(key, breaker, now)
+	case CircuitClosed:
+		breaker.Failures++
+		if breaker.Failures >= config.CircuitBreakerFailureThreshold {
+			cb.open(key, breaker, now)
+		}
+	}
+}
+
+func (cb *CircuitBreakers) open(key circuitKey, breaker *circuitBreaker, now int64) {
+	if key.Model == "" {
+		metrics.RecordChannelDisabled(key.ChannelId, metrics.ChannelActionCircuitOpen)
+	}
+	breaker.State = CircuitOpen
+	breaker.OpenedAt = now
+	breaker.Successes = 0
+}
+
+// RecordSuccess 记录一次成功的请求，同时计入渠道级别和模型级别
+func (cb *CircuitBreakers) RecordSuccess(channelId int, modelName string) {
+	if !config.CircuitBreakerEnabled {
+		return
+	}
+
+	cb.Lock()
+	defer cb.Unlock()
+
+	cb.success(circuitKey{ChannelId: channelId})
+	if modelName != "" {
+		cb.success(circuitKey{ChannelId: channelId, Model: modelName})
+	}
+}
+
+// RecordFailure 记录一次失败的请求，channelWide 为 false 时只计入模型级别（例如模型不存在）
+func (cb *CircuitBreakers) RecordFailure(channelId int, modelName string, channelWide bool) {
+	if !config.CircuitBreakerEnabled {
+		return
+	}
+
+	cb.Lock()
+	defer cb.Unlock()
+
+	if channelWide {
+		cb.failure(circuitKey{ChannelId: channelId})
+	}
+	if modelName != "" {
+		cb.failure(circuitKey{ChannelId: channelId, Model: modelName})
+	}
+}
+
+// Trip 直接熔断整个渠道，用于余额不足、密钥失效等不可能自行恢复的错误
+func (cb *CircuitBreakers) Trip(channelId int) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	key := circuitKey{ChannelId: channelId}
+	breaker, ok := cb.breakers[key]
+	if !ok {
+		breaker = &circuitBreaker{}
+		cb.breakers[key] = breaker
+	}
+	breaker.Failures = config.CircuitBreakerFailureThreshold
+	cb.open(key, breaker, time.Now().Unix())
+}
+
+// Reset 清除渠道的所有熔断状态
+func (cb *CircuitBreakers) Reset(channelId int) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	for key := range cb.breakers {
+		if key.ChannelId == channelId {
+			delete(cb.breakers, key)
+		}
+	}
+}
+
+// StartProbes 取出处于半开状态且没有在探测中的熔断器，并标记为探测中
+func (cb *CircuitBreakers) StartProbes() []CircuitBreakerStatus {
+	cb.Lock()
+	defer cb.Unlock()
+
+	now := time.Now().Unix()
+	var probes []CircuitBreakerStatus
+	for key, breaker := range cb.breakers {
+		cb.refresh(breaker, now)
+		if breaker.State != CircuitHalfOpen || breaker.Probing {
+			continue
+		}
+		breaker.Probing = true
+		probes = append(probes, newCircuitBreakerStatus(key, breaker))
+	}
+
+	return probes
+}
+
+// FinishProbe 记录探测结果，探测成功直接恢复，失败则重新熔断
+func (cb *CircuitBreakers) FinishProbe(channelId int, modelName string, success bool) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	key := circuitKey{ChannelId: channelId, Model: modelName}
+	breaker, ok := cb.breakers[key]
+	if !ok {
+		return
+	}
+	breaker.Probing = false
+
+	if breaker.State != CircuitHalfOpen {
+		return
+	}
+	if success {
+		delete(cb.breakers, key)
+	} else {
+		cb.open(key, breaker, time.Now().Unix())
+	}
+}
+
+// CancelProbe 探测无法进行时（例如未配置测速模型），仅清除探测标记
+func (cb *CircuitBreakers) CancelProbe(channelId int, modelName string) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	if breaker, ok := cb.breakers[circuitKey{ChannelId: channelId, Model: modelName}]; ok {
+		breaker.Probing = false
+	}
+}
+
+func (cb *CircuitBreakers) List() []CircuitBreakerStatus {
+	cb.Lock()
+	defer cb.Unlock()
+
+	now := time.Now().Unix()
+	list := make([]CircuitBreakerStatus, 0, len(cb.breakers))
+	for key, breaker := range cb.breakers {
+		cb.refresh(breaker, now)
+		list = append(list, newCircuitBreakerStatus(key, breaker))
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].ChannelId != list[j].ChannelId {
+			return list[i].ChannelId < list[j].ChannelId
+		}
+		return list[i].Model < list[j].Model
+	})
+
+	return list
+}
+
+func newCircuitBreakerStatus(key circuitKey, breaker *circuitBreaker) CircuitBreakerStatus {
+	return CircuitBreakerStatus{
+		ChannelId: key.ChannelId,
+		Model:     key.Model,
+		State:     breaker.State,
+		Failures:  breaker.Failures,
+		OpenedAt:  breaker.OpenedAt,
+	}
+}
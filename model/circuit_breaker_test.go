@@ -0,0 +1,83 @@
+package model_test
+
+import (
+	"one-api/common/config"
+	"one-api/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakers(t *testing.T) {
+	config.CircuitBreakerEnabled = true
+	config.CircuitBreakerFailureThreshold = 3
+	config.CircuitBreakerOpenSeconds = 0
+	config.CircuitBreakerHalfOpenRatio = 1
+	config.CircuitBreakerHalfOpenSuccesses = 2
+	defer func() {
+		config.CircuitBreakerEnabled = false
+		config.CircuitBreakerOpenSeconds = 60
+		config.CircuitBreakerHalfOpenRatio = 0.1
+	}()
+
+	breakers := model.NewCircuitBreakers()
+	breakers.RecordFailure(1, "gpt-4o", true)
+	breakers.RecordFailure(1, "gpt-4o", true)
+	breakers.RecordSuccess(1, "gpt-4o")
+	breakers.RecordFailure(1, "gpt-4o", true)
+	breakers.RecordFailure(1, "gpt-4o", true)
+	// 成功后连续失败次数重新计算
+	assert.Equal(t, model.CircuitClosed, breakers.List()[0].State)
+	assert.Equal(t, 2, breakers.List()[0].Failures)
+
+	breakers.RecordFailure(1, "gpt-4o", true)
+	list := breakers.List()
+	assert.Len(t, list, 2)
+	// 熔断时间为 0，立即进入半开状态
+	assert.Equal(t, model.CircuitHalfOpen, list[0].State)
+	assert.True(t, breakers.Allow(1, "gpt-4o"))
+
+	// 半开状态下失败重新熔断
+	config.CircuitBreakerOpenSeconds = 60
+	breakers.RecordFailure(1, "gpt-4o", true)
+	assert.False(t, breakers.Allow(1, "gpt-4o"))
+	assert.False(t, breakers.Allow(1, "gpt-4o-mini"))
+
+	// 半开状态下连续成功后恢复
+	config.CircuitBreakerOpenSeconds = 0
+	breakers.RecordSuccess(1, "gpt-4o")
+	assert.NotEmpty(t, breakers.List())
+	breakers.RecordSuccess(1, "gpt-4o")
+	assert.Empty(t, breakers.List())
+
+	// 仅熔断模型
+	config.CircuitBreakerOpenSeconds = 60
+	for i := 0; i < 3; i++ {
+		breakers.RecordFailure(2, "dall-e-3", false)
+	}
+	assert.False(t, breakers.Allow(2, "dall-e-3"))
+	assert.True(t, breakers.Allow(2, "gpt-4o"))
+}
+
+func TestCircuitBreakersProbe(t *testing.T) {
+	config.CircuitBreakerEnabled = true
+	config.CircuitBreakerOpenSeconds = 0
+	defer func() {
+		config.CircuitBreakerEnabled = false
+		config.CircuitBreakerOpenSeconds = 60
+	}()
+
+	breakers := model.NewCircuitBreakers()
+	breakers.Trip(1)
+
+	probes := breakers.StartProbes()
+	assert.Len(t, probes, 1)
+	// 探测中的不重复探测
+	assert.Empty(t, breakers.StartProbes())
+
+	breakers.FinishProbe(1, "", false)
+	assert.Len(t, breakers.StartProbes(), 1)
+
+	breakers.FinishProbe(1, "", true)
+	assert.Empty(t, breakers.List())
+}
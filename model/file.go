@@ -0,0 +1,125 @@
+package model
+
+import (
+	"errors"
+	"one-api/common/logger"
+	"one-api/common/storage"
+	"one-api/common/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	FilePurposeBatch       = "batch"
+	FilePurposeBatchOutput = "batch_output"
+)
+
+// File 网关保存的文件，内容保存在文件存储中，只有上传者可以访问
+type File struct {
+	ID         int            `json:"id"`
+	FileID     string         `json:"file_id" gorm:"type:varchar(50);uniqueIndex"`
+	UserId     int            `json:"user_id" gorm:"index"`
+	Filename   string         `json:"filename" gorm:"type:varchar(255)"`
+	Purpose    string         `json:"purpose" gorm:"type:varchar(32);index"`
+	Bytes      int64          `json:"bytes"`
+	Drive      string         `json:"drive" gorm:"type:varchar(32)"` // 保存文件的存储
+	StorageKey string         `json:"-" gorm:"type:varchar(255)"`
+	CreatedAt  int64          `json:"created_at" gorm:"bigint"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// SaveFile 将内容写入文件存储并保存文件信息
+func SaveFile(userId int, filename, purpose string, data []byte) (*File, error) {
+	fileID := "file-" + utils.GetRandomString(24)
+	storageKey := "files/" + fileID
+
+	drive, err := storage.Save(data, storageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{
+		FileID:     fileID,
+		UserId:     userId,
+		Filename:   filename,
+		Purpose:    purpose,
+		Bytes:      int64(len(data)),
+		Drive:      drive,
+		StorageKey: storageKey,
+	}
+	if err := DB.Create(file).Error; err != nil {
+		if deleteErr := storage.Delete(drive, storageKey); deleteErr != nil {
+			logger.SysError("failed to delete file " + fileID + ": " + deleteErr.Error())
+		}
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// GetUserFile 获取用户的文件，不存在时返回 nil
+func GetUserFile(userId int, fileID string) (*File, error) {
+	file := &File{}
+	err := DB.Where("user_id = ? and file_id = ?", userId, fileID).First(file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return file, err
+}
+
+type FileListParams struct {
+	Purpose string `form:"purpose"`
+	Limit   int    `form:"limit"`
+	After   string `form:"after"`
+	Order   string `form:"order"`
+}
+
+// GetUserFiles 按照创建时间排序获取用户的文件，after 为上一页最后一个文件的 ID
+func GetUserFiles(userId int, params *FileListParams) ([]*File, error) {
+	tx := DB.Where("user_id = ?", userId)
+	if params.Purpose != "" {
+		tx = tx.Where("purpose = ?", params.Purpose)
+	}
+
+	order := "id desc"
+	if params.Order == "asc" {
+		order = "id asc"
+	}
+
+	if params.After != "" {
+		after, err := GetUserFile(userId, params.After)
+		if err != nil {
+			return nil, err
+		}
+		if after == nil {
+			return nil, errors.New("文件不存在")
+		}
+		if params.Order == "asc" {
+			tx = tx.Where("id > ?", after.ID)
+		} else {
+			tx = tx.Where("id < ?", after.ID)
+		}
+	}
+
+	var files []*File
+	err := tx.Order(order).Limit(params.Limit).Find(&files).Error
+	return files, err
+}
+
+func (f *File) Content() ([]byte, error) {
+	return storage.Download(f.Drive, f.StorageKey)
+}
+
+// Delete 删除文件信息和存储中的内容，存储删除失败时只记录日志
+func (f *File) Delete() error {
+	if err := DB.Delete(f).Error; err != nil {
+		return err
+	}
+
+	if err := storage.Delete(f.Drive, f.StorageKey); err != nil {
+		logger.SysError("failed to delete file " + f.FileID + ": " + err.Error())
+	}
+
+	return nil
+}
@@ -34,6 +34,7 @@ const (
 	LogTypeConsume
 	LogTypeManage
 	LogTypeSystem
+	LogTypeViolation // 请求内容违反内容策略
 )
 
 func RecordLog(userId int, logType int, content string) {
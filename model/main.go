@@ -154,6 +154,10 @@ func InitDB() (err error) {
 		if err != nil {
 			return err
 		}
+		err = db.AutoMigrate(&ChatCacheVector{})
+		if err != nil {
+			return err
+		}
 		err = db.AutoMigrate(&Payment{})
 		if err != nil {
 			return err
@@ -166,6 +170,30 @@ func InitDB() (err error) {
 		if err != nil {
 			return err
 		}
+		err = db.AutoMigrate(&SubscriptionPlan{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&Subscription{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&AuditLog{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&PromptTemplate{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&File{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&RelayObject{})
+		if err != nil {
+			return err
+		}
 		logger.SysLog("database migrated")
 		err = createRootAccountIfNeed()
 		return err
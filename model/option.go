@@ -70,17 +70,37 @@ func InitOptionMap() {
 	config.OptionMap["QuotaRemindThreshold"] = strconv.Itoa(config.QuotaRemindThreshold)
 	config.OptionMap["PreConsumedQuota"] = strconv.Itoa(config.PreConsumedQuota)
 	config.OptionMap["GroupRatio"] = common.GroupRatio2JSONString()
+	config.OptionMap["GroupRateLimit"] = common.GroupRateLimit2JSONString()
+	config.OptionMap["GroupHedgeDelay"] = common.GroupHedgeDelay2JSONString()
+	config.OptionMap["GroupModelFallback"] = common.GroupModelFallback2JSONString()
+	config.OptionMap["GroupContentPolicy"] = common.GroupContentPolicy2JSONString()
+	config.OptionMap["GroupRedaction"] = common.GroupRedaction2JSONString()
+	config.OptionMap["ChannelBalanceStrategy"] = common.ChannelBalanceStrategy2JSONString()
 	config.OptionMap["TopUpLink"] = config.TopUpLink
 	config.OptionMap["ChatLink"] = config.ChatLink
 	config.OptionMap["ChatLinks"] = config.ChatLinks
 	config.OptionMap["QuotaPerUnit"] = strconv.FormatFloat(config.QuotaPerUnit, 'f', -1, 64)
 	config.OptionMap["RetryTimes"] = strconv.Itoa(config.RetryTimes)
 	config.OptionMap["RetryCooldownSeconds"] = strconv.Itoa(config.RetryCooldownSeconds)
+	config.OptionMap["ChannelQueueTimeoutSeconds"] = strconv.Itoa(config.ChannelQueueTimeoutSeconds)
+	config.OptionMap["BatchDiscountRatio"] = strconv.FormatFloat(config.BatchDiscountRatio, 'f', -1, 64)
+	config.OptionMap["BatchMaxRequests"] = strconv.Itoa(config.BatchMaxRequests)
+	config.OptionMap["CircuitBreakerEnabled"] = strconv.FormatBool(config.CircuitBreakerEnabled)
+	config.OptionMap["CircuitBreakerFailureThreshold"] = strconv.Itoa(config.CircuitBreakerFailureThreshold)
+	config.OptionMap["CircuitBreakerOpenSeconds"] = strconv.Itoa(config.CircuitBreakerOpenSeconds)
+	config.OptionMap["CircuitBreakerHalfOpenRatio"] = strconv.FormatFloat(config.CircuitBreakerHalfOpenRatio, 'f', -1, 64)
+	config.OptionMap["CircuitBreakerHalfOpenSuccesses"] = strconv.Itoa(config.CircuitBreakerHalfOpenSuccesses)
+
+	config.OptionMap["AuditLogEnabled"] = strconv.FormatBool(config.AuditLogEnabled)
+	config.OptionMap["AuditLogRetentionDays"] = strconv.Itoa(config.AuditLogRetentionDays)
 
 	config.OptionMap["MjNotifyEnabled"] = strconv.FormatBool(config.MjNotifyEnabled)
 
 	config.OptionMap["ChatCacheEnabled"] = strconv.FormatBool(config.ChatCacheEnabled)
 	config.OptionMap["ChatCacheExpireMinute"] = strconv.Itoa(config.ChatCacheExpireMinute)
+	config.OptionMap["ChatCacheSemanticEnabled"] = strconv.FormatBool(config.ChatCacheSemanticEnabled)
+	config.OptionMap["ChatCacheEmbeddingModel"] = config.ChatCacheEmbeddingModel
+	config.OptionMap["ChatCacheEmbeddingChannelId"] = strconv.Itoa(config.ChatCacheEmbeddingChannelId)
 
 	config.OptionMap["ChatImageRequestProxy"] = ""
 
@@ -140,6 +160,14 @@ var optionIntMap = map[string]*int{
 	"RetryCooldownSeconds":  &config.RetryCooldownSeconds,
 	"ChatCacheExpireMinute": &config.ChatCacheExpireMinute,
 	"PaymentMinAmount":      &config.PaymentMinAmount,
+
+	"AuditLogRetentionDays":           &config.AuditLogRetentionDays,
+	"CircuitBreakerFailureThreshold":  &config.CircuitBreakerFailureThreshold,
+	"CircuitBreakerOpenSeconds":       &config.CircuitBreakerOpenSeconds,
+	"CircuitBreakerHalfOpenSuccesses": &config.CircuitBreakerHalfOpenSuccesses,
+	"ChatCacheEmbeddingChannelId":     &config.ChatCacheEmbeddingChannelId,
+	"ChannelQueueTimeoutSeconds":      &config.ChannelQueueTimeoutSeconds,
+	"BatchMaxRequests":                &config.BatchMaxRequests,
 }
 
 var optionBoolMap = map[string]*bool{
@@ -160,6 +188,9 @@ var optionBoolMap = map[string]*bool{
 	"DisplayTokenStatEnabled":        &config.DisplayTokenStatEnabled,
 	"MjNotifyEnabled":                &config.MjNotifyEnabled,
 	"ChatCacheEnabled":               &config.ChatCacheEnabled,
+	"ChatCacheSemanticEnabled":       &config.ChatCacheSemanticEnabled,
+	"CircuitBreakerEnabled":          &config.CircuitBreakerEnabled,
+	"AuditLogEnabled":                &config.AuditLogEnabled,
 }
 
 var optionStringMap = map[string]*string{
@@ -186,6 +217,7 @@ var optionStringMap = map[string]*string{
 	"ChatImageRequestProxy":       &config.ChatImageRequestProxy,
 	"CFWorkerImageUrl":            &config.CFWorkerImageUrl,
 	"CFWorkerImageKey":            &config.CFWorkerImageKey,
+	"ChatCacheEmbeddingModel":     &config.ChatCacheEmbeddingModel,
 }
 
 func updateOptionMap(key string, value string) (err error) {
@@ -212,10 +244,26 @@ func updateOptionMap(key string, value string) (err error) {
 		config.EmailDomainWhitelist = strings.Split(value, ",")
 	case "GroupRatio":
 		err = common.UpdateGroupRatioByJSONString(value)
+	case "GroupRateLimit":
+		err = common.UpdateGroupRateLimitByJSONString(value)
+	case "GroupHedgeDelay":
+		err = common.UpdateGroupHedgeDelayByJSONString(value)
+	case "GroupModelFallback":
+		err = common.UpdateGroupModelFallbackByJSONString(value)
+	case "GroupContentPolicy":
+		err = common.UpdateGroupContentPolicyByJSONString(value)
+	case "GroupRedaction":
+		err = common.UpdateGroupRedactionByJSONString(value)
+	case "ChannelBalanceStrategy":
+		err = common.UpdateChannelBalanceStrategyByJSONString(value)
 	case "ChannelDisableThreshold":
 		config.ChannelDisableThreshold, _ = strconv.ParseFloat(value, 64)
 	case "QuotaPerUnit":
 		config.QuotaPerUnit, _ = strconv.ParseFloat(value, 64)
+	case "CircuitBreakerHalfOpenRatio":
+		config.CircuitBreakerHalfOpenRatio, _ = strconv.ParseFloat(value, 64)
+	case "BatchDiscountRatio":
+		config.BatchDiscountRatio, _ = strconv.ParseFloat(value, 64)
 	case "PaymentUSDRate":
 		config.PaymentUSDRate, _ = strconv.ParseFloat(value, 64)
 	case "RechargeDiscount":
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -25,6 +26,7 @@ type Order struct {
 	OrderAmount   float64        `json:"order_amount" gorm:"type:decimal(10,2);default:0"`
 	OrderCurrency CurrencyType   `json:"order_currency" gorm:"type:varchar(16)"`
 	Quota         int            `json:"quota" gorm:"type:int;default:0"`
+	PlanId        int            `json:"plan_id" gorm:"default:0"` // 订阅套餐订单，为 0 表示普通充值
 	Fee           float64        `json:"fee" gorm:"type:decimal(10,2);default:0"`
 	Discount      float64        `json:"discount" gorm:"type:decimal(10,2);default:0"`
 	Status        OrderStatus    `json:"status" gorm:"type:varchar(32)"`
@@ -60,6 +62,49 @@ func (o *Order) Update() error {
 	return DB.Save(o).Error
 }
 
+// FulfillOrder 在同一事务中将待支付订单标记为成功并开通套餐或增加额度，
+// 任一步失败都会回滚，订单保持待支付以便支付网关重试；订单已处理过时返回 false
+func FulfillOrder(order *Order, gatewayNo string) (bool, error) {
+	var plan *SubscriptionPlan
+	if order.PlanId > 0 {
+		var err error
+		plan, err = GetSubscriptionPlanByID(order.PlanId)
+		if err != nil {
+			return false, errors.New("套餐不存在")
+		}
+	}
+
+	var subscription *Subscription
+	renew := false
+	fulfilled := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Order{}).
+			Where("id = ? AND status = ?", order.ID, OrderStatusPending).
+			Updates(map[string]any{"gateway_no": gatewayNo, "status": OrderStatusSuccess})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		fulfilled = true
+
+		if plan != nil {
+			var err error
+			subscription, renew, err = activateSubscription(tx, order.UserId, plan)
+			return err
+		}
+		return tx.Model(&User{}).Where("id = ?", order.UserId).Update("quota", gorm.Expr("quota + ?", order.Quota)).Error
+	})
+	if err != nil || !fulfilled {
+		return false, err
+	}
+
+	order.GatewayNo = gatewayNo
+	order.Status = OrderStatusSuccess
+	if plan != nil {
+		afterActivateSubscription(subscription, plan, renew)
+	}
+	return true, nil
+}
+
 var allowedOrderFields = map[string]bool{
 	"id":         true,
 	"gateway_id": true,
@@ -2,8 +2,10 @@ package model
 
 import (
 	"one-api/common/config"
+	"strings"
 
 	"github.com/shopspring/decimal"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -21,6 +23,34 @@ type Price struct {
 	ChannelType int     `json:"channel_type" gorm:"default:0" binding:"gte=0"`
 	Input       float64 `json:"input" gorm:"default:0" binding:"gte=0"`
 	Output      float64 `json:"output" gorm:"default:0" binding:"gte=0"`
+
+	// 分项价格，为 0 时按照输入或输出价格计费
+	CachedInput      float64 `json:"cached_input" gorm:"default:0" binding:"gte=0"`
+	CachedWriteInput float64 `json:"cached_write_input" gorm:"default:0" binding:"gte=0"`
+	AudioInput       float64 `json:"audio_input" gorm:"default:0" binding:"gte=0"`
+	ImageInput       float64 `json:"image_input" gorm:"default:0" binding:"gte=0"`
+	AudioOutput      float64 `json:"audio_output" gorm:"default:0" binding:"gte=0"`
+	Reasoning        float64 `json:"reasoning" gorm:"default:0" binding:"gte=0"`
+
+	Tiers *datatypes.JSONType[[]PriceTier] `json:"tiers,omitempty" gorm:"type:json"`
+
+	// 图片价格矩阵，匹配时按张计费，不再使用输入输出价格
+	ImagePrices *datatypes.JSONType[[]ImagePrice] `json:"image_prices,omitempty" gorm:"type:json"`
+}
+
+// ImagePrice 图片价格矩阵中的一项，为空的条件匹配任意值，Price 为每张图片的价格，单位与按次计费相同
+type ImagePrice struct {
+	Size    string  `json:"size,omitempty"`
+	Quality string  `json:"quality,omitempty"`
+	Style   string  `json:"style,omitempty"`
+	Price   float64 `json:"price"`
+}
+
+// PriceTier 按上下文长度分级计费，输入 token 数超过 Tokens 时，输入和输出价格分别乘以对应倍率
+type PriceTier struct {
+	Tokens      int     `json:"tokens"`
+	InputRatio  float64 `json:"input_ratio"`
+	OutputRatio float64 `json:"output_ratio"`
 }
 
 func GetAllPrices() ([]*Price, error) {
@@ -62,6 +92,106 @@ func (price *Price) GetOutput() float64 {
 	return price.Output
 }
 
+func (price *Price) GetCachedInput() float64 {
+	return price.getInputComponent(price.CachedInput)
+}
+
+func (price *Price) GetCachedWriteInput() float64 {
+	return price.getInputComponent(price.CachedWriteInput)
+}
+
+func (price *Price) GetAudioInput() float64 {
+	return price.getInputComponent(price.AudioInput)
+}
+
+func (price *Price) GetImageInput() float64 {
+	return price.getInputComponent(price.ImageInput)
+}
+
+func (price *Price) GetAudioOutput() float64 {
+	return price.getOutputComponent(price.AudioOutput)
+}
+
+func (price *Price) GetReasoning() float64 {
+	return price.getOutputComponent(price.Reasoning)
+}
+
+func (price *Price) getInputComponent(value float64) float64 {
+	if value <= 0 {
+		return price.GetInput()
+	}
+	return value
+}
+
+func (price *Price) getOutputComponent(value float64) float64 {
+	if price.Type == TimesPriceType {
+		return 0
+	}
+	if value <= 0 {
+		return price.GetOutput()
+	}
+	return value
+}
+
+func (price *Price) GetTiers() []PriceTier {
+	if price.Tiers == nil {
+		return nil
+	}
+	return price.Tiers.Data()
+}
+
+// GetTierRatio 获取输入 token 数对应的分级倍率，匹配阈值最高的一级，倍率为 0 时按 1 计算
+func (price *Price) GetTierRatio(promptTokens int) (inputRatio, outputRatio float64) {
+	inputRatio, outputRatio = 1, 1
+
+	matched := 0
+	for _, tier := range price.GetTiers() {
+		if promptTokens <= tier.Tokens || tier.Tokens <= matched {
+			continue
+		}
+		matched = tier.Tokens
+		inputRatio, outputRatio = 1, 1
+		if tier.InputRatio > 0 {
+			inputRatio = tier.InputRatio
+		}
+		if tier.OutputRatio > 0 {
+			outputRatio = tier.OutputRatio
+		}
+	}
+
+	return inputRatio, outputRatio
+}
+
+// GetImagePrice 获取图片的单价，多项匹配时使用条件最多的一项
+func (price *Price) GetImagePrice(size, quality, style string) (float64, bool) {
+	if price.ImagePrices == nil {
+		return 0, false
+	}
+
+	matched := -1
+	imagePrice := 0.0
+	for _, item := range price.ImagePrices.Data() {
+		conditions := 0
+		for _, condition := range [][2]string{{item.Size, size}, {item.Quality, quality}, {item.Style, style}} {
+			if condition[0] == "" {
+				continue
+			}
+			if !strings.EqualFold(condition[0], condition[1]) {
+				conditions = -1
+				break
+			}
+			conditions++
+		}
+
+		if conditions > matched {
+			matched = conditions
+			imagePrice = max(item.Price, 0)
+		}
+	}
+
+	return imagePrice, matched >= 0
+}
+
 func (price *Price) FetchInputCurrencyPrice(rate float64) string {
 	r := decimal.NewFromFloat(price.GetInput()).Mul(decimal.NewFromFloat(rate))
 	return r.String()
@@ -75,10 +205,18 @@ func (price *Price) FetchOutputCurrencyPrice(rate float64) string {
 func UpdatePrices(tx *gorm.DB, models []string, prices *Price) error {
 	err := tx.Model(Price{}).Where("model IN (?)", models).Select("*").Omit("model").Updates(
 		Price{
-			Type:        prices.Type,
-			ChannelType: prices.ChannelType,
-			Input:       prices.Input,
-			Output:      prices.Output,
+			Type:             prices.Type,
+			ChannelType:      prices.ChannelType,
+			Input:            prices.Input,
+			Output:           prices.Output,
+			CachedInput:      prices.CachedInput,
+			CachedWriteInput: prices.CachedWriteInput,
+			AudioInput:       prices.AudioInput,
+			ImageInput:       prices.ImageInput,
+			AudioOutput:      prices.AudioOutput,
+			Reasoning:        prices.Reasoning,
+			Tiers:            prices.Tiers,
+			ImagePrices:      prices.ImagePrices,
 		}).Error
 
 	return err
@@ -0,0 +1,38 @@
+package model_test
+
+import (
+	"one-api/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestPriceGetImagePrice(t *testing.T) {
+	price := &model.Price{Model: "dall-e-3", Type: model.TimesPriceType, Input: 20}
+	_, ok := price.GetImagePrice("1024x1024", "standard", "")
+	assert.False(t, ok)
+
+	imagePrices := datatypes.NewJSONType([]model.ImagePrice{
+		{Price: 20},
+		{Size: "1792x1024", Price: 40},
+		{Size: "1024x1792", Price: 40},
+		{Quality: "hd", Price: 40},
+		{Size: "1792x1024", Quality: "hd", Price: 60},
+	})
+	price.ImagePrices = &imagePrices
+
+	imagePrice, ok := price.GetImagePrice("1024x1024", "standard", "vivid")
+	assert.True(t, ok)
+	assert.Equal(t, 20.0, imagePrice)
+
+	imagePrice, _ = price.GetImagePrice("1792x1024", "standard", "")
+	assert.Equal(t, 40.0, imagePrice)
+
+	imagePrice, _ = price.GetImagePrice("1024x1024", "HD", "")
+	assert.Equal(t, 40.0, imagePrice)
+
+	// 多项匹配时使用条件最多的一项
+	imagePrice, _ = price.GetImagePrice("1792x1024", "hd", "natural")
+	assert.Equal(t, 60.0, imagePrice)
+}
@@ -0,0 +1,159 @@
+package model
+
+import (
+	"one-api/common/logger"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PromptTemplateModePrepend = "prepend" // 插入到系统提示词之前
+	PromptTemplateModeAppend  = "append"  // 追加到系统提示词之后
+	PromptTemplateModeReplace = "replace" // 替换客户端的系统提示词
+)
+
+// PromptTemplate 托管的系统提示词模板，按分组或令牌注入到聊天请求中
+type PromptTemplate struct {
+	ID          int            `json:"id"`
+	Name        string         `json:"name" form:"name" gorm:"type:varchar(255);not null"`
+	Description string         `json:"description" form:"description" gorm:"type:text"`
+	Mode        string         `json:"mode" form:"mode" gorm:"type:varchar(16);default:'prepend'"`
+	Content     string         `json:"content" form:"content" gorm:"type:text"`
+	Groups      string         `json:"groups" form:"groups" gorm:"type:varchar(255);default:''"` // 生效的分组，多个用逗号分隔，为空则只对绑定的令牌生效
+	Sort        int            `json:"sort" form:"sort" gorm:"default:1"`                        // 同一分组有多个模板时，按照从大到小的顺序应用
+	Enable      *bool          `json:"enable" form:"enable" gorm:"default:true"`
+	CreatedAt   int64          `json:"created_at" gorm:"bigint"`
+	UpdatedAt   int64          `json:"-" gorm:"bigint"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+var allowedPromptTemplateOrderFields = map[string]bool{
+	"id":         true,
+	"name":       true,
+	"mode":       true,
+	"sort":       true,
+	"enable":     true,
+	"created_at": true,
+}
+
+func GetPromptTemplateList(params *GenericParams) (*DataResult[PromptTemplate], error) {
+	var templates []*PromptTemplate
+	db := DB
+	if params.Keyword != "" {
+		db = db.Where("name LIKE ?", params.Keyword+"%")
+	}
+
+	return PaginateAndOrder(db, &params.PaginationParams, &templates, allowedPromptTemplateOrderFields)
+}
+
+func GetPromptTemplateByID(id int) (*PromptTemplate, error) {
+	var template PromptTemplate
+	err := DB.First(&template, id).Error
+	return &template, err
+}
+
+func (t *PromptTemplate) Insert() error {
+	if err := DB.Create(t).Error; err != nil {
+		return err
+	}
+	PromptTemplates.Load()
+	return nil
+}
+
+func (t *PromptTemplate) Update() error {
+	err := DB.Model(t).Select("name", "description", "mode", "content", "groups", "sort", "enable").Updates(t).Error
+	if err != nil {
+		return err
+	}
+	PromptTemplates.Load()
+	return nil
+}
+
+func (t *PromptTemplate) Delete() error {
+	if err := DB.Delete(t).Error; err != nil {
+		return err
+	}
+	PromptTemplates.Load()
+	return nil
+}
+
+func (t *PromptTemplate) IsEnabled() bool {
+	return t.Enable == nil || *t.Enable
+}
+
+// PromptTemplateCache 已启用的模板，修改后以及同步渠道时重新加载
+type PromptTemplateCache struct {
+	sync.RWMutex
+	templates map[int]*PromptTemplate
+	groups    map[string][]*PromptTemplate
+}
+
+var PromptTemplates = &PromptTemplateCache{}
+
+func (cache *PromptTemplateCache) Load() {
+	var templates []*PromptTemplate
+	if err := DB.Where("enable = ?", true).Order("sort desc, id asc").Find(&templates).Error; err != nil {
+		logger.SysError("failed to load prompt templates: " + err.Error())
+		return
+	}
+
+	cache.SetTemplates(templates)
+}
+
+// SetTemplates 替换缓存中的模板
+func (cache *PromptTemplateCache) SetTemplates(templates []*PromptTemplate) {
+	templateMap := make(map[int]*PromptTemplate, len(templates))
+	groups := make(map[string][]*PromptTemplate)
+	for _, template := range templates {
+		if !template.IsEnabled() {
+			continue
+		}
+		templateMap[template.ID] = template
+		seen := make(map[string]bool)
+		for _, group := range strings.Split(template.Groups, ",") {
+			group = strings.TrimSpace(group)
+			if group == "" || seen[group] {
+				continue
+			}
+			seen[group] = true
+			groups[group] = append(groups[group], template)
+		}
+	}
+
+	for _, items := range groups {
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Sort > items[j].Sort
+		})
+	}
+
+	cache.Lock()
+	defer cache.Unlock()
+	cache.templates = templateMap
+	cache.groups = groups
+}
+
+// Match 获取请求需要应用的模板，分组的模板始终生效
+// 令牌绑定的模板在分组模板之前应用，替换模式的令牌模板不会移除分组模板注入的内容
+func (cache *PromptTemplateCache) Match(templateId int, group string) []*PromptTemplate {
+	cache.RLock()
+	defer cache.RUnlock()
+
+	groupTemplates := cache.groups[group]
+	template, ok := cache.templates[templateId]
+	if templateId <= 0 || !ok {
+		return groupTemplates
+	}
+
+	templates := make([]*PromptTemplate, 0, len(groupTemplates)+1)
+	templates = append(templates, template)
+	for _, item := range groupTemplates {
+		if item.ID != templateId {
+			templates = append(templates, item)
+		}
+	}
+
+	return templates
+}
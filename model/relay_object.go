@@ -0,0 +1,80 @@
+package model
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	RelayObjectAssistant   = "assistant"
+	RelayObjectThread      = "thread"
+	RelayObjectVectorStore = "vector_store"
+	RelayObjectFile        = "file"
+)
+
+// RelayObject 记录通过中继创建的上游对象（assistants、threads、vector_stores、files），
+// 对象只存在于创建它的渠道中，后续请求需要转发到同一个渠道，并且只有创建者可以访问
+type RelayObject struct {
+	ID        int    `json:"id"`
+	ObjectID  string `json:"object_id" gorm:"type:varchar(100);uniqueIndex"`
+	Object    string `json:"object" gorm:"type:varchar(32)"`
+	UserId    int    `json:"user_id" gorm:"index"`
+	ChannelId int    `json:"channel_id" gorm:"index"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint"`
+}
+
+// InsertRelayObject 记录新创建的对象，已经记录过的对象不会被覆盖
+func InsertRelayObject(objectID, object string, userId, channelId int) error {
+	return DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&RelayObject{
+		ObjectID:  objectID,
+		Object:    object,
+		UserId:    userId,
+		ChannelId: channelId,
+	}).Error
+}
+
+// GetRelayObject 获取对象的记录，不存在时返回 nil
+func GetRelayObject(objectID string) (*RelayObject, error) {
+	object := &RelayObject{}
+	err := DB.Where("object_id = ?", objectID).First(object).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return object, err
+}
+
+// GetRelayObjects 批量获取对象的记录，未记录的对象不在结果中
+func GetRelayObjects(objectIDs []string) (map[string]*RelayObject, error) {
+	objects := make(map[string]*RelayObject)
+	if len(objectIDs) == 0 {
+		return objects, nil
+	}
+
+	var items []*RelayObject
+	if err := DB.Where("object_id in ?", objectIDs).Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		objects[item.ObjectID] = item
+	}
+	return objects, nil
+}
+
+// GetUserLastRelayObject 获取用户最近创建的某类对象，不存在时返回 nil
+func GetUserLastRelayObject(userId int, object string) (*RelayObject, error) {
+	relayObject := &RelayObject{}
+	err := DB.Where("user_id = ? and object = ?", userId, object).Order("id desc").First(relayObject).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return relayObject, err
+}
+
+func DeleteRelayObject(objectID string) error {
+	return DB.Where("object_id = ?", objectID).Delete(&RelayObject{}).Error
+}
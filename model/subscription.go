@@ -0,0 +1,287 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/common/utils"
+
+	"gorm.io/gorm"
+)
+
+// 订阅额度的发放周期，单位为秒
+const SubscriptionGrantInterval int64 = 30 * 24 * 3600
+
+type SubscriptionPlan struct {
+	ID           int            `json:"id"`
+	Name         string         `json:"name" form:"name" gorm:"type:varchar(255);not null"`
+	Description  string         `json:"description" form:"description" gorm:"type:text"`
+	Amount       int            `json:"amount" form:"amount" gorm:"default:0"`                 // 价格，与充值金额的单位一致
+	Quota        int            `json:"quota" form:"quota" gorm:"default:0"`                   // 每个周期（30天）发放的额度
+	Group        string         `json:"group" form:"group" gorm:"type:varchar(32);default:''"` // 订阅期间使用的分组，为空则不变更
+	DurationDays int            `json:"duration_days" form:"duration_days" gorm:"default:30"`  // 每次购买的订阅时长
+	Sort         int            `json:"sort" form:"sort" gorm:"default:1"`
+	Enable       *bool          `json:"enable" form:"enable" gorm:"default:true"`
+	CreatedAt    int64          `json:"created_at" gorm:"bigint"`
+	UpdatedAt    int64          `json:"-" gorm:"bigint"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type SubscriptionStatus string
+
+const (
+	SubscriptionStatusActive  SubscriptionStatus = "active"
+	SubscriptionStatusExpired SubscriptionStatus = "expired"
+)
+
+type Subscription struct {
+	ID            int                `json:"id"`
+	UserId        int                `json:"user_id" gorm:"index"`
+	PlanId        int                `json:"plan_id"`
+	Status        SubscriptionStatus `json:"status" gorm:"type:varchar(16);index"`
+	OriginalGroup string             `json:"original_group" gorm:"type:varchar(32);default:''"` // 订阅前的分组，到期后恢复
+	Group         string             `json:"group" gorm:"type:varchar(32);default:''"`          // 开通时套餐的分组，套餐修改或删除后仍按此恢复
+	StartTime     int64              `json:"start_time" gorm:"bigint"`
+	ExpiredTime   int64              `json:"expired_time" gorm:"bigint;index"`
+	NextGrantTime int64              `json:"next_grant_time" gorm:"bigint;index"`
+	CreatedAt     int64              `json:"created_at" gorm:"bigint"`
+	UpdatedAt     int64              `json:"-" gorm:"bigint"`
+
+	Plan *SubscriptionPlan `json:"plan,omitempty" gorm:"foreignKey:PlanId"`
+}
+
+var allowedSubscriptionPlanOrderFields = map[string]bool{
+	"id":         true,
+	"name":       true,
+	"amount":     true,
+	"sort":       true,
+	"enable":     true,
+	"created_at": true,
+}
+
+func GetSubscriptionPlanList(params *GenericParams) (*DataResult[SubscriptionPlan], error) {
+	var plans []*SubscriptionPlan
+	db := DB
+	if params.Keyword != "" {
+		db = db.Where("name LIKE ?", params.Keyword+"%")
+	}
+
+	return PaginateAndOrder(db, &params.PaginationParams, &plans, allowedSubscriptionPlanOrderFields)
+}
+
+func GetUserSubscriptionPlanList() ([]*SubscriptionPlan, error) {
+	var plans []*SubscriptionPlan
+	err := DB.Where("enable = ?", true).Order("sort desc").Find(&plans).Error
+	return plans, err
+}
+
+func GetSubscriptionPlanByID(id int) (*SubscriptionPlan, error) {
+	var plan SubscriptionPlan
+	err := DB.First(&plan, id).Error
+	return &plan, err
+}
+
+func (p *SubscriptionPlan) Insert() error {
+	return DB.Create(p).Error
+}
+
+func (p *SubscriptionPlan) Update() error {
+	return DB.Model(p).Select("name", "description", "amount", "quota", "group", "duration_days", "sort", "enable").Updates(p).Error
+}
+
+func (p *SubscriptionPlan) Delete() error {
+	return DB.Delete(p).Error
+}
+
+// 套餐是软删除的，已开通的订阅需要读取已删除的套餐
+func withDeletedPlan(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+func GetUserActiveSubscription(userId int) (*Subscription, error) {
+	var subscription Subscription
+	err := DB.Preload("Plan", withDeletedPlan).Where("user_id = ? AND status = ?", userId, SubscriptionStatusActive).First(&subscription).Error
+	return &subscription, err
+}
+
+// ActivateSubscription 开通或续订套餐，同一套餐顺延到期时间，不同套餐则替换当前订阅
+func ActivateSubscription(userId int, planId int) (*Subscription, error) {
+	plan, err := GetSubscriptionPlanByID(planId)
+	if err != nil {
+		return nil, errors.New("套餐不存在")
+	}
+
+	subscription := &Subscription{}
+	renew := false
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		subscription, renew, err = activateSubscription(tx, userId, plan)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	afterActivateSubscription(subscription, plan, renew)
+	return subscription, nil
+}
+
+// activateSubscription 在事务中开通或续订套餐，提交后需调用 afterActivateSubscription
+func activateSubscription(tx *gorm.DB, userId int, plan *SubscriptionPlan) (*Subscription, bool, error) {
+	now := utils.GetTimestamp()
+	duration := int64(plan.DurationDays) * 24 * 3600
+	subscription := &Subscription{}
+
+	err := tx.Where("user_id = ? AND status = ?", userId, SubscriptionStatusActive).First(subscription).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	if err == nil && subscription.PlanId == plan.ID {
+		subscription.ExpiredTime += duration
+		return subscription, true, tx.Save(subscription).Error
+	}
+
+	var originalGroup string
+	if err == nil {
+		// 更换套餐，保留最初的分组以便到期后恢复
+		originalGroup = subscription.OriginalGroup
+		subscription.Status = SubscriptionStatusExpired
+		subscription.ExpiredTime = now
+		if err := tx.Save(subscription).Error; err != nil {
+			return nil, false, err
+		}
+	} else {
+		if err := tx.Model(&User{}).Where("id = ?", userId).Select(quotePostgresField("group")).Find(&originalGroup).Error; err != nil {
+			return nil, false, err
+		}
+	}
+
+	subscription = &Subscription{
+		UserId:        userId,
+		PlanId:        plan.ID,
+		Status:        SubscriptionStatusActive,
+		OriginalGroup: originalGroup,
+		Group:         plan.Group,
+		StartTime:     now,
+		ExpiredTime:   now + duration,
+		NextGrantTime: now,
+	}
+	if err := tx.Create(subscription).Error; err != nil {
+		return nil, false, err
+	}
+
+	group := plan.Group
+	if group == "" {
+		group = originalGroup
+	}
+	return subscription, false, tx.Model(&User{}).Where("id = ?", userId).Update("group", group).Error
+}
+
+func afterActivateSubscription(subscription *Subscription, plan *SubscriptionPlan, renew bool) {
+	CacheDeleteUserGroup(subscription.UserId)
+	if renew {
+		RecordLog(subscription.UserId, LogTypeTopup, fmt.Sprintf("续订套餐 %s，到期时间顺延 %d 天", plan.Name, plan.DurationDays))
+	} else {
+		RecordLog(subscription.UserId, LogTypeTopup, fmt.Sprintf("开通套餐 %s，有效期 %d 天", plan.Name, plan.DurationDays))
+		grantSubscriptionQuota(subscription, plan)
+	}
+}
+
+// ProcessSubscriptions 发放到期的周期额度，并将过期的订阅降级回原分组
+func ProcessSubscriptions() {
+	now := utils.GetTimestamp()
+
+	var dueSubscriptions []*Subscription
+	err := DB.Preload("Plan", withDeletedPlan).Where("status = ? AND next_grant_time <= ? AND next_grant_time < expired_time", SubscriptionStatusActive, now).Find(&dueSubscriptions).Error
+	if err != nil {
+		logger.SysError("failed to fetch due subscriptions: " + err.Error())
+	}
+	for _, subscription := range dueSubscriptions {
+		if subscription.Plan == nil {
+			logger.SysError(fmt.Sprintf("subscription #%d plan #%d not found, skip granting quota", subscription.ID, subscription.PlanId))
+			continue
+		}
+		grantSubscriptionQuota(subscription, subscription.Plan)
+	}
+
+	var expiredSubscriptions []*Subscription
+	err = DB.Preload("Plan", withDeletedPlan).Where("status = ? AND expired_time <= ?", SubscriptionStatusActive, now).Find(&expiredSubscriptions).Error
+	if err != nil {
+		logger.SysError("failed to fetch expired subscriptions: " + err.Error())
+	}
+	for _, subscription := range expiredSubscriptions {
+		expireSubscription(subscription)
+	}
+}
+
+// 发放一个周期的额度，通过 next_grant_time 做乐观锁，避免多节点重复发放
+func grantSubscriptionQuota(subscription *Subscription, plan *SubscriptionPlan) {
+	granted := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Subscription{}).
+			Where("id = ? AND next_grant_time = ?", subscription.ID, subscription.NextGrantTime).
+			Update("next_grant_time", subscription.NextGrantTime+SubscriptionGrantInterval)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		granted = true
+		if plan.Quota <= 0 {
+			return nil
+		}
+		return tx.Model(&User{}).Where("id = ?", subscription.UserId).Update("quota", gorm.Expr("quota + ?", plan.Quota)).Error
+	})
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to grant subscription #%d quota: %s", subscription.ID, err.Error()))
+		return
+	}
+	if !granted || plan.Quota <= 0 {
+		return
+	}
+
+	subscription.NextGrantTime += SubscriptionGrantInterval
+	if err := CacheUpdateUserQuota(subscription.UserId); err != nil {
+		logger.SysError("failed to update user quota cache: " + err.Error())
+	}
+	RecordLog(subscription.UserId, LogTypeTopup, fmt.Sprintf("套餐 %s 发放额度 %s", plan.Name, common.LogQuota(plan.Quota)))
+}
+
+func expireSubscription(subscription *Subscription) {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Subscription{}).
+			Where("id = ? AND status = ?", subscription.ID, SubscriptionStatusActive).
+			Update("status", SubscriptionStatusExpired)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		// 只有分组仍是套餐分组时才恢复，避免覆盖管理员的手动调整
+		group := subscription.getGroup()
+		if group == "" {
+			return nil
+		}
+		return tx.Model(&User{}).
+			Where("id = ? AND "+quotePostgresField("group")+" = ?", subscription.UserId, group).
+			Update("group", subscription.OriginalGroup).Error
+	})
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to expire subscription #%d: %s", subscription.ID, err.Error()))
+		return
+	}
+
+	CacheDeleteUserGroup(subscription.UserId)
+	planName := ""
+	if subscription.Plan != nil {
+		planName = subscription.Plan.Name
+	}
+	RecordLog(subscription.UserId, LogTypeSystem, fmt.Sprintf("套餐 %s 已到期", planName))
+}
+
+// getGroup 订阅期间使用的分组，旧的订阅没有记录分组时使用套餐的分组
+func (s *Subscription) getGroup() string {
+	if s.Group != "" || s.Plan == nil {
+		return s.Group
+	}
+	return s.Plan.Group
+}
@@ -0,0 +1,82 @@
+package model_test
+
+import (
+	"one-api/common/utils"
+	"one-api/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestExpireSubscriptionWithDeletedPlan(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, db.AutoMigrate(&model.User{}, &model.SubscriptionPlan{}, &model.Subscription{}, &model.Log{}))
+	originDB := model.DB
+	model.DB = db
+	defer func() { model.DB = originDB }()
+
+	user := &model.User{Username: "subscriber", Group: "default"}
+	assert.Nil(t, db.Create(user).Error)
+	plan := &model.SubscriptionPlan{Name: "vip", Group: "vip", DurationDays: 30}
+	assert.Nil(t, plan.Insert())
+
+	subscription, err := model.ActivateSubscription(user.Id, plan.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "vip", subscription.Group)
+	group, _ := model.GetUserGroup(user.Id)
+	assert.Equal(t, "vip", group)
+
+	// 删除套餐后订阅到期，仍然恢复原分组
+	assert.Nil(t, plan.Delete())
+	assert.Nil(t, db.Model(subscription).Update("expired_time", utils.GetTimestamp()-1).Error)
+	model.ProcessSubscriptions()
+
+	group, _ = model.GetUserGroup(user.Id)
+	assert.Equal(t, "default", group)
+	expired := &model.Subscription{}
+	assert.Nil(t, db.First(expired, subscription.ID).Error)
+	assert.Equal(t, model.SubscriptionStatusExpired, expired.Status)
+}
+
+func TestFulfillOrderRollsBackOnFailure(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, db.AutoMigrate(&model.User{}, &model.SubscriptionPlan{}, &model.Subscription{}, &model.Order{}, &model.Log{}))
+	originDB := model.DB
+	model.DB = db
+	defer func() { model.DB = originDB }()
+
+	user := &model.User{Username: "buyer", Group: "default"}
+	assert.Nil(t, db.Create(user).Error)
+	order := &model.Order{UserId: user.Id, TradeNo: "trade-1", Quota: 100, Status: model.OrderStatusPending}
+	assert.Nil(t, order.Insert())
+
+	// 开通失败时订单保持待支付，网关重试时仍可处理
+	assert.Nil(t, db.Migrator().DropTable(&model.Subscription{}))
+	plan := &model.SubscriptionPlan{Name: "vip", Group: "vip", DurationDays: 30}
+	assert.Nil(t, plan.Insert())
+	planOrder := &model.Order{UserId: user.Id, TradeNo: "trade-2", PlanId: plan.ID, Status: model.OrderStatusPending}
+	assert.Nil(t, planOrder.Insert())
+	_, err = model.FulfillOrder(planOrder, "gw-2")
+	assert.NotNil(t, err)
+	stored, _ := model.GetOrderByTradeNo("trade-2")
+	assert.Equal(t, model.OrderStatusPending, stored.Status)
+
+	fulfilled, err := model.FulfillOrder(order, "gw-1")
+	assert.Nil(t, err)
+	assert.True(t, fulfilled)
+	stored, _ = model.GetOrderByTradeNo("trade-1")
+	assert.Equal(t, model.OrderStatusSuccess, stored.Status)
+	quota, _ := model.GetUserQuota(user.Id)
+	assert.Equal(t, 100, quota)
+
+	// 重复回调不会重复充值
+	fulfilled, err = model.FulfillOrder(order, "gw-1")
+	assert.Nil(t, err)
+	assert.False(t, fulfilled)
+	quota, _ = model.GetUserQuota(user.Id)
+	assert.Equal(t, 100, quota)
+}
@@ -8,7 +8,8 @@ import (
 )
 
 const (
-	TaskPlatformSuno = "suno"
+	TaskPlatformSuno  = "suno"
+	TaskPlatformBatch = "batch"
 )
 
 type TaskStatus string
@@ -21,6 +22,9 @@ const (
 	TaskStatusFailure               = "FAILURE"
 	TaskStatusSuccess               = "SUCCESS"
 	TaskStatusUnknown               = "UNKNOWN"
+	TaskStatusCancelling            = "CANCELLING" // 批处理任务取消中
+	TaskStatusCancelled             = "CANCELLED"
+	TaskStatusExpired               = "EXPIRED" // 批处理任务未在完成窗口内完成
 )
 
 type Task struct {
@@ -62,6 +66,30 @@ func GetTaskByTaskId(platform string, userId int, taskId string) (task *Task, er
 	return
 }
 
+// GetUserTasks 按照创建时间倒序获取用户的任务，afterId 大于 0 时只返回比它更早的任务
+func GetUserTasks(platform string, userId int, afterId int64, limit int) (tasks []*Task, err error) {
+	tx := DB.Where("platform = ? and user_id = ?", platform, userId)
+	if afterId > 0 {
+		tx = tx.Where("id < ?", afterId)
+	}
+	err = tx.Order("id desc").Limit(limit).Find(&tasks).Error
+
+	return
+}
+
+// GetTaskStatus 只查询任务的状态
+func GetTaskStatus(id int64) (TaskStatus, error) {
+	task := &Task{}
+	err := DB.Select("status").Where("id = ?", id).First(task).Error
+	return task.Status, err
+}
+
+// UpdateTaskIfStatus 任务处于指定状态时才更新，返回是否更新成功
+func UpdateTaskIfStatus(id int64, statuses []TaskStatus, params map[string]any) (bool, error) {
+	result := DB.Model(&Task{}).Where("id = ? and status in (?)", id, statuses).Updates(params)
+	return result.RowsAffected > 0, result.Error
+}
+
 func (Task *Task) Insert() error {
 	return DB.Create(Task).Error
 }
@@ -3,12 +3,16 @@ package model
 import (
 	"errors"
 	"fmt"
+	"net"
+	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/common/redis"
 	"one-api/common/stmp"
 	"one-api/common/utils"
+	"strings"
 
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -25,6 +29,94 @@ type Token struct {
 	UnlimitedQuota bool   `json:"unlimited_quota" gorm:"default:false"`
 	UsedQuota      int    `json:"used_quota" gorm:"default:0"` // used quota
 	ChatCache      bool   `json:"chat_cache" gorm:"default:false"`
+
+	Setting *datatypes.JSONType[TokenSetting] `json:"setting" form:"setting" gorm:"type:json"`
+}
+
+type TokenSetting struct {
+	Models       []string `json:"models,omitempty"`        // 允许调用的模型，为空则不限制，支持 * 结尾的通配
+	DeniedModels []string `json:"denied_models,omitempty"` // 禁止调用的模型，优先级高于 models
+	AllowIPs     []string `json:"allow_ips,omitempty"`     // 允许访问的 IP 或 CIDR，为空则不限制
+	RPM          int      `json:"rpm,omitempty"`           // 每分钟请求数限制，0 表示不限制
+	TPM          int      `json:"tpm,omitempty"`           // 每分钟 tokens 限制，0 表示不限制
+	Audit        bool     `json:"audit,omitempty"`         // 记录完整的请求与响应，需管理员开启审计功能
+
+	SemanticCacheThreshold float64 `json:"semantic_cache_threshold,omitempty"` // 语义缓存的相似度阈值，0 表示只使用精确缓存
+	HedgeDelay             int     `json:"hedge_delay,omitempty"`              // 对冲请求的等待时间，单位毫秒，0 表示使用分组设置
+
+	Redaction        *common.RedactionSetting `json:"redaction,omitempty"`          // 脱敏设置，与分组设置合并后生效
+	PromptTemplateId int                      `json:"prompt_template_id,omitempty"` // 绑定的提示词模板，由管理员设置，与分组的模板一起应用
+}
+
+// IsIPAllowed 判断客户端 IP 是否在令牌的白名单内
+func (s *TokenSetting) IsIPAllowed(clientIP string) bool {
+	if len(s.AllowIPs) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+
+	for _, allowIP := range s.AllowIPs {
+		if strings.Contains(allowIP, "/") {
+			_, ipNet, err := net.ParseCIDR(allowIP)
+			if err == nil && ipNet.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		if allowed := net.ParseIP(allowIP); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *TokenSetting) HasModelLimit() bool {
+	return len(s.Models) > 0 || len(s.DeniedModels) > 0
+}
+
+// IsModelAllowed 判断令牌是否允许调用该模型
+func (s *TokenSetting) IsModelAllowed(modelName string) bool {
+	if matchModelPatterns(s.DeniedModels, modelName) {
+		return false
+	}
+
+	if len(s.Models) == 0 {
+		return true
+	}
+
+	return matchModelPatterns(s.Models, modelName)
+}
+
+func matchModelPatterns(patterns []string, modelName string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(modelName, strings.TrimRight(pattern, "*")) {
+				return true
+			}
+			continue
+		}
+
+		if pattern == modelName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (token *Token) GetSetting() *TokenSetting {
+	if token.Setting == nil {
+		return &TokenSetting{}
+	}
+
+	setting := token.Setting.Data()
+	return &setting
 }
 
 var allowedTokenOrderFields = map[string]bool{
@@ -137,7 +229,7 @@ func (token *Token) Update() error {
 		token.ChatCache = false
 	}
 
-	err := DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota", "chat_cache").Updates(token).Error
+	err := DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota", "chat_cache", "setting").Updates(token).Error
 	// 防止Redis缓存不生效，直接删除
 	if err == nil && config.RedisEnabled {
 		redis.RedisDel(fmt.Sprintf("token:%s", token.Key))
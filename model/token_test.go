@@ -0,0 +1,39 @@
+package model_test
+
+import (
+	"one-api/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenSettingIsModelAllowed(t *testing.T) {
+	setting := &model.TokenSetting{}
+	assert.True(t, setting.IsModelAllowed("gpt-4o"))
+
+	setting = &model.TokenSetting{
+		Models:       []string{"gpt-4*", "claude-3-5-sonnet"},
+		DeniedModels: []string{"gpt-4-32k*"},
+	}
+	assert.True(t, setting.IsModelAllowed("gpt-4o"))
+	assert.True(t, setting.IsModelAllowed("claude-3-5-sonnet"))
+	assert.False(t, setting.IsModelAllowed("claude-3-5-sonnet-20240620"))
+	assert.False(t, setting.IsModelAllowed("gpt-4-32k-0613"))
+	assert.False(t, setting.IsModelAllowed("gpt-3.5-turbo"))
+
+	setting = &model.TokenSetting{DeniedModels: []string{"dall-e-3"}}
+	assert.True(t, setting.IsModelAllowed("gpt-4o"))
+	assert.False(t, setting.IsModelAllowed("dall-e-3"))
+}
+
+func TestTokenSettingIsIPAllowed(t *testing.T) {
+	setting := &model.TokenSetting{}
+	assert.True(t, setting.IsIPAllowed("8.8.8.8"))
+
+	setting = &model.TokenSetting{AllowIPs: []string{"10.0.0.0/8", "192.168.1.10", "2001:db8::/32"}}
+	assert.True(t, setting.IsIPAllowed("10.1.2.3"))
+	assert.True(t, setting.IsIPAllowed("192.168.1.10"))
+	assert.True(t, setting.IsIPAllowed("2001:db8::1"))
+	assert.False(t, setting.IsIPAllowed("192.168.1.11"))
+	assert.False(t, setting.IsIPAllowed("invalid"))
+}
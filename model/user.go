@@ -37,6 +37,7 @@ type User struct {
 	AffQuota         int            `json:"aff_quota" gorm:"type:int;default:0;column:aff_quota"`
 	AffHistoryQuota  int            `json:"aff_history_quota" gorm:"type:int;default:0;column:aff_history"`
 	InviterId        int            `json:"inviter_id" gorm:"type:int;column:inviter_id;index"`
+	AuditEnabled     *bool          `json:"audit_enabled,omitempty" gorm:"default:false"` // 记录该用户完整的请求与响应
 	CreatedTime      int64          `json:"created_time" gorm:"bigint"`
 	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
 }
@@ -325,6 +326,15 @@ func IsUserEnabled(userId int) (bool, error) {
 	return user.Status == config.UserStatusEnabled, nil
 }
 
+func IsUserAuditEnabled(userId int) (bool, error) {
+	var user User
+	err := DB.Where("id = ?", userId).Select("audit_enabled").Find(&user).Error
+	if err != nil {
+		return false, err
+	}
+	return user.AuditEnabled != nil && *user.AuditEnabled, nil
+}
+
 func ValidateAccessToken(token string) (user *User) {
 	if token == "" {
 		return nil
@@ -0,0 +1,145 @@
+package stripe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: RequestTimeout * time.Second}
+
+type Client struct {
+	SecretKey     string `json:"secret_key"`
+	WebhookSecret string `json:"webhook_secret"`
+	APIBase       string `json:"api_base"` // 可选，默认为 https://api.stripe.com
+}
+
+// CreateCheckoutSession 创建 Checkout Session
+func (c *Client) CreateCheckoutSession(args *CheckoutSessionArgs) (*CheckoutSession, error) {
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("client_reference_id", args.TradeNo)
+	form.Set("metadata[trade_no]", args.TradeNo)
+	form.Set("success_url", args.SuccessURL)
+	form.Set("cancel_url", args.CancelURL)
+	form.Set("expires_at", strconv.FormatInt(time.Now().Unix()+CheckoutSessionExpires, 10))
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", args.Currency)
+	form.Set("line_items[0][price_data][unit_amount]", strconv.FormatInt(args.UnitAmount, 10))
+	form.Set("line_items[0][price_data][product_data][name]", args.Name)
+
+	req, err := http.NewRequest(http.MethodPost, c.getAPIBase()+CheckoutSessionsURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.SecretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// 同一订单重复提交时，Stripe 会返回同一个 Session
+	req.Header.Set("Idempotency-Key", args.TradeNo)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp ErrorResponse
+		if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
+			return nil, fmt.Errorf("stripe create checkout session failed: %s", errResp.Error.Message)
+		}
+		return nil, fmt.Errorf("stripe create checkout session failed: status code %d", resp.StatusCode)
+	}
+
+	var session CheckoutSession
+	if err := json.Unmarshal(body, &session); err != nil {
+		return nil, err
+	}
+	if session.URL == "" {
+		return nil, errors.New("stripe create checkout session failed: empty url")
+	}
+
+	return &session, nil
+}
+
+// VerifyWebhook 校验 Stripe-Signature 并解析事件
+// https://docs.stripe.com/webhooks#verify-manually
+func (c *Client) VerifyWebhook(payload []byte, signatureHeader string) (*Event, error) {
+	timestamp, signatures := parseSignatureHeader(signatureHeader)
+	if timestamp == "" || len(signatures) == 0 {
+		return nil, errors.New("invalid signature header")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid signature timestamp")
+	}
+	if diff := time.Now().Unix() - ts; diff > SignatureTolerance || diff < -SignatureTolerance {
+		return nil, errors.New("signature timestamp out of tolerance")
+	}
+
+	expected := c.Sign(timestamp, payload)
+	verified := false
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("signature mismatch")
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// Sign 计算 webhook 签名
+func (c *Client) Sign(timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(c.WebhookSecret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c *Client) getAPIBase() string {
+	if c.APIBase == "" {
+		return DefaultAPIBase
+	}
+	return strings.TrimSuffix(c.APIBase, "/")
+}
+
+func parseSignatureHeader(header string) (timestamp string, signatures []string) {
+	for _, item := range strings.Split(header, ",") {
+		parts := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch parts[0] {
+		case "t":
+			timestamp = parts[1]
+		case "v1":
+			signatures = append(signatures, parts[1])
+		}
+	}
+	return
+}
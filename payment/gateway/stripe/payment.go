@@ -0,0 +1,118 @@
+package stripe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	sysconfig "one-api/common/config"
+	"one-api/payment/types"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Stripe struct{}
+
+type StripeConfig struct {
+	Client
+}
+
+func (s *Stripe) Name() string {
+	return "Stripe"
+}
+
+func (s *Stripe) Pay(config *types.PayConfig, gatewayConfig string) (*types.PayRequest, error) {
+	stripeConfig, err := getStripeConfig(gatewayConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := strings.ToLower(config.Currency)
+	if currency == "" {
+		currency = "usd"
+	}
+
+	session, err := stripeConfig.CreateCheckoutSession(&CheckoutSessionArgs{
+		TradeNo:    config.TradeNo,
+		Name:       sysconfig.SystemName + "-Token充值:" + strconv.FormatFloat(config.Money, 'f', 2, 64),
+		Currency:   currency,
+		UnitAmount: int64(math.Round(config.Money * 100)),
+		SuccessURL: config.ReturnURL,
+		CancelURL:  config.ReturnURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payRequest := &types.PayRequest{
+		Type: 1,
+		Data: types.PayRequestData{
+			URL:    session.URL,
+			Method: http.MethodGet,
+		},
+	}
+
+	return payRequest, nil
+}
+
+func (s *Stripe) HandleCallback(c *gin.Context, gatewayConfig string) (*types.PayNotify, error) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.String(http.StatusBadRequest, "fail")
+		return nil, err
+	}
+
+	stripeConfig, err := getStripeConfig(gatewayConfig)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "fail")
+		return nil, err
+	}
+
+	event, err := stripeConfig.VerifyWebhook(payload, c.GetHeader(SignatureHeader))
+	if err != nil {
+		c.String(http.StatusBadRequest, "fail")
+		return nil, fmt.Errorf("verify webhook failed: %v", err)
+	}
+
+	// 其他事件和未完成支付的事件直接确认，避免 Stripe 重复推送
+	if event.Type != EventSessionCompleted && event.Type != EventAsyncPaymentPaid {
+		c.String(http.StatusOK, "success")
+		return nil, fmt.Errorf("event: %s, ignored", event.Type)
+	}
+
+	session := event.Data.Object
+	if session.PaymentStatus != PaymentStatusPaid {
+		c.String(http.StatusOK, "success")
+		return nil, fmt.Errorf("tradeNo: %s, PaymentNo: %s, payment status: %s", session.ClientReferenceID, session.ID, session.PaymentStatus)
+	}
+
+	// 支付成功的事件在订单处理完成后由 RespondCallback 响应
+	return &types.PayNotify{
+		TradeNo:   session.ClientReferenceID,
+		GatewayNo: session.ID,
+		Amount:    float64(session.AmountTotal) / 100,
+		Currency:  session.Currency,
+	}, nil
+}
+
+// RespondCallback 订单处理失败时返回 500，Stripe 会在之后重试推送
+func (s *Stripe) RespondCallback(c *gin.Context, err error) {
+	if err != nil {
+		c.String(http.StatusInternalServerError, "fail")
+		return
+	}
+	c.String(http.StatusOK, "success")
+}
+
+func getStripeConfig(gatewayConfig string) (*StripeConfig, error) {
+	var stripeConfig StripeConfig
+	if err := json.Unmarshal([]byte(gatewayConfig), &stripeConfig); err != nil {
+		return nil, errors.New("config error")
+	}
+
+	return &stripeConfig, nil
+}
@@ -0,0 +1,92 @@
+package stripe_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"one-api/payment/gateway/stripe"
+	"one-api/payment/types"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupStripeStubServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, stripe.CheckoutSessionsURL, r.URL.Path)
+		assert.Equal(t, "Bearer sk_test", r.Header.Get("Authorization"))
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "usd", r.PostForm.Get("line_items[0][price_data][currency]"))
+		assert.Equal(t, "1050", r.PostForm.Get("line_items[0][price_data][unit_amount]"))
+		// 有效期需要留出余量，避免因为延迟低于 Stripe 要求的 30 分钟
+		expiresAt, _ := strconv.ParseInt(r.PostForm.Get("expires_at"), 10, 64)
+		assert.Greater(t, expiresAt-time.Now().Unix(), int64(30*60))
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":                  "cs_test_123",
+			"url":                 "https://checkout.stripe.com/c/pay/cs_test_123",
+			"client_reference_id": r.PostForm.Get("client_reference_id"),
+		})
+	}))
+}
+
+func getGatewayConfig(apiBase string) string {
+	return fmt.Sprintf(`{"secret_key":"sk_test","webhook_secret":"whsec_test","api_base":"%s"}`, apiBase)
+}
+
+func TestStripePay(t *testing.T) {
+	server := setupStripeStubServer(t)
+	defer server.Close()
+
+	gateway := &stripe.Stripe{}
+	payRequest, err := gateway.Pay(&types.PayConfig{
+		TradeNo:   "trade_1",
+		Money:     10.5,
+		Currency:  "USD",
+		ReturnURL: "http://localhost/panel/log",
+	}, getGatewayConfig(server.URL))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, payRequest.Type)
+	assert.Equal(t, "https://checkout.stripe.com/c/pay/cs_test_123", payRequest.Data.URL)
+}
+
+func TestStripeHandleCallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	gateway := &stripe.Stripe{}
+	client := &stripe.Client{WebhookSecret: "whsec_test"}
+
+	payload := []byte(`{"id":"evt_1","type":"checkout.session.completed","data":{"object":{"id":"cs_test_123","client_reference_id":"trade_1","payment_status":"paid","currency":"usd","amount_total":1050}}}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/payment/notify/uuid", bytes.NewReader(payload))
+	c.Request.Header.Set(stripe.SignatureHeader, "t="+timestamp+",v1="+client.Sign(timestamp, payload))
+
+	payNotify, err := gateway.HandleCallback(c, getGatewayConfig(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "trade_1", payNotify.TradeNo)
+	assert.Equal(t, "cs_test_123", payNotify.GatewayNo)
+	assert.Equal(t, 10.5, payNotify.Amount)
+	assert.Equal(t, "usd", payNotify.Currency)
+
+	// 订单处理失败时返回非 2xx，Stripe 会重试推送
+	gateway.RespondCallback(c, errors.New("failed to update order"))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/payment/notify/uuid", bytes.NewReader(payload))
+	c.Request.Header.Set(stripe.SignatureHeader, "t="+timestamp+",v1=invalid")
+
+	_, err = gateway.HandleCallback(c, getGatewayConfig(""))
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
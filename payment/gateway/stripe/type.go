@@ -0,0 +1,47 @@
+package stripe
+
+const (
+	DefaultAPIBase         = "https://api.stripe.com"
+	CheckoutSessionsURL    = "/v1/checkout/sessions"
+	SignatureHeader        = "Stripe-Signature"
+	SignatureTolerance     = 300 // 签名时间戳允许的误差，单位为秒
+	PaymentStatusPaid      = "paid"
+	EventSessionCompleted  = "checkout.session.completed"
+	EventAsyncPaymentPaid  = "checkout.session.async_payment_succeeded"
+	CheckoutSessionExpires = 31 * 60 // Checkout Session 有效期，单位为秒，Stripe 要求至少 30 分钟，预留网络延迟和时钟误差
+	RequestTimeout         = 30      // 请求 Stripe API 的超时时间，单位为秒
+)
+
+type CheckoutSessionArgs struct {
+	TradeNo    string
+	Name       string
+	Currency   string
+	UnitAmount int64 // 以货币的最小单位计，如美分
+	SuccessURL string
+	CancelURL  string
+}
+
+type CheckoutSession struct {
+	ID                string `json:"id"`
+	URL               string `json:"url"`
+	ClientReferenceID string `json:"client_reference_id"`
+	PaymentStatus     string `json:"payment_status"`
+	Currency          string `json:"currency"`
+	AmountTotal       int64  `json:"amount_total"`
+}
+
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object CheckoutSession `json:"object"`
+	} `json:"data"`
+}
+
+type ErrorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
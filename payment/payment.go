@@ -3,6 +3,7 @@ package payment
 import (
 	"one-api/payment/gateway/alipay"
 	"one-api/payment/gateway/epay"
+	"one-api/payment/gateway/stripe"
 	"one-api/payment/gateway/wxpay"
 	"one-api/payment/types"
 
@@ -15,10 +16,16 @@ type PaymentProcessor interface {
 	HandleCallback(c *gin.Context, gatewayConfig string) (*types.PayNotify, error)
 }
 
+// CallbackResponder 订单处理完成后才响应回调的网关，处理失败时返回非 2xx，由支付平台重试
+type CallbackResponder interface {
+	RespondCallback(c *gin.Context, err error)
+}
+
 var Gateways = make(map[string]PaymentProcessor)
 
 func init() {
 	Gateways["epay"] = &epay.Epay{}
 	Gateways["alipay"] = &alipay.Alipay{}
 	Gateways["wxpay"] = &wxpay.WeChatPay{}
+	Gateways["stripe"] = &stripe.Stripe{}
 }
@@ -42,6 +42,7 @@ func NewPaymentService(uuid string) (*PaymentService, error) {
 func (s *PaymentService) Pay(tradeNo string, amount float64) (*types.PayRequest, error) {
 	config := &types.PayConfig{
 		Money:     amount,
+		Currency:  string(s.Payment.Currency),
 		TradeNo:   tradeNo,
 		NotifyURL: s.getNotifyURL(),
 		ReturnURL: s.getReturnURL(),
@@ -65,6 +66,13 @@ func (s *PaymentService) HandleCallback(c *gin.Context, gatewayConfig string) (*
 	return payNotify, err
 }
 
+// RespondCallback 订单处理完成后响应回调，只对实现了 CallbackResponder 的网关生效
+func (s *PaymentService) RespondCallback(c *gin.Context, err error) {
+	if responder, ok := s.gateway.(CallbackResponder); ok {
+		responder.RespondCallback(c, err)
+	}
+}
+
 func (s *PaymentService) getNotifyURL() string {
 	notifyDomain := s.Payment.NotifyDomain
 	if notifyDomain == "" {
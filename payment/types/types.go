@@ -6,6 +6,7 @@ type PayConfig struct {
 	ReturnURL string  `json:"return_url"`
 	TradeNo   string  `json:"trade_no"`
 	Money     float64 `json:"money"`
+	Currency  string  `json:"currency"`
 }
 
 // 请求支付时的数据结构
@@ -24,4 +25,7 @@ type PayRequestData struct {
 type PayNotify struct {
 	TradeNo   string `json:"trade_no"`
 	GatewayNo string `json:"gateway_no"`
+	// 网关回调中的实付金额和币种，为空时不校验
+	Amount   float64 `json:"amount,omitempty"`
+	Currency string  `json:"currency,omitempty"`
 }
@@ -7,6 +7,7 @@ import (
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/requester"
+	"one-api/common/tracing"
 	"one-api/model"
 	"one-api/types"
 	"strings"
@@ -76,6 +77,10 @@ func (p *BaseProvider) SetUsage(usage *types.Usage) {
 
 func (p *BaseProvider) SetContext(c *gin.Context) {
 	p.Context = c
+	// 上游请求不使用客户端的请求上下文，这里只关联链路追踪的 span
+	if p.Requester != nil && c.Request != nil {
+		p.Requester.Context = tracing.WithSpan(p.Requester.Context, c.Request.Context())
+	}
 }
 
 func (p *BaseProvider) SetOriginalModel(ModelName string) {
@@ -68,7 +68,18 @@ func ClaudeUsageToOpenaiUsage(cUsage *Usage, usage *types.Usage) {
 		return
 	}
 
-	usage.PromptTokens = cUsage.InputTokens
+	ClaudePromptUsageToOpenaiUsage(cUsage, usage)
 	usage.CompletionTokens = cUsage.OutputTokens
-	usage.TotalTokens = cUsage.InputTokens + cUsage.OutputTokens
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+}
+
+// ClaudePromptUsageToOpenaiUsage 转换输入 token，Claude 的 input_tokens 不包含缓存读取和写入的 token
+func ClaudePromptUsageToOpenaiUsage(cUsage *Usage, usage *types.Usage) {
+	usage.PromptTokens = cUsage.InputTokens + cUsage.CacheCreationInputTokens + cUsage.CacheReadInputTokens
+	if cUsage.CacheReadInputTokens > 0 || cUsage.CacheCreationInputTokens > 0 {
+		usage.PromptTokensDetails = &types.PromptTokensDetails{
+			CachedTokens:      cUsage.CacheReadInputTokens,
+			CachedWriteTokens: cUsage.CacheCreationInputTokens,
+		}
+	}
 }
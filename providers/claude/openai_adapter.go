@@ -0,0 +1,592 @@
+package claude
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common/requester"
+	"one-api/common/utils"
+	"one-api/providers/base"
+	"one-api/types"
+	"strings"
+)
+
+// OpenAIChatAdapter 将只实现了 base.ChatInterface 的供应商包装为 ClaudeChatInterface
+// 请求转换为 OpenAI 格式发送，响应再转换回 Claude 格式
+type OpenAIChatAdapter struct {
+	base.ChatInterface
+}
+
+func NewOpenAIChatAdapter(provider base.ChatInterface) *OpenAIChatAdapter {
+	return &OpenAIChatAdapter{ChatInterface: provider}
+}
+
+func (a *OpenAIChatAdapter) CreateClaudeChat(request *ClaudeRequest) (*ClaudeResponse, *ClaudeErrorWithStatusCode) {
+	openaiRequest, err := ConvertClaudeToOpenaiRequest(request)
+	if err != nil {
+		return nil, StringErrorWrapper(err.Error(), "conversion_error", http.StatusBadRequest, true)
+	}
+
+	response, errWithCode := a.CreateChatCompletion(openaiRequest)
+	if errWithCode != nil {
+		return nil, OpenaiErrToClaudeErr(errWithCode)
+	}
+
+	claudeResponse := ConvertOpenaiToClaudeResponse(response, request.Model)
+	if usage := a.GetUsage(); usage != nil && usage.PromptTokens > 0 {
+		claudeResponse.Usage.InputTokens = usage.PromptTokens
+		claudeResponse.Usage.OutputTokens = usage.CompletionTokens
+	}
+
+	return claudeResponse, nil
+}
+
+func (a *OpenAIChatAdapter) CreateClaudeChatStream(request *ClaudeRequest) (requester.StreamReaderInterface[string], *ClaudeErrorWithStatusCode) {
+	openaiRequest, err := ConvertClaudeToOpenaiRequest(request)
+	if err != nil {
+		return nil, StringErrorWrapper(err.Error(), "conversion_error", http.StatusBadRequest, true)
+	}
+
+	stream, errWithCode := a.CreateChatCompletionStream(openaiRequest)
+	if errWithCode != nil {
+		return nil, OpenaiErrToClaudeErr(errWithCode)
+	}
+
+	return NewOpenaiClaudeStream(stream, a.GetUsage(), request.Model), nil
+}
+
+// ConvertClaudeToOpenaiRequest 将 Claude 的请求转换为 OpenAI 的请求
+func ConvertClaudeToOpenaiRequest(request *ClaudeRequest) (*types.ChatCompletionRequest, error) {
+	openaiRequest := &types.ChatCompletionRequest{
+		Model:       request.Model,
+		Messages:    make([]types.ChatCompletionMessage, 0, len(request.Messages)+1),
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+		TopP:        request.TopP,
+		Stop:        request.StopSequences,
+		Stream:      request.Stream,
+	}
+
+	if request.System != "" {
+		openaiRequest.Messages = append(openaiRequest.Messages, types.ChatCompletionMessage{
+			Role:    types.ChatMessageRoleSystem,
+			Content: request.System,
+		})
+	}
+
+	for _, message := range request.Messages {
+		messages, err := convertClaudeMessage(&message)
+		if err != nil {
+			return nil, err
+		}
+		openaiRequest.Messages = append(openaiRequest.Messages, messages...)
+	}
+
+	for _, tool := range request.Tools {
+		openaiRequest.Tools = append(openaiRequest.Tools, &types.ChatCompletionTool{
+			Type: "function",
+			Function: types.ChatCompletionFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+
+	if request.ToolChoice != nil && len(request.Tools) > 0 {
+		openaiRequest.ToolChoice = convertClaudeToolChoice(request.ToolChoice)
+	}
+
+	return openaiRequest, nil
+}
+
+func convertClaudeToolChoice(choice *ToolChoice) any {
+	switch choice.Type {
+	case "any":
+		return types.ToolChoiceTypeRequired
+	case "tool":
+		return map[string]any{
+			"type": types.ToolChoiceTypeFunction,
+			"function": map[string]any{
+				"name": choice.Name,
+			},
+		}
+	case "none":
+		return types.ToolChoiceTypeNone
+	default:
+		return types.ToolChoiceTypeAuto
+	}
+}
+
+func parseMessageContent(content any) ([]MessageContent, error) {
+	switch v := content.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []MessageContent{{Type: ContentTypeText, Text: v}}, nil
+	case []MessageContent:
+		return v, nil
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []MessageContent
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return nil, errors.New("invalid message content")
+	}
+	return parts, nil
+}
+
+// 一条 Claude 消息可能会拆分为多条 OpenAI 消息，tool_result 需要作为单独的 tool 消息
+func convertClaudeMessage(message *Message) ([]types.ChatCompletionMessage, error) {
+	parts, err := parseMessageContent(message.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []types.ChatCompletionMessage
+	var contentParts []any
+	var toolCalls []*types.ChatCompletionToolCalls
+	hasImage := false
+
+	for _, part := range parts {
+		switch part.Type {
+		case ContentTypeText:
+			contentParts = append(contentParts, textPart(part.Text))
+		case ContentTypeImage:
+			if url := imageSourceURL(part.Source); url != "" {
+				contentParts = append(contentParts, imagePart(url))
+				hasImage = true
+			}
+		case ContentTypeToolUes:
+			arguments, _ := json.Marshal(part.Input)
+			if part.Input == nil {
+				arguments = []byte("{}")
+			}
+			toolCalls = append(toolCalls, &types.ChatCompletionToolCalls{
+				Id:    part.Id,
+				Type:  "function",
+				Index: len(toolCalls),
+				Function: &types.ChatCompletionToolCallsFunction{
+					Name:      part.Name,
+					Arguments: string(arguments),
+				},
+			})
+		case ContentTypeToolResult:
+			resultParts, err := parseMessageContent(part.Content)
+			if err != nil {
+				return nil, err
+			}
+			var texts []string
+			for _, resultPart := range resultParts {
+				switch resultPart.Type {
+				case ContentTypeText:
+					texts = append(texts, resultPart.Text)
+				case ContentTypeImage:
+					// tool 消息不支持图片，放到后面的用户消息中
+					if url := imageSourceURL(resultPart.Source); url != "" {
+						contentParts = append(contentParts, imagePart(url))
+						hasImage = true
+					}
+				}
+			}
+			content := strings.Join(texts, "\n")
+			if part.IsError != nil && *part.IsError && content == "" {
+				content = "error"
+			}
+			messages = append(messages, types.ChatCompletionMessage{
+				Role:       types.ChatMessageRoleTool,
+				Content:    content,
+				ToolCallID: part.ToolUseId,
+			})
+		}
+	}
+
+	role := types.ChatMessageRoleUser
+	if message.Role == types.ChatMessageRoleAssistant {
+		role = types.ChatMessageRoleAssistant
+	}
+
+	if len(contentParts) == 0 && len(toolCalls) == 0 {
+		return messages, nil
+	}
+
+	openaiMessage := types.ChatCompletionMessage{
+		Role:      role,
+		ToolCalls: toolCalls,
+	}
+	if hasImage {
+		openaiMessage.Content = contentParts
+	} else {
+		var texts []string
+		for _, contentPart := range contentParts {
+			texts = append(texts, contentPart.(map[string]any)["text"].(string))
+		}
+		openaiMessage.Content = strings.Join(texts, "\n")
+	}
+
+	return append(messages, openaiMessage), nil
+}
+
+func textPart(text string) map[string]any {
+	return map[string]any{
+		"type": types.ContentTypeText,
+		"text": text,
+	}
+}
+
+func imagePart(url string) map[string]any {
+	return map[string]any{
+		"type": types.ContentTypeImageURL,
+		"image_url": map[string]any{
+			"url": url,
+		},
+	}
+}
+
+func imageSourceURL(source *ContentSource) string {
+	if source == nil {
+		return ""
+	}
+	if source.Type == "url" {
+		return source.Url
+	}
+	if source.Data == "" {
+		return ""
+	}
+	return fmt.Sprintf("data:%s;base64,%s", source.MediaType, source.Data)
+}
+
+func stopReasonOpenAI2Claude(reason any) string {
+	finishReason, _ := reason.(string)
+	switch finishReason {
+	case "":
+		return ""
+	case types.FinishReasonLength:
+		return "max_tokens"
+	case types.FinishReasonToolCalls, types.FinishReasonFunctionCall:
+		return FinishReasonToolUse
+	default:
+		return FinishReasonEndTurn
+	}
+}
+
+func toolInput(arguments string) any {
+	input := make(map[string]any)
+	if arguments != "" {
+		_ = json.Unmarshal([]byte(arguments), &input)
+	}
+	return input
+}
+
+func newClaudeToolId(id string) string {
+	if id != "" {
+		return id
+	}
+	return "toolu_" + utils.GetUUID()
+}
+
+// ConvertOpenaiToClaudeResponse 将 OpenAI 的响应转换为 Claude 的响应
+func ConvertOpenaiToClaudeResponse(response *types.ChatCompletionResponse, modelName string) *ClaudeResponse {
+	claudeResponse := &ClaudeResponse{
+		Id:      response.ID,
+		Type:    "message",
+		Role:    types.ChatMessageRoleAssistant,
+		Content: make([]ResContent, 0),
+		Model:   modelName,
+	}
+
+	if len(response.Choices) > 0 {
+		choice := response.Choices[0]
+		if text := choice.Message.StringContent(); text != "" {
+			claudeResponse.Content = append(claudeResponse.Content, ResContent{
+				Type: ContentTypeText,
+				Text: text,
+			})
+		}
+
+		choice.Message.FuncToToolCalls()
+		for _, toolCall := range choice.Message.ToolCalls {
+			if toolCall.Function == nil {
+				continue
+			}
+			claudeResponse.Content = append(claudeResponse.Content, ResContent{
+				Type:  ContentTypeToolUes,
+				Id:    newClaudeToolId(toolCall.Id),
+				Name:  toolCall.Function.Name,
+				Input: toolInput(toolCall.Function.Arguments),
+			})
+		}
+
+		claudeResponse.StopReason = stopReasonOpenAI2Claude(choice.FinishReason)
+		if len(choice.Message.ToolCalls) > 0 {
+			claudeResponse.StopReason = FinishReasonToolUse
+		}
+	}
+
+	if claudeResponse.StopReason == "" {
+		claudeResponse.StopReason = FinishReasonEndTurn
+	}
+
+	if response.Usage != nil {
+		claudeResponse.Usage.InputTokens = response.Usage.PromptTokens
+		claudeResponse.Usage.OutputTokens = response.Usage.CompletionTokens
+	}
+
+	return claudeResponse
+}
+
+// 流式事件中的字段即使为空也需要输出，所以不复用 ClaudeStreamResponse
+type claudeStreamEvent struct {
+	Type         string               `json:"type"`
+	Message      *claudeStreamMessage `json:"message,omitempty"`
+	Index        *int                 `json:"index,omitempty"`
+	ContentBlock map[string]any       `json:"content_block,omitempty"`
+	Delta        map[string]any       `json:"delta,omitempty"`
+	Usage        *claudeStreamUsage   `json:"usage,omitempty"`
+}
+
+type claudeStreamMessage struct {
+	Id           string            `json:"id"`
+	Type         string            `json:"type"`
+	Role         string            `json:"role"`
+	Content      []ResContent      `json:"content"`
+	Model        string            `json:"model"`
+	StopReason   *string           `json:"stop_reason"`
+	StopSequence *string           `json:"stop_sequence"`
+	Usage        claudeStreamUsage `json:"usage"`
+}
+
+type claudeStreamUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// claudeStreamError 流式响应中的错误，按照 Claude 的 error 事件输出
+type claudeStreamError struct {
+	ClaudeError
+}
+
+func (e *claudeStreamError) Error() string {
+	data, _ := json.Marshal(e.ClaudeError)
+	return "event: error\ndata: " + string(data) + "\n\n"
+}
+
+// openaiClaudeStream 将 OpenAI 的流式响应转换为 Claude 的 SSE 事件
+type openaiClaudeStream struct {
+	stream    requester.StreamReaderInterface[string]
+	usage     *types.Usage
+	modelName string
+
+	started    bool
+	blockIndex int
+	blockType  string // 当前打开的内容块类型，为空表示没有
+	toolIndex  int    // 当前 tool_use 块对应的 OpenAI tool_calls 下标
+	stopReason string
+
+	dataChan chan string
+	errChan  chan error
+}
+
+// NewOpenaiClaudeStream 将 OpenAI 格式的流转换为 Claude 的 SSE 事件流
+func NewOpenaiClaudeStream(stream requester.StreamReaderInterface[string], usage *types.Usage, modelName string) requester.StreamReaderInterface[string] {
+	return &openaiClaudeStream{
+		stream:     stream,
+		usage:      usage,
+		modelName:  modelName,
+		blockIndex: -1,
+		dataChan:   make(chan string),
+		errChan:    make(chan error),
+	}
+}
+
+func (s *openaiClaudeStream) Recv() (<-chan string, <-chan error) {
+	go s.process()
+	return s.dataChan, s.errChan
+}
+
+func (s *openaiClaudeStream) Close() {
+	s.stream.Close()
+}
+
+func (s *openaiClaudeStream) process() {
+	dataChan, errChan := s.stream.Recv()
+	for {
+		select {
+		case data := <-dataChan:
+			for _, event := range s.convertChunk(data) {
+				s.dataChan <- event
+			}
+		case err := <-errChan:
+			if !errors.Is(err, io.EOF) {
+				s.errChan <- toClaudeStreamError(err)
+				return
+			}
+			for _, event := range s.finish() {
+				s.dataChan <- event
+			}
+			s.errChan <- io.EOF
+			return
+		}
+	}
+}
+
+func toClaudeStreamError(err error) error {
+	streamError := &claudeStreamError{ClaudeError: ClaudeError{
+		Type: "error",
+		ErrorInfo: ClaudeErrorInfo{
+			Type:    "api_error",
+			Message: err.Error(),
+		},
+	}}
+
+	var openaiError *types.OpenAIError
+	if errors.As(err, &openaiError) {
+		streamError.ErrorInfo.Message = openaiError.Message
+		if openaiError.Type != "" {
+			streamError.ErrorInfo.Type = openaiError.Type
+		}
+	}
+
+	return streamError
+}
+
+func formatClaudeEvent(event *claudeStreamEvent) string {
+	data, _ := json.Marshal(event)
+	return "event: " + event.Type + "\ndata: " + string(data) + "\n\n"
+}
+
+func (s *openaiClaudeStream) messageStart(id string) string {
+	s.started = true
+	message := &claudeStreamMessage{
+		Id:      id,
+		Type:    "message",
+		Role:    types.ChatMessageRoleAssistant,
+		Content: make([]ResContent, 0),
+		Model:   s.modelName,
+	}
+	if s.usage != nil {
+		message.Usage.InputTokens = s.usage.PromptTokens
+	}
+	return formatClaudeEvent(&claudeStreamEvent{Type: "message_start", Message: message})
+}
+
+func (s *openaiClaudeStream) startBlock(blockType string, contentBlock map[string]any) []string {
+	events := s.stopBlock()
+	s.blockIndex++
+	s.blockType = blockType
+	index := s.blockIndex
+	events = append(events, formatClaudeEvent(&claudeStreamEvent{
+		Type:         "content_block_start",
+		Index:        &index,
+		ContentBlock: contentBlock,
+	}))
+	return events
+}
+
+func (s *openaiClaudeStream) stopBlock() []string {
+	if s.blockType == "" {
+		return nil
+	}
+	s.blockType = ""
+	index := s.blockIndex
+	return []string{formatClaudeEvent(&claudeStreamEvent{Type: "content_block_stop", Index: &index})}
+}
+
+func (s *openaiClaudeStream) blockDelta(delta map[string]any) string {
+	index := s.blockIndex
+	return formatClaudeEvent(&claudeStreamEvent{Type: "content_block_delta", Index: &index, Delta: delta})
+}
+
+func (s *openaiClaudeStream) convertChunk(data string) []string {
+	var chunk types.ChatCompletionStreamResponse
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return nil
+	}
+
+	var events []string
+	if !s.started {
+		id := chunk.ID
+		if id == "" {
+			id = "msg_" + utils.GetUUID()
+		}
+		events = append(events, s.messageStart(id))
+	}
+
+	if chunk.Usage != nil && s.usage != nil && chunk.Usage.CompletionTokens > s.usage.CompletionTokens {
+		s.usage.CompletionTokens = chunk.Usage.CompletionTokens
+	}
+
+	for _, choice := range chunk.Choices {
+		// Claude 只有一个回复，忽略 n > 1 的其余回复
+		if choice.Index != 0 {
+			continue
+		}
+
+		if choice.Delta.Content != "" {
+			if s.blockType != ContentTypeText {
+				events = append(events, s.startBlock(ContentTypeText, map[string]any{"type": ContentTypeText, "text": ""})...)
+			}
+			events = append(events, s.blockDelta(map[string]any{"type": "text_delta", "text": choice.Delta.Content}))
+		}
+
+		toolCalls := choice.Delta.ToolCalls
+		if choice.Delta.FunctionCall != nil {
+			toolCalls = []*types.ChatCompletionToolCalls{{Function: choice.Delta.FunctionCall}}
+		}
+		for _, toolCall := range toolCalls {
+			if toolCall.Function == nil {
+				continue
+			}
+			// 带有函数名或者下标发生变化时，说明是一个新的工具调用
+			if s.blockType != ContentTypeToolUes || toolCall.Index != s.toolIndex || toolCall.Function.Name != "" {
+				s.toolIndex = toolCall.Index
+				events = append(events, s.startBlock(ContentTypeToolUes, map[string]any{
+					"type":  ContentTypeToolUes,
+					"id":    newClaudeToolId(toolCall.Id),
+					"name":  toolCall.Function.Name,
+					"input": map[string]any{},
+				})...)
+			}
+			if toolCall.Function.Arguments != "" {
+				events = append(events, s.blockDelta(map[string]any{"type": "input_json_delta", "partial_json": toolCall.Function.Arguments}))
+			}
+		}
+
+		if stopReason := stopReasonOpenAI2Claude(choice.FinishReason); stopReason != "" {
+			s.stopReason = stopReason
+		}
+	}
+
+	return events
+}
+
+func (s *openaiClaudeStream) finish() []string {
+	var events []string
+	if !s.started {
+		events = append(events, s.messageStart("msg_"+utils.GetUUID()))
+	}
+	events = append(events, s.stopBlock()...)
+
+	stopReason := s.stopReason
+	if stopReason == "" {
+		stopReason = FinishReasonEndTurn
+	}
+	usage := &claudeStreamUsage{}
+	if s.usage != nil {
+		usage.OutputTokens = s.usage.CompletionTokens
+	}
+	events = append(events, formatClaudeEvent(&claudeStreamEvent{
+		Type:  "message_delta",
+		Delta: map[string]any{"stop_reason": stopReason, "stop_sequence": nil},
+		Usage: usage,
+	}))
+	events = append(events, formatClaudeEvent(&claudeStreamEvent{Type: "message_stop"}))
+
+	return events
+}
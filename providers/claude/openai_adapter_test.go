@@ -0,0 +1,166 @@
+package claude_test
+
+import (
+	"encoding/json"
+	"io"
+	"one-api/providers/claude"
+	"one-api/types"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertClaudeToOpenaiRequest(t *testing.T) {
+	body := `{
+		"model": "gpt-4o",
+		"system": "be helpful",
+		"max_tokens": 1024,
+		"messages": [
+			{"role": "user", "content": [
+				{"type": "text", "text": "what is in the image?"},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "aGVsbG8="}}
+			]},
+			{"role": "assistant", "content": [
+				{"type": "text", "text": "let me check"},
+				{"type": "tool_use", "id": "toolu_1", "name": "lookup", "input": {"q": "cat"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "a cat"}]},
+				{"type": "text", "text": "thanks"}
+			]}
+		],
+		"tools": [{"name": "lookup", "description": "search", "input_schema": {"type": "object"}}],
+		"tool_choice": {"type": "tool", "name": "lookup"}
+	}`
+
+	var request claude.ClaudeRequest
+	assert.NoError(t, json.Unmarshal([]byte(body), &request))
+
+	openaiRequest, err := claude.ConvertClaudeToOpenaiRequest(&request)
+	assert.NoError(t, err)
+	assert.Equal(t, 1024, openaiRequest.MaxTokens)
+
+	messages := openaiRequest.Messages
+	assert.Len(t, messages, 5)
+	assert.Equal(t, types.ChatMessageRoleSystem, messages[0].Role)
+
+	parts := messages[1].ParseContent()
+	assert.Len(t, parts, 2)
+	assert.Equal(t, "data:image/png;base64,aGVsbG8=", parts[1].ImageURL.URL)
+
+	assert.Equal(t, "let me check", messages[2].Content)
+	assert.Equal(t, "toolu_1", messages[2].ToolCalls[0].Id)
+	assert.JSONEq(t, `{"q":"cat"}`, messages[2].ToolCalls[0].Function.Arguments)
+
+	// tool_result 需要紧跟在 assistant 的 tool_calls 之后
+	assert.Equal(t, types.ChatMessageRoleTool, messages[3].Role)
+	assert.Equal(t, "toolu_1", messages[3].ToolCallID)
+	assert.Equal(t, "a cat", messages[3].Content)
+	assert.Equal(t, "thanks", messages[4].Content)
+
+	toolType, toolFunc := openaiRequest.ParseToolChoice()
+	assert.Equal(t, types.ToolChoiceTypeFunction, toolType)
+	assert.Equal(t, "lookup", toolFunc)
+}
+
+func TestConvertOpenaiToClaudeResponse(t *testing.T) {
+	response := &types.ChatCompletionResponse{
+		ID: "chatcmpl-1",
+		Choices: []types.ChatCompletionChoice{{
+			Message: types.ChatCompletionMessage{
+				Role:    types.ChatMessageRoleAssistant,
+				Content: "checking",
+				ToolCalls: []*types.ChatCompletionToolCalls{{
+					Id:       "call_1",
+					Type:     "function",
+					Function: &types.ChatCompletionToolCallsFunction{Name: "lookup", Arguments: `{"q":"cat"}`},
+				}},
+			},
+			FinishReason: types.FinishReasonToolCalls,
+		}},
+		Usage: &types.Usage{PromptTokens: 10, CompletionTokens: 5},
+	}
+
+	claudeResponse := claude.ConvertOpenaiToClaudeResponse(response, "claude-3-5-sonnet")
+	assert.Equal(t, "message", claudeResponse.Type)
+	assert.Equal(t, claude.FinishReasonToolUse, claudeResponse.StopReason)
+	assert.Len(t, claudeResponse.Content, 2)
+	assert.Equal(t, "checking", claudeResponse.Content[0].Text)
+	assert.Equal(t, "call_1", claudeResponse.Content[1].Id)
+	assert.Equal(t, map[string]any{"q": "cat"}, claudeResponse.Content[1].Input)
+	assert.Equal(t, 10, claudeResponse.Usage.InputTokens)
+	assert.Equal(t, 5, claudeResponse.Usage.OutputTokens)
+}
+
+type fakeStream struct {
+	chunks []string
+}
+
+func (s *fakeStream) Recv() (<-chan string, <-chan error) {
+	dataChan := make(chan string)
+	errChan := make(chan error)
+	go func() {
+		for _, chunk := range s.chunks {
+			dataChan <- chunk
+		}
+		errChan <- io.EOF
+	}()
+	return dataChan, errChan
+}
+
+func (s *fakeStream) Close() {}
+
+func TestOpenaiStreamToClaudeEvents(t *testing.T) {
+	chunks := []string{
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"lookup","arguments":""}}]}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"q\":1}"}}]}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+	}
+
+	usage := &types.Usage{PromptTokens: 7, CompletionTokens: 3}
+	stream := claude.NewOpenaiClaudeStream(&fakeStream{chunks: chunks}, usage, "claude-3-5-sonnet")
+
+	var events []string
+	dataChan, errChan := stream.Recv()
+	for done := false; !done; {
+		select {
+		case data := <-dataChan:
+			events = append(events, data)
+		case err := <-errChan:
+			assert.ErrorIs(t, err, io.EOF)
+			done = true
+		}
+	}
+
+	var eventTypes []string
+	for _, event := range events {
+		eventTypes = append(eventTypes, strings.TrimPrefix(strings.SplitN(event, "\n", 2)[0], "event: "))
+	}
+	assert.Equal(t, []string{
+		"message_start",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}, eventTypes)
+
+	assert.Contains(t, events[0], `"input_tokens":7`)
+	assert.Contains(t, events[4], `"type":"tool_use"`)
+	assert.Contains(t, events[4], `"index":1`)
+	assert.Contains(t, events[5], `"partial_json":"{\"q\":1}"`)
+	assert.Contains(t, events[7], `"stop_reason":"tool_use"`)
+	assert.Contains(t, events[7], `"output_tokens":3`)
+}
+
+func TestClaudePromptUsageToOpenaiUsage(t *testing.T) {
+	usage := &types.Usage{}
+	claude.ClaudePromptUsageToOpenaiUsage(&claude.Usage{InputTokens: 100, CacheCreationInputTokens: 300, CacheReadInputTokens: 600}, usage)
+	assert.Equal(t, 1000, usage.PromptTokens)
+	assert.Equal(t, 600, usage.PromptTokensDetails.CachedTokens)
+	assert.Equal(t, 300, usage.PromptTokensDetails.CachedWriteTokens)
+
+	usage = &types.Usage{}
+	claude.ClaudePromptUsageToOpenaiUsage(&claude.Usage{InputTokens: 100}, usage)
+	assert.Nil(t, usage.PromptTokensDetails)
+}
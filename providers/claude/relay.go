@@ -89,7 +89,7 @@ func (h *ClaudeRelayStreamHandler) HandlerStream(rawLine *[]byte, dataChan chan
 
 	switch claudeResponse.Type {
 	case "message_start":
-		h.Usage.PromptTokens = claudeResponse.Message.Usage.InputTokens
+		ClaudePromptUsageToOpenaiUsage(&claudeResponse.Message.Usage, h.Usage)
 	case "message_delta":
 		h.Usage.CompletionTokens = claudeResponse.Usage.OutputTokens
 		h.Usage.TotalTokens = h.Usage.PromptTokens + h.Usage.CompletionTokens
@@ -245,8 +245,10 @@ func (h *GeminiStreamHandler) convertToOpenaiStream(geminiResponse *GeminiChatRe
 	adjustTokenCounts(h.Request.Model, geminiResponse.UsageMetadata)
 
 	h.Usage.PromptTokens = geminiResponse.UsageMetadata.PromptTokenCount
-	h.Usage.CompletionTokens += geminiResponse.UsageMetadata.CandidatesTokenCount - h.LastCandidates
+	// 思考的 token 不包含在 candidatesTokenCount 中，需要单独累加
+	h.Usage.CompletionTokens += geminiResponse.UsageMetadata.CandidatesTokenCount - h.LastCandidates + geminiResponse.UsageMetadata.ThoughtsTokenCount - h.Usage.GetReasoningTokens()
 	h.Usage.TotalTokens = h.Usage.PromptTokens + h.Usage.CompletionTokens
+	setUsageDetails(h.Usage, geminiResponse.UsageMetadata)
 	h.LastCandidates = geminiResponse.UsageMetadata.CandidatesTokenCount
 }
 
@@ -295,11 +297,38 @@ func adjustTokenCounts(modelName string, usage *GeminiUsageMetadata) {
 func convertOpenAIUsage(modelName string, geminiUsage *GeminiUsageMetadata) types.Usage {
 	adjustTokenCounts(modelName, geminiUsage)
 
-	return types.Usage{
+	usage := types.Usage{
 		PromptTokens:     geminiUsage.PromptTokenCount,
-		CompletionTokens: geminiUsage.CandidatesTokenCount,
+		CompletionTokens: geminiUsage.CandidatesTokenCount + geminiUsage.ThoughtsTokenCount,
 		TotalTokens:      geminiUsage.TotalTokenCount,
 	}
+	setUsageDetails(&usage, geminiUsage)
+
+	return usage
+}
+
+// setUsageDetails 记录缓存、音频、图片输入和思考的 token，用于分项计费
+func setUsageDetails(usage *types.Usage, geminiUsage *GeminiUsageMetadata) {
+	promptDetails := &types.PromptTokensDetails{
+		CachedTokens: geminiUsage.CachedContentTokenCount,
+	}
+	for _, detail := range geminiUsage.PromptTokensDetails {
+		switch detail.Modality {
+		case "AUDIO":
+			promptDetails.AudioTokens = detail.TokenCount
+		case "IMAGE":
+			promptDetails.ImageTokens = detail.TokenCount
+		}
+	}
+	if *promptDetails != (types.PromptTokensDetails{}) {
+		usage.PromptTokensDetails = promptDetails
+	}
+
+	if geminiUsage.ThoughtsTokenCount > 0 {
+		usage.CompletionTokensDetails = &types.CompletionTokensDetails{
+			ReasoningTokens: geminiUsage.ThoughtsTokenCount,
+		}
+	}
 }
 
 func (p *GeminiProvider) pluginHandle(request *GeminiChatRequest) {
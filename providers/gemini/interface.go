@@ -0,0 +1,14 @@
+package gemini
+
+import (
+	"one-api/common/requester"
+	"one-api/providers/base"
+	"one-api/types"
+)
+
+// GeminiChatInterface Gemini 原生 generateContent 接口
+type GeminiChatInterface interface {
+	base.ProviderInterface
+	CreateGeminiChat(request *GeminiRelayRequest) (*GeminiRelayResponse, *types.OpenAIErrorWithStatusCode)
+	CreateGeminiChatStream(request *GeminiRelayRequest) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode)
+}
@@ -0,0 +1,545 @@
+package gemini
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/common/requester"
+	"one-api/providers/base"
+	"one-api/types"
+	"sort"
+	"strings"
+)
+
+// OpenAIChatAdapter 将只实现了 base.ChatInterface 的供应商包装为 GeminiChatInterface
+// 请求转换为 OpenAI 格式发送，响应再转换回 Gemini 格式
+type OpenAIChatAdapter struct {
+	base.ChatInterface
+}
+
+func NewOpenAIChatAdapter(provider base.ChatInterface) *OpenAIChatAdapter {
+	return &OpenAIChatAdapter{ChatInterface: provider}
+}
+
+func (a *OpenAIChatAdapter) CreateGeminiChat(request *GeminiRelayRequest) (*GeminiRelayResponse, *types.OpenAIErrorWithStatusCode) {
+	openaiRequest, err := ConvertGeminiToOpenaiRequest(request)
+	if err != nil {
+		return nil, common.StringErrorWrapperLocal(err.Error(), "conversion_error", http.StatusBadRequest)
+	}
+
+	response, errWithCode := a.CreateChatCompletion(openaiRequest)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	geminiResponse := ConvertOpenaiToGeminiResponse(response, request.Model)
+	if usage := a.GetUsage(); usage != nil && usage.PromptTokens > 0 {
+		geminiResponse.UsageMetadata = toGeminiUsage(usage)
+	}
+
+	return geminiResponse, nil
+}
+
+func (a *OpenAIChatAdapter) CreateGeminiChatStream(request *GeminiRelayRequest) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode) {
+	openaiRequest, err := ConvertGeminiToOpenaiRequest(request)
+	if err != nil {
+		return nil, common.StringErrorWrapperLocal(err.Error(), "conversion_error", http.StatusBadRequest)
+	}
+
+	stream, errWithCode := a.CreateChatCompletionStream(openaiRequest)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	return NewOpenaiGeminiStream(stream, a.GetUsage(), request.Model), nil
+}
+
+// ConvertGeminiToOpenaiRequest 将 Gemini 的请求转换为 OpenAI 的请求
+func ConvertGeminiToOpenaiRequest(request *GeminiRelayRequest) (*types.ChatCompletionRequest, error) {
+	openaiRequest := &types.ChatCompletionRequest{
+		Model:    request.Model,
+		Messages: make([]types.ChatCompletionMessage, 0, len(request.Contents)+1),
+		Stream:   request.Stream,
+	}
+
+	if config := request.GenerationConfig; config != nil {
+		openaiRequest.MaxTokens = config.MaxOutputTokens
+		openaiRequest.N = config.CandidateCount
+		openaiRequest.Stop = config.StopSequences
+		openaiRequest.Seed = config.Seed
+		if config.Temperature != nil {
+			openaiRequest.Temperature = *config.Temperature
+		}
+		if config.TopP != nil {
+			openaiRequest.TopP = *config.TopP
+		}
+		if config.PresencePenalty != nil {
+			openaiRequest.PresencePenalty = *config.PresencePenalty
+		}
+		if config.FrequencyPenalty != nil {
+			openaiRequest.FrequencyPenalty = *config.FrequencyPenalty
+		}
+		if config.ResponseMimeType == "application/json" {
+			openaiRequest.ResponseFormat = &types.ChatCompletionResponseFormat{Type: "json_object"}
+		}
+	}
+
+	if request.SystemInstruction != nil {
+		var texts []string
+		for _, part := range request.SystemInstruction.Parts {
+			if part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+		if len(texts) > 0 {
+			openaiRequest.Messages = append(openaiRequest.Messages, types.ChatCompletionMessage{
+				Role:    types.ChatMessageRoleSystem,
+				Content: strings.Join(texts, "\n"),
+			})
+		}
+	}
+
+	// Gemini 的函数调用没有 id，按照函数名依次匹配调用和结果
+	toolCallIds := make(map[string][]string)
+	toolCallNum := 0
+	for _, content := range request.Contents {
+		messages, err := convertGeminiContent(&content, toolCallIds, &toolCallNum)
+		if err != nil {
+			return nil, err
+		}
+		openaiRequest.Messages = append(openaiRequest.Messages, messages...)
+	}
+
+	for _, tool := range request.Tools {
+		for _, function := range tool.FunctionDeclarations {
+			function.Parameters = normalizeSchemaType(function.Parameters)
+			openaiRequest.Tools = append(openaiRequest.Tools, &types.ChatCompletionTool{
+				Type:     "function",
+				Function: function,
+			})
+		}
+	}
+
+	if request.ToolConfig != nil && request.ToolConfig.FunctionCallingConfig != nil && len(openaiRequest.Tools) > 0 {
+		openaiRequest.ToolChoice = convertGeminiToolChoice(request.ToolConfig.FunctionCallingConfig)
+	}
+
+	return openaiRequest, nil
+}
+
+func convertGeminiToolChoice(config *GeminiFunctionCallingConfig) any {
+	switch strings.ToUpper(config.Mode) {
+	case "ANY":
+		if len(config.AllowedFunctionNames) == 1 {
+			return map[string]any{
+				"type": types.ToolChoiceTypeFunction,
+				"function": map[string]any{
+					"name": config.AllowedFunctionNames[0],
+				},
+			}
+		}
+		return types.ToolChoiceTypeRequired
+	case "NONE":
+		return types.ToolChoiceTypeNone
+	default:
+		return types.ToolChoiceTypeAuto
+	}
+}
+
+// Gemini 的参数定义使用 OpenAPI 格式，类型为大写，例如 OBJECT、STRING
+func normalizeSchemaType(schema any) any {
+	switch v := schema.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if key == "type" {
+				if typeName, ok := value.(string); ok {
+					v[key] = strings.ToLower(typeName)
+					continue
+				}
+			}
+			v[key] = normalizeSchemaType(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = normalizeSchemaType(value)
+		}
+	}
+	return schema
+}
+
+// 一条 Gemini 消息可能会拆分为多条 OpenAI 消息，functionResponse 需要作为单独的 tool 消息
+func convertGeminiContent(content *GeminiChatContent, toolCallIds map[string][]string, toolCallNum *int) ([]types.ChatCompletionMessage, error) {
+	var messages []types.ChatCompletionMessage
+	var contentParts []any
+	var toolCalls []*types.ChatCompletionToolCalls
+	hasImage := false
+
+	for _, part := range content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			arguments, err := json.Marshal(part.FunctionCall.Args)
+			if err != nil {
+				return nil, err
+			}
+			if part.FunctionCall.Args == nil {
+				arguments = []byte("{}")
+			}
+			*toolCallNum++
+			id := fmt.Sprintf("call_%d", *toolCallNum)
+			toolCallIds[part.FunctionCall.Name] = append(toolCallIds[part.FunctionCall.Name], id)
+			toolCalls = append(toolCalls, &types.ChatCompletionToolCalls{
+				Id:    id,
+				Type:  "function",
+				Index: len(toolCalls),
+				Function: &types.ChatCompletionToolCallsFunction{
+					Name:      part.FunctionCall.Name,
+					Arguments: string(arguments),
+				},
+			})
+		case part.FunctionResponse != nil:
+			name := part.FunctionResponse.Name
+			id := ""
+			if ids := toolCallIds[name]; len(ids) > 0 {
+				id = ids[0]
+				toolCallIds[name] = ids[1:]
+			} else {
+				*toolCallNum++
+				id = fmt.Sprintf("call_%d", *toolCallNum)
+			}
+			result, err := json.Marshal(part.FunctionResponse.Response)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, types.ChatCompletionMessage{
+				Role:       types.ChatMessageRoleTool,
+				Content:    string(result),
+				ToolCallID: id,
+			})
+		case part.InlineData != nil:
+			if !strings.HasPrefix(part.InlineData.MimeType, "image/") {
+				return nil, fmt.Errorf("unsupported inline data type: %s", part.InlineData.MimeType)
+			}
+			contentParts = append(contentParts, imagePart(fmt.Sprintf("data:%s;base64,%s", part.InlineData.MimeType, part.InlineData.Data)))
+			hasImage = true
+		case part.FileData != nil:
+			if part.FileData.MimeType != "" && !strings.HasPrefix(part.FileData.MimeType, "image/") {
+				return nil, fmt.Errorf("unsupported file data type: %s", part.FileData.MimeType)
+			}
+			contentParts = append(contentParts, imagePart(part.FileData.FileUri))
+			hasImage = true
+		case part.Text != "":
+			contentParts = append(contentParts, textPart(part.Text))
+		}
+	}
+
+	role := types.ChatMessageRoleUser
+	if content.Role == "model" {
+		role = types.ChatMessageRoleAssistant
+	}
+
+	if len(contentParts) == 0 && len(toolCalls) == 0 {
+		return messages, nil
+	}
+
+	openaiMessage := types.ChatCompletionMessage{
+		Role:      role,
+		ToolCalls: toolCalls,
+	}
+	if hasImage {
+		openaiMessage.Content = contentParts
+	} else {
+		var texts []string
+		for _, contentPart := range contentParts {
+			texts = append(texts, contentPart.(map[string]any)["text"].(string))
+		}
+		openaiMessage.Content = strings.Join(texts, "\n")
+	}
+
+	return append(messages, openaiMessage), nil
+}
+
+func textPart(text string) map[string]any {
+	return map[string]any{
+		"type": types.ContentTypeText,
+		"text": text,
+	}
+}
+
+func imagePart(url string) map[string]any {
+	return map[string]any{
+		"type": types.ContentTypeImageURL,
+		"image_url": map[string]any{
+			"url": url,
+		},
+	}
+}
+
+func finishReasonOpenAI2Gemini(reason any) string {
+	finishReason, _ := reason.(string)
+	switch finishReason {
+	case "":
+		return ""
+	case types.FinishReasonLength:
+		return "MAX_TOKENS"
+	case types.FinishReasonContentFilter:
+		return "SAFETY"
+	default:
+		return "STOP"
+	}
+}
+
+func toGeminiUsage(usage *types.Usage) *GeminiUsageMetadata {
+	return &GeminiUsageMetadata{
+		PromptTokenCount:     usage.PromptTokens,
+		CandidatesTokenCount: usage.CompletionTokens,
+		TotalTokenCount:      usage.PromptTokens + usage.CompletionTokens,
+	}
+}
+
+func toolCallPart(toolCall *types.ChatCompletionToolCalls) GeminiPart {
+	args := make(map[string]any)
+	if toolCall.Function.Arguments != "" {
+		_ = json.Unmarshal([]byte(toolCall.Function.Arguments), &args)
+	}
+	return GeminiPart{
+		FunctionCall: &GeminiFunctionCall{
+			Name: toolCall.Function.Name,
+			Args: args,
+		},
+	}
+}
+
+// ConvertOpenaiToGeminiResponse 将 OpenAI 的响应转换为 Gemini 的响应
+func ConvertOpenaiToGeminiResponse(response *types.ChatCompletionResponse, modelName string) *GeminiRelayResponse {
+	geminiResponse := &GeminiRelayResponse{
+		Candidates:   make([]GeminiRelayCandidate, 0, len(response.Choices)),
+		ModelVersion: modelName,
+	}
+
+	for _, choice := range response.Choices {
+		candidate := GeminiRelayCandidate{
+			Content: GeminiChatContent{
+				Role:  "model",
+				Parts: make([]GeminiPart, 0),
+			},
+			Index:        int64(choice.Index),
+			FinishReason: finishReasonOpenAI2Gemini(choice.FinishReason),
+		}
+
+		if text := choice.Message.StringContent(); text != "" {
+			candidate.Content.Parts = append(candidate.Content.Parts, GeminiPart{Text: text})
+		}
+
+		choice.Message.FuncToToolCalls()
+		for _, toolCall := range choice.Message.ToolCalls {
+			if toolCall.Function == nil {
+				continue
+			}
+			candidate.Content.Parts = append(candidate.Content.Parts, toolCallPart(toolCall))
+		}
+
+		if candidate.FinishReason == "" {
+			candidate.FinishReason = "STOP"
+		}
+		geminiResponse.Candidates = append(geminiResponse.Candidates, candidate)
+	}
+
+	if response.Usage != nil {
+		geminiResponse.UsageMetadata = toGeminiUsage(response.Usage)
+	}
+
+	return geminiResponse
+}
+
+// openaiGeminiStream 将 OpenAI 的流式响应转换为 Gemini 的 SSE 响应
+// 函数调用的参数在 OpenAI 中是分段返回的，需要合并后在最后一个响应中输出
+type openaiGeminiStream struct {
+	stream    requester.StreamReaderInterface[string]
+	usage     *types.Usage
+	modelName string
+
+	// 最后一个文本响应先不输出，用于携带 finishReason 和用量
+	pending       *GeminiRelayResponse
+	toolCalls     map[int][]*types.ChatCompletionToolCalls
+	finishReasons map[int]string
+
+	dataChan chan string
+	errChan  chan error
+}
+
+// NewOpenaiGeminiStream 将 OpenAI 格式的流转换为 Gemini 的 SSE 流
+func NewOpenaiGeminiStream(stream requester.StreamReaderInterface[string], usage *types.Usage, modelName string) requester.StreamReaderInterface[string] {
+	return &openaiGeminiStream{
+		stream:        stream,
+		usage:         usage,
+		modelName:     modelName,
+		toolCalls:     make(map[int][]*types.ChatCompletionToolCalls),
+		finishReasons: make(map[int]string),
+		dataChan:      make(chan string),
+		errChan:       make(chan error),
+	}
+}
+
+func (s *openaiGeminiStream) Recv() (<-chan string, <-chan error) {
+	go s.process()
+	return s.dataChan, s.errChan
+}
+
+func (s *openaiGeminiStream) Close() {
+	s.stream.Close()
+}
+
+func (s *openaiGeminiStream) process() {
+	dataChan, errChan := s.stream.Recv()
+	for {
+		select {
+		case data := <-dataChan:
+			if event := s.convertChunk(data); event != "" {
+				s.dataChan <- event
+			}
+		case err := <-errChan:
+			if !errors.Is(err, io.EOF) {
+				s.errChan <- toGeminiStreamError(err)
+				return
+			}
+			s.dataChan <- s.finish()
+			s.errChan <- io.EOF
+			return
+		}
+	}
+}
+
+func formatGeminiEvent(response *GeminiRelayResponse) string {
+	data, _ := json.Marshal(response)
+	return "data: " + string(data) + "\n\n"
+}
+
+// convertChunk 返回需要输出的上一个文本响应
+func (s *openaiGeminiStream) convertChunk(data string) string {
+	var chunk types.ChatCompletionStreamResponse
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return ""
+	}
+
+	if chunk.Usage != nil && s.usage != nil && chunk.Usage.CompletionTokens > s.usage.CompletionTokens {
+		s.usage.CompletionTokens = chunk.Usage.CompletionTokens
+	}
+
+	var response *GeminiRelayResponse
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" {
+			if response == nil {
+				response = &GeminiRelayResponse{ModelVersion: s.modelName}
+			}
+			response.Candidates = append(response.Candidates, GeminiRelayCandidate{
+				Content: GeminiChatContent{
+					Role:  "model",
+					Parts: []GeminiPart{{Text: choice.Delta.Content}},
+				},
+				Index: int64(choice.Index),
+			})
+		}
+
+		toolCalls := choice.Delta.ToolCalls
+		if choice.Delta.FunctionCall != nil {
+			toolCalls = []*types.ChatCompletionToolCalls{{Function: choice.Delta.FunctionCall}}
+		}
+		for _, toolCall := range toolCalls {
+			s.mergeToolCall(choice.Index, toolCall)
+		}
+
+		if finishReason := finishReasonOpenAI2Gemini(choice.FinishReason); finishReason != "" {
+			s.finishReasons[choice.Index] = finishReason
+		}
+	}
+
+	if response == nil {
+		return ""
+	}
+
+	event := ""
+	if s.pending != nil {
+		event = formatGeminiEvent(s.pending)
+	}
+	s.pending = response
+
+	return event
+}
+
+func (s *openaiGeminiStream) mergeToolCall(choiceIndex int, toolCall *types.ChatCompletionToolCalls) {
+	if toolCall.Function == nil {
+		return
+	}
+
+	toolCalls := s.toolCalls[choiceIndex]
+	// 带有函数名或者下标发生变化时，说明是一个新的工具调用
+	if len(toolCalls) == 0 || toolCall.Function.Name != "" || toolCalls[len(toolCalls)-1].Index != toolCall.Index {
+		s.toolCalls[choiceIndex] = append(toolCalls, &types.ChatCompletionToolCalls{
+			Index: toolCall.Index,
+			Function: &types.ChatCompletionToolCallsFunction{
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Arguments,
+			},
+		})
+		return
+	}
+
+	toolCalls[len(toolCalls)-1].Function.Arguments += toolCall.Function.Arguments
+}
+
+func (s *openaiGeminiStream) candidate(response *GeminiRelayResponse, index int) *GeminiRelayCandidate {
+	for i := range response.Candidates {
+		if response.Candidates[i].Index == int64(index) {
+			return &response.Candidates[i]
+		}
+	}
+
+	response.Candidates = append(response.Candidates, GeminiRelayCandidate{
+		Content: GeminiChatContent{
+			Role:  "model",
+			Parts: make([]GeminiPart, 0),
+		},
+		Index: int64(index),
+	})
+	return &response.Candidates[len(response.Candidates)-1]
+}
+
+func (s *openaiGeminiStream) finish() string {
+	response := s.pending
+	if response == nil {
+		response = &GeminiRelayResponse{ModelVersion: s.modelName}
+	}
+
+	indexes := []int{0}
+	for index := range s.toolCalls {
+		if index != 0 {
+			indexes = append(indexes, index)
+		}
+	}
+	for index := range s.finishReasons {
+		if index != 0 && s.toolCalls[index] == nil {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Ints(indexes)
+
+	for _, index := range indexes {
+		candidate := s.candidate(response, index)
+		for _, toolCall := range s.toolCalls[index] {
+			candidate.Content.Parts = append(candidate.Content.Parts, toolCallPart(toolCall))
+		}
+		candidate.FinishReason = s.finishReasons[index]
+		if candidate.FinishReason == "" {
+			candidate.FinishReason = "STOP"
+		}
+	}
+
+	if s.usage != nil {
+		response.UsageMetadata = toGeminiUsage(s.usage)
+	}
+
+	return formatGeminiEvent(response)
+}
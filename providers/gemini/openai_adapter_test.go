@@ -0,0 +1,160 @@
+package gemini_test
+
+import (
+	"encoding/json"
+	"io"
+	"one-api/providers/gemini"
+	"one-api/types"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertGeminiToOpenaiRequest(t *testing.T) {
+	body := `{
+		"systemInstruction": {"parts": [{"text": "be helpful"}]},
+		"contents": [
+			{"role": "user", "parts": [
+				{"text": "what is in the image?"},
+				{"inlineData": {"mimeType": "image/png", "data": "aGVsbG8="}}
+			]},
+			{"role": "model", "parts": [
+				{"functionCall": {"name": "lookup", "args": {"q": "cat"}}}
+			]},
+			{"role": "user", "parts": [
+				{"functionResponse": {"name": "lookup", "response": {"result": "a cat"}}},
+				{"text": "thanks"}
+			]}
+		],
+		"tools": [{"functionDeclarations": [{"name": "lookup", "parameters": {"type": "OBJECT", "properties": {"q": {"type": "STRING"}}}}]}],
+		"toolConfig": {"functionCallingConfig": {"mode": "ANY", "allowedFunctionNames": ["lookup"]}},
+		"generationConfig": {"temperature": 0.5, "maxOutputTokens": 1024, "stopSequences": ["END"]}
+	}`
+
+	request, err := gemini.NewGeminiRelayRequest("gpt-4o", false, []byte(body))
+	assert.NoError(t, err)
+
+	openaiRequest, err := gemini.ConvertGeminiToOpenaiRequest(request)
+	assert.NoError(t, err)
+	assert.Equal(t, "gpt-4o", openaiRequest.Model)
+	assert.Equal(t, 1024, openaiRequest.MaxTokens)
+	assert.Equal(t, 0.5, openaiRequest.Temperature)
+	assert.Equal(t, []string{"END"}, openaiRequest.Stop)
+
+	messages := openaiRequest.Messages
+	assert.Len(t, messages, 5)
+	assert.Equal(t, types.ChatMessageRoleSystem, messages[0].Role)
+
+	parts := messages[1].ParseContent()
+	assert.Len(t, parts, 2)
+	assert.Equal(t, "data:image/png;base64,aGVsbG8=", parts[1].ImageURL.URL)
+
+	assert.Equal(t, types.ChatMessageRoleAssistant, messages[2].Role)
+	assert.JSONEq(t, `{"q":"cat"}`, messages[2].ToolCalls[0].Function.Arguments)
+
+	// functionResponse 按照函数名匹配之前的调用
+	assert.Equal(t, types.ChatMessageRoleTool, messages[3].Role)
+	assert.Equal(t, messages[2].ToolCalls[0].Id, messages[3].ToolCallID)
+	assert.JSONEq(t, `{"result":"a cat"}`, messages[3].Content.(string))
+	assert.Equal(t, "thanks", messages[4].Content)
+
+	parameters, _ := json.Marshal(openaiRequest.Tools[0].Function.Parameters)
+	assert.JSONEq(t, `{"type":"object","properties":{"q":{"type":"string"}}}`, string(parameters))
+
+	toolType, toolFunc := openaiRequest.ParseToolChoice()
+	assert.Equal(t, types.ToolChoiceTypeFunction, toolType)
+	assert.Equal(t, "lookup", toolFunc)
+}
+
+func TestConvertOpenaiToGeminiResponse(t *testing.T) {
+	response := &types.ChatCompletionResponse{
+		ID: "chatcmpl-1",
+		Choices: []types.ChatCompletionChoice{{
+			Message: types.ChatCompletionMessage{
+				Role:    types.ChatMessageRoleAssistant,
+				Content: "checking",
+				ToolCalls: []*types.ChatCompletionToolCalls{{
+					Id:       "call_1",
+					Type:     "function",
+					Function: &types.ChatCompletionToolCallsFunction{Name: "lookup", Arguments: `{"q":"cat"}`},
+				}},
+			},
+			FinishReason: types.FinishReasonToolCalls,
+		}},
+		Usage: &types.Usage{PromptTokens: 10, CompletionTokens: 5},
+	}
+
+	geminiResponse := gemini.ConvertOpenaiToGeminiResponse(response, "gemini-1.5-pro")
+	assert.Len(t, geminiResponse.Candidates, 1)
+
+	candidate := geminiResponse.Candidates[0]
+	assert.Equal(t, "model", candidate.Content.Role)
+	assert.Equal(t, "STOP", candidate.FinishReason)
+	assert.Len(t, candidate.Content.Parts, 2)
+	assert.Equal(t, "checking", candidate.Content.Parts[0].Text)
+	assert.Equal(t, "lookup", candidate.Content.Parts[1].FunctionCall.Name)
+	assert.Equal(t, map[string]any{"q": "cat"}, candidate.Content.Parts[1].FunctionCall.Args)
+	assert.Equal(t, 10, geminiResponse.UsageMetadata.PromptTokenCount)
+	assert.Equal(t, 15, geminiResponse.UsageMetadata.TotalTokenCount)
+}
+
+type fakeStream struct {
+	chunks []string
+}
+
+func (s *fakeStream) Recv() (<-chan string, <-chan error) {
+	dataChan := make(chan string)
+	errChan := make(chan error)
+	go func() {
+		for _, chunk := range s.chunks {
+			dataChan <- chunk
+		}
+		errChan <- io.EOF
+	}()
+	return dataChan, errChan
+}
+
+func (s *fakeStream) Close() {}
+
+func TestOpenaiStreamToGeminiEvents(t *testing.T) {
+	chunks := []string{
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":" there"}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"lookup","arguments":""}}]}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"q\":1}"}}]}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+	}
+
+	usage := &types.Usage{PromptTokens: 7, CompletionTokens: 3}
+	stream := gemini.NewOpenaiGeminiStream(&fakeStream{chunks: chunks}, usage, "gemini-1.5-pro")
+
+	var events []gemini.GeminiRelayResponse
+	dataChan, errChan := stream.Recv()
+	for done := false; !done; {
+		select {
+		case data := <-dataChan:
+			assert.True(t, strings.HasPrefix(data, "data: "))
+			var event gemini.GeminiRelayResponse
+			assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &event))
+			events = append(events, event)
+		case err := <-errChan:
+			assert.ErrorIs(t, err, io.EOF)
+			done = true
+		}
+	}
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, "Hi", events[0].Candidates[0].Content.Parts[0].Text)
+	assert.Empty(t, events[0].Candidates[0].FinishReason)
+	assert.Nil(t, events[0].UsageMetadata)
+
+	// 最后一个响应同时携带文本、函数调用、结束原因和用量
+	last := events[1].Candidates[0]
+	assert.Equal(t, " there", last.Content.Parts[0].Text)
+	assert.Equal(t, "lookup", last.Content.Parts[1].FunctionCall.Name)
+	assert.Equal(t, map[string]any{"q": float64(1)}, last.Content.Parts[1].FunctionCall.Args)
+	assert.Equal(t, "STOP", last.FinishReason)
+	assert.Equal(t, 7, events[1].UsageMetadata.PromptTokenCount)
+	assert.Equal(t, 3, events[1].UsageMetadata.CandidatesTokenCount)
+}
@@ -0,0 +1,314 @@
+package gemini
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"one-api/common"
+	"one-api/common/requester"
+	"one-api/types"
+	"strings"
+)
+
+// GeminiRelayRequest 客户端通过 Gemini 原生接口发送的请求，模型名称和是否流式来自请求路径
+type GeminiRelayRequest struct {
+	Model  string `json:"-"`
+	Stream bool   `json:"-"`
+
+	Contents          []GeminiChatContent          `json:"contents"`
+	SystemInstruction *GeminiChatContent           `json:"systemInstruction,omitempty"`
+	Tools             []GeminiChatTools            `json:"tools,omitempty"`
+	ToolConfig        *GeminiToolConfig            `json:"toolConfig,omitempty"`
+	SafetySettings    []GeminiChatSafetySettings   `json:"safetySettings,omitempty"`
+	GenerationConfig  *GeminiRelayGenerationConfig `json:"generationConfig,omitempty"`
+
+	// 原始请求体，原生渠道直接透传，避免丢失未解析的字段
+	raw []byte
+}
+
+type GeminiToolConfig struct {
+	FunctionCallingConfig *GeminiFunctionCallingConfig `json:"functionCallingConfig,omitempty"`
+}
+
+type GeminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode,omitempty"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type GeminiRelayGenerationConfig struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	TopK             *float64 `json:"topK,omitempty"`
+	MaxOutputTokens  int      `json:"maxOutputTokens,omitempty"`
+	CandidateCount   int      `json:"candidateCount,omitempty"`
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	PresencePenalty  *float64 `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	ResponseMimeType string   `json:"responseMimeType,omitempty"`
+}
+
+func NewGeminiRelayRequest(modelName string, stream bool, body []byte) (*GeminiRelayRequest, error) {
+	request := &GeminiRelayRequest{}
+	if err := json.Unmarshal(body, request); err != nil {
+		return nil, err
+	}
+
+	request.Model = modelName
+	request.Stream = stream
+	request.raw = body
+
+	return request, nil
+}
+
+// Body 返回发送给原生渠道的请求体
+func (r *GeminiRelayRequest) Body() any {
+	if len(r.raw) > 0 {
+		return bytes.NewReader(r.raw)
+	}
+	return r
+}
+
+// GeminiRelayResponse 返回给客户端的 Gemini 原生响应
+type GeminiRelayResponse struct {
+	Candidates     []GeminiRelayCandidate `json:"candidates"`
+	PromptFeedback json.RawMessage        `json:"promptFeedback,omitempty"`
+	UsageMetadata  *GeminiUsageMetadata   `json:"usageMetadata,omitempty"`
+	ModelVersion   string                 `json:"modelVersion,omitempty"`
+	Error          *GeminiError           `json:"error,omitempty"`
+}
+
+type GeminiRelayCandidate struct {
+	Content           GeminiChatContent        `json:"content"`
+	FinishReason      string                   `json:"finishReason,omitempty"`
+	Index             int64                    `json:"index"`
+	SafetyRatings     []GeminiChatSafetyRating `json:"safetyRatings,omitempty"`
+	CitationMetadata  json.RawMessage          `json:"citationMetadata,omitempty"`
+	GroundingMetadata json.RawMessage          `json:"groundingMetadata,omitempty"`
+}
+
+func (r *GeminiRelayResponse) GetResponseText() string {
+	text := ""
+	for _, candidate := range r.Candidates {
+		for _, part := range candidate.Content.Parts {
+			text += part.Text
+		}
+	}
+	return text
+}
+
+// GeminiRelayError 按照 Gemini 的格式输出的错误
+type GeminiRelayError struct {
+	ErrorInfo GeminiError `json:"error"`
+}
+
+func (e *GeminiRelayError) Error() string {
+	data, _ := json.Marshal(e)
+	return string(data)
+}
+
+var geminiErrorStatus = map[int]string{
+	http.StatusBadRequest:          "INVALID_ARGUMENT",
+	http.StatusUnauthorized:        "UNAUTHENTICATED",
+	http.StatusForbidden:           "PERMISSION_DENIED",
+	http.StatusNotFound:            "NOT_FOUND",
+	http.StatusTooManyRequests:     "RESOURCE_EXHAUSTED",
+	http.StatusServiceUnavailable:  "UNAVAILABLE",
+	http.StatusGatewayTimeout:      "DEADLINE_EXCEEDED",
+	http.StatusInternalServerError: "INTERNAL",
+}
+
+func geminiStatus(statusCode int) string {
+	if status, ok := geminiErrorStatus[statusCode]; ok {
+		return status
+	}
+	return "INTERNAL"
+}
+
+func OpenaiErrToGeminiErr(err *types.OpenAIErrorWithStatusCode) *GeminiRelayError {
+	if err == nil {
+		return nil
+	}
+
+	geminiErr := &GeminiRelayError{
+		ErrorInfo: GeminiError{
+			Code:    err.StatusCode,
+			Message: err.Message,
+			Status:  geminiStatus(err.StatusCode),
+		},
+	}
+
+	// 上游本身就是 Gemini 时，保留原始的状态
+	if err.Type == "gemini_error" && err.Param != "" {
+		geminiErr.ErrorInfo.Status = err.Param
+	}
+
+	return geminiErr
+}
+
+func ErrorToGeminiErr(err error, statusCode int) *GeminiRelayError {
+	if err == nil {
+		return nil
+	}
+
+	return &GeminiRelayError{
+		ErrorInfo: GeminiError{
+			Code:    statusCode,
+			Message: err.Error(),
+			Status:  geminiStatus(statusCode),
+		},
+	}
+}
+
+// geminiStreamError 流式响应中的错误，按照 SSE 的 data 输出
+type geminiStreamError struct {
+	GeminiRelayError
+}
+
+func (e *geminiStreamError) Error() string {
+	return "data: " + e.GeminiRelayError.Error() + "\n\n"
+}
+
+func toGeminiStreamError(err error) error {
+	streamError := &geminiStreamError{GeminiRelayError: *ErrorToGeminiErr(err, http.StatusInternalServerError)}
+
+	if openaiError, ok := err.(*types.OpenAIError); ok {
+		streamError.ErrorInfo.Message = openaiError.Message
+		if openaiError.Type == "gemini_error" && openaiError.Param != "" {
+			streamError.ErrorInfo.Status = openaiError.Param
+		}
+	}
+
+	return streamError
+}
+
+// 将 Gemini 的用量写入 usage，不修改返回给客户端的用量
+func setRelayUsage(modelName string, metadata *GeminiUsageMetadata, usage *types.Usage) {
+	if metadata == nil || usage == nil {
+		return
+	}
+
+	usageMetadata := *metadata
+	*usage = convertOpenAIUsage(modelName, &usageMetadata)
+}
+
+func (p *GeminiProvider) CreateGeminiChat(request *GeminiRelayRequest) (*GeminiRelayResponse, *types.OpenAIErrorWithStatusCode) {
+	req, errWithCode := p.getGeminiRequest(request)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	defer req.Body.Close()
+
+	response := &GeminiRelayResponse{}
+	// 发送请求
+	_, errWithCode = p.Requester.SendRequest(req, response, false)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	if errWithCode = RelayResponseUsage(response, request.Model, p.GetUsage()); errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	return response, nil
+}
+
+func (p *GeminiProvider) CreateGeminiChatStream(request *GeminiRelayRequest) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode) {
+	req, errWithCode := p.getGeminiRequest(request)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	defer req.Body.Close()
+
+	// 发送请求
+	resp, errWithCode := p.Requester.SendRequestRaw(req)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	chatHandler := &GeminiRelayStreamHandler{
+		Usage:     p.Usage,
+		ModelName: request.Model,
+	}
+
+	return requester.RequestStream[string](p.Requester, resp, chatHandler.HandlerStream)
+}
+
+func (p *GeminiProvider) getGeminiRequest(request *GeminiRelayRequest) (*http.Request, *types.OpenAIErrorWithStatusCode) {
+	url := "generateContent"
+	if request.Stream {
+		url = "streamGenerateContent?alt=sse"
+	}
+	// 获取请求地址
+	fullRequestURL := p.GetFullRequestURL(url, request.Model)
+
+	// 获取请求头
+	headers := p.GetRequestHeaders()
+	if request.Stream {
+		headers["Accept"] = "text/event-stream"
+	}
+
+	// 创建请求
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(request.Body()), p.Requester.WithHeader(headers))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	return req, nil
+}
+
+// RelayResponseUsage 检查原生响应中的错误，并计算用量
+func RelayResponseUsage(response *GeminiRelayResponse, modelName string, usage *types.Usage) *types.OpenAIErrorWithStatusCode {
+	if response.Error != nil {
+		aiError := errorHandle(&GeminiErrorResponse{Error: *response.Error})
+		if aiError != nil {
+			return &types.OpenAIErrorWithStatusCode{
+				OpenAIError: *aiError,
+				StatusCode:  http.StatusBadRequest,
+			}
+		}
+	}
+
+	if response.UsageMetadata != nil {
+		setRelayUsage(modelName, response.UsageMetadata, usage)
+	} else if usage != nil {
+		usage.CompletionTokens = common.CountTokenText(response.GetResponseText(), modelName)
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+
+	return nil
+}
+
+// GeminiRelayStreamHandler 原生流式响应直接透传，只解析其中的用量
+type GeminiRelayStreamHandler struct {
+	Usage     *types.Usage
+	ModelName string
+}
+
+func (h *GeminiRelayStreamHandler) HandlerStream(rawLine *[]byte, dataChan chan string, errChan chan error) {
+	// 如果rawLine 前缀不为data:，则直接返回
+	if !strings.HasPrefix(string(*rawLine), "data: ") {
+		*rawLine = nil
+		return
+	}
+
+	data := (*rawLine)[6:]
+
+	var response GeminiRelayResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		errChan <- toGeminiStreamError(common.ErrorToOpenAIError(err))
+		return
+	}
+
+	if response.Error != nil {
+		if aiError := errorHandle(&GeminiErrorResponse{Error: *response.Error}); aiError != nil {
+			errChan <- toGeminiStreamError(aiError)
+			return
+		}
+	}
+
+	// 流式响应中的用量是累计值，直接覆盖
+	setRelayUsage(h.ModelName, response.UsageMetadata, h.Usage)
+
+	dataChan <- "data: " + string(data) + "\n\n"
+}
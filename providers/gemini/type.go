@@ -21,11 +21,17 @@ type GeminiInlineData struct {
 	Data     string `json:"data"`
 }
 
+type GeminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileUri  string `json:"fileUri"`
+}
+
 type GeminiPart struct {
 	FunctionCall        *GeminiFunctionCall            `json:"functionCall,omitempty"`
 	FunctionResponse    *GeminiFunctionResponse        `json:"functionResponse,omitempty"`
 	Text                string                         `json:"text,omitempty"`
 	InlineData          *GeminiInlineData              `json:"inlineData,omitempty"`
+	FileData            *GeminiFileData                `json:"fileData,omitempty"`
 	ExecutableCode      *GeminiPartExecutableCode      `json:"executableCode,omitempty"`
 	CodeExecutionResult *GeminiPartCodeExecutionResult `json:"codeExecutionResult,omitempty"`
 }
@@ -132,8 +138,8 @@ func (candidate *GeminiChatCandidate) ToOpenAIChoice(request *types.ChatCompleti
 }
 
 type GeminiFunctionResponse struct {
-	Name     string                        `json:"name,omitempty"`
-	Response GeminiFunctionResponseContent `json:"response,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Response any    `json:"response,omitempty"`
 }
 
 type GeminiFunctionResponseContent struct {
@@ -201,9 +207,17 @@ type GeminiChatResponse struct {
 }
 
 type GeminiUsageMetadata struct {
-	PromptTokenCount     int `json:"promptTokenCount"`
-	CandidatesTokenCount int `json:"candidatesTokenCount"`
-	TotalTokenCount      int `json:"totalTokenCount"`
+	PromptTokenCount        int                   `json:"promptTokenCount"`
+	CandidatesTokenCount    int                   `json:"candidatesTokenCount"`
+	TotalTokenCount         int                   `json:"totalTokenCount"`
+	CachedContentTokenCount int                   `json:"cachedContentTokenCount,omitempty"`
+	ThoughtsTokenCount      int                   `json:"thoughtsTokenCount,omitempty"`
+	PromptTokensDetails     []GeminiModalityCount `json:"promptTokensDetails,omitempty"`
+}
+
+type GeminiModalityCount struct {
+	Modality   string `json:"modality"`
+	TokenCount int    `json:"tokenCount"`
 }
 
 type GeminiChatCandidate struct {
@@ -20,6 +20,7 @@ type MidjourneyRequest struct {
 	Base64Array []string `json:"base64Array"`
 	Content     string   `json:"content"`
 	MaskBase64  string   `json:"maskBase64"`
+	Dimensions  string   `json:"dimensions,omitempty"`
 }
 
 type MidjourneyResponse struct {
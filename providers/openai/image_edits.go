@@ -125,6 +125,13 @@ func imagesEditsMultipartForm(request *types.ImageEditRequest, b requester.FormB
 		}
 	}
 
+	if request.Quality != "" {
+		err = b.WriteField("quality", request.Quality)
+		if err != nil {
+			return fmt.Errorf("writing quality: %w", err)
+		}
+	}
+
 	if request.User != "" {
 		err = b.WriteField("user", request.User)
 		if err != nil {
@@ -79,6 +79,12 @@ func init() {
 
 // 获取供应商
 func GetProvider(channel *model.Channel, c *gin.Context) base.ProviderInterface {
+	// 多密钥渠道，按轮换方式选择一个可用的密钥
+	channel, err := channel.SelectKey()
+	if err != nil {
+		return nil
+	}
+
 	factory, ok := providerFactories[channel.Type]
 	var provider base.ProviderInterface
 	if !ok {
@@ -3,12 +3,15 @@ package stabilityAI
 import (
 	"bytes"
 	"encoding/base64"
+	"math"
 	"net/http"
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/storage"
 	"one-api/common/utils"
 	"one-api/types"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -20,6 +23,49 @@ func convertModelName(modelName string) string {
 	return "sd3"
 }
 
+var aspectRatios = []string{"21:9", "16:9", "3:2", "5:4", "1:1", "4:5", "2:3", "9:16", "9:21"}
+
+// getAspectRatio 将 1792x1024 这类尺寸转换为最接近的 aspect_ratio，也支持直接传入 16:9 这类比例
+func getAspectRatio(size string) string {
+	target, ok := parseRatio(size)
+	if !ok {
+		return ""
+	}
+
+	aspectRatio := ""
+	minDiff := math.MaxFloat64
+	for _, ratio := range aspectRatios {
+		value, _ := parseRatio(ratio)
+		if diff := math.Abs(math.Log(value / target)); diff < minDiff {
+			minDiff = diff
+			aspectRatio = ratio
+		}
+	}
+
+	return aspectRatio
+}
+
+func parseRatio(size string) (float64, bool) {
+	width, height, found := strings.Cut(strings.ToLower(size), "x")
+	if !found {
+		width, height, found = strings.Cut(size, ":")
+	}
+	if !found {
+		return 0, false
+	}
+
+	w, err := strconv.ParseFloat(strings.TrimSpace(width), 64)
+	if err != nil || w <= 0 {
+		return 0, false
+	}
+	h, err := strconv.ParseFloat(strings.TrimSpace(height), 64)
+	if err != nil || h <= 0 {
+		return 0, false
+	}
+
+	return w / h, true
+}
+
 func (p *StabilityAIProvider) CreateImageGenerations(request *types.ImageRequest) (*types.ImageResponse, *types.OpenAIErrorWithStatusCode) {
 	url, errWithCode := p.GetSupportedAPIUri(config.RelayModeImagesGenerations)
 	if errWithCode != nil {
@@ -40,6 +86,9 @@ func (p *StabilityAIProvider) CreateImageGenerations(request *types.ImageRequest
 	builder := p.Requester.CreateFormBuilder(&formBody)
 	builder.WriteField("prompt", request.Prompt)
 	builder.WriteField("output_format", "png")
+	if aspectRatio := getAspectRatio(request.Size); aspectRatio != "" {
+		builder.WriteField("aspect_ratio", aspectRatio)
+	}
 	if request.Model != "stable-image-core" {
 		builder.WriteField("model", request.Model)
 	}
@@ -83,6 +132,7 @@ func (p *StabilityAIProvider) CreateImageGenerations(request *types.ImageRequest
 		openaiResponse.Data = []types.ImageResponseDataInner{{URL: imgUrl}}
 	}
 
+	// 每次请求只生成一张图片
 	p.Usage.PromptTokens = 1000
 
 	return openaiResponse, nil
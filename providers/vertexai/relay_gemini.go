@@ -0,0 +1,88 @@
+package vertexai
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/common/requester"
+	"one-api/providers/gemini"
+	"one-api/providers/vertexai/category"
+	"one-api/types"
+)
+
+func (p *VertexAIProvider) CreateGeminiChat(request *gemini.GeminiRelayRequest) (*gemini.GeminiRelayResponse, *types.OpenAIErrorWithStatusCode) {
+	req, errWithCode := p.getGeminiRequest(request)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	defer req.Body.Close()
+
+	response := &gemini.GeminiRelayResponse{}
+	// 发送请求
+	_, errWithCode = p.Requester.SendRequest(req, response, false)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	if errWithCode = gemini.RelayResponseUsage(response, request.Model, p.GetUsage()); errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	return response, nil
+}
+
+func (p *VertexAIProvider) CreateGeminiChatStream(request *gemini.GeminiRelayRequest) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode) {
+	req, errWithCode := p.getGeminiRequest(request)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	defer req.Body.Close()
+
+	// 发送请求
+	resp, errWithCode := p.Requester.SendRequestRaw(req)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	chatHandler := &gemini.GeminiRelayStreamHandler{
+		Usage:     p.Usage,
+		ModelName: request.Model,
+	}
+
+	return requester.RequestStream[string](p.Requester, resp, chatHandler.HandlerStream)
+}
+
+func (p *VertexAIProvider) getGeminiRequest(request *gemini.GeminiRelayRequest) (*http.Request, *types.OpenAIErrorWithStatusCode) {
+	var err error
+	p.Category, err = category.GetCategory(request.Model)
+	if err != nil || p.Category.Category != "gemini" {
+		return nil, common.StringErrorWrapperLocal("vertexAI provider not found", "vertexAI_err", http.StatusInternalServerError)
+	}
+
+	otherUrl := p.Category.GetOtherUrl(request.Stream)
+	modelName := p.Category.GetModelName(request.Model)
+
+	// 获取请求地址
+	fullRequestURL := p.GetFullRequestURL(modelName, otherUrl)
+	if fullRequestURL == "" {
+		return nil, common.ErrorWrapperLocal(nil, "invalid_vertexai_config", http.StatusInternalServerError)
+	}
+
+	headers := p.GetRequestHeaders()
+	if headers == nil {
+		return nil, common.ErrorWrapperLocal(nil, "invalid_vertexai_config", http.StatusInternalServerError)
+	}
+
+	if request.Stream {
+		headers["Accept"] = "text/event-stream"
+	}
+
+	// 错误处理
+	p.Requester.ErrorHandler = RequestErrorHandle(p.Category.ErrorHandler)
+
+	// 创建请求
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(request.Body()), p.Requester.WithHeader(headers))
+	if err != nil {
+		return nil, common.ErrorWrapperLocal(err, "new_request_failed", http.StatusInternalServerError)
+	}
+	return req, nil
+}
@@ -15,6 +15,9 @@ type relayBase struct {
 	originalModel string
 	modelName     string
 	cache         *relay_util.ChatCacheProps
+	redactor      *relay_util.Redactor
+	// 图片请求的计费参数，其他请求为空
+	imagePricing *relay_util.ImagePricing
 }
 
 type RelayBaseInterface interface {
@@ -23,15 +26,21 @@ type RelayBaseInterface interface {
 	setRequest() error
 	getRequest() any
 	setProvider(modelName string) error
+	setOriginalModel(modelName string)
 	getProvider() providersBase.ProviderInterface
 	getOriginalModel() string
 	getModelName() string
+	getImagePricing() *relay_util.ImagePricing
 	getContext() *gin.Context
 	SetChatCache(allow bool)
 	GetChatCache() *relay_util.ChatCacheProps
 	IsStream() bool
 }
 
+func (r *relayBase) getImagePricing() *relay_util.ImagePricing {
+	return r.imagePricing
+}
+
 func (r *relayBase) SetChatCache(allow bool) {
 	r.cache = relay_util.NewChatCacheProps(r.c, allow)
 }
@@ -58,6 +67,12 @@ func (r *relayBase) setProvider(modelName string) error {
 	return nil
 }
 
+// useProvider 使用已经选好的渠道，用于对冲请求切换到胜出的渠道
+func (r *relayBase) useProvider(provider providersBase.ProviderInterface, modelName string) {
+	r.provider = provider
+	r.modelName = modelName
+}
+
 func (r *relayBase) getContext() *gin.Context {
 	return r.c
 }
@@ -66,6 +81,11 @@ func (r *relayBase) getProvider() providersBase.ProviderInterface {
 	return r.provider
 }
 
+// setOriginalModel 修改请求的模型，用于降级到其他模型
+func (r *relayBase) setOriginalModel(modelName string) {
+	r.originalModel = modelName
+}
+
 func (r *relayBase) getOriginalModel() string {
 	return r.originalModel
 }
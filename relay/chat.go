@@ -10,6 +10,7 @@ import (
 	"one-api/common/requester"
 	"one-api/common/utils"
 	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
 	"one-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -44,6 +45,7 @@ func (r *relayChat) setRequest() error {
 	}
 
 	r.originalModel = r.chatRequest.Model
+	r.chatRequest.Messages = relay_util.ApplyChatPromptTemplates(r.c, r.originalModel, r.chatRequest.Messages)
 
 	return nil
 }
@@ -62,33 +64,47 @@ func (r *relayChat) getPromptTokens() (int, error) {
 }
 
 func (r *relayChat) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
-	chatProvider, ok := r.provider.(providersBase.ChatInterface)
+	response, err, done := r.sendRequest(r.provider, r.modelName)
+	if err != nil {
+		return
+	}
+
+	return r.writeResponse(response)
+}
+
+// sendRequest 向上游发送请求，不写入响应，流式请求返回流
+func (r *relayChat) sendRequest(provider providersBase.ProviderInterface, modelName string) (response any, err *types.OpenAIErrorWithStatusCode, done bool) {
+	chatProvider, ok := provider.(providersBase.ChatInterface)
 	if !ok {
 		err = common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
 		done = true
 		return
 	}
 
-	r.chatRequest.Model = r.modelName
+	// 对冲请求会同时发送到多个渠道，每个渠道使用各自的模型名称
+	request := r.chatRequest
+	request.Model = modelName
 
-	if r.chatRequest.Stream {
-		var response requester.StreamReaderInterface[string]
-		response, err = chatProvider.CreateChatCompletionStream(&r.chatRequest)
-		if err != nil {
-			return
-		}
+	if request.Stream {
+		response, err = chatProvider.CreateChatCompletionStream(&request)
+	} else {
+		response, err = chatProvider.CreateChatCompletion(&request)
+	}
+
+	return
+}
 
+func (r *relayChat) writeResponse(response any) (err *types.OpenAIErrorWithStatusCode, done bool) {
+	switch response := response.(type) {
+	case requester.StreamReaderInterface[string]:
 		doneStr := func() string {
 			return r.getUsageResponse()
 		}
 
-		err = responseStreamClient(r.c, response, r.cache, doneStr)
-	} else {
-		var response *types.ChatCompletionResponse
-		response, err = chatProvider.CreateChatCompletion(&r.chatRequest)
-		if err != nil {
-			return
-		}
+		err = responseStreamClient(r.c, withServedModel(r.c, r.redactor.RestoreChatStream(response)), r.cache, doneStr)
+	case *types.ChatCompletionResponse:
+		r.redactor.RestoreChatResponse(response)
+		response.Model = getServedModel(r.c, response.Model)
 		err = responseJsonClient(r.c, response)
 
 		if err == nil && response.GetContent() != "" {
@@ -109,7 +125,7 @@ func (r *relayChat) getUsageResponse() string {
 			ID:      fmt.Sprintf("chatcmpl-%s", utils.GetUUID()),
 			Object:  "chat.completion.chunk",
 			Created: utils.GetTimestamp(),
-			Model:   r.chatRequest.Model,
+			Model:   getServedModel(r.c, r.modelName),
 			Choices: []types.ChatCompletionStreamChoice{},
 			Usage:   r.provider.GetUsage(),
 		}
@@ -1,6 +1,7 @@
 package relay
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -9,13 +10,18 @@ import (
 	"one-api/common/config"
 	"one-api/common/image"
 	"one-api/common/logger"
+	"one-api/common/metrics"
 	"one-api/common/requester"
+	"one-api/common/tracing"
 	"one-api/model"
+	providersBase "one-api/providers/base"
 	"one-api/providers/claude"
 	"one-api/relay/relay_util"
 	"one-api/types"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func RelaycClaudeOnly(c *gin.Context) {
@@ -26,6 +32,13 @@ func RelaycClaudeOnly(c *gin.Context) {
 		return
 	}
 
+	request.System = relay_util.ApplySystemPromptTemplates(c, request.Model, request.System)
+
+	if apiErr := relay_util.CheckContentPolicy(c, request.Model, claudeRequestTexts(request)); apiErr != nil {
+		common.AbortWithErr(c, apiErr.StatusCode, &claude.OpenaiErrToClaudeErr(apiErr).ClaudeError)
+		return
+	}
+
 	cacheProps := relay_util.NewChatCacheProps(c, true)
 	cacheProps.SetHash(request)
 
@@ -37,8 +50,10 @@ func RelaycClaudeOnly(c *gin.Context) {
 		return
 	}
 
+	endAttempt := startRelayAttempt(c, 0)
 	chatProvider, modelName, fail := GetClaudeChatInterface(c, request.Model)
 	if fail != nil {
+		endAttempt(common.StringErrorWrapperLocal(fail.Error(), "channel_error", http.StatusServiceUnavailable))
 		common.AbortWithErr(c, http.StatusServiceUnavailable, claude.ErrorToClaudeErr(fail))
 		return
 	}
@@ -49,8 +64,9 @@ func RelaycClaudeOnly(c *gin.Context) {
 	channel := chatProvider.GetChannel()
 	originaPreCostType := channel.PreCost
 
-	promptTokens, tonkeErr := CountTokenMessages(request, originaPreCostType)
+	promptTokens, tonkeErr := countClaudeTokens(c, request, originaPreCostType)
 	if tonkeErr != nil {
+		endAttempt(common.ErrorWrapperLocal(tonkeErr, "token_error", http.StatusBadRequest))
 		common.AbortWithErr(c, http.StatusBadRequest, claude.ErrorToClaudeErr(tonkeErr))
 		return
 	}
@@ -58,12 +74,14 @@ func RelaycClaudeOnly(c *gin.Context) {
 	errWithCode, done := RelayClaudeHandler(c, promptTokens, chatProvider, cacheProps, request, originalModel)
 
 	if errWithCode == nil {
+		endAttempt(nil)
 		return
 	}
 
 	apiErr := errWithCode.ToOpenAiError()
+	endAttempt(apiErr)
 
-	go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+	go processChannelRelayError(c.Request.Context(), channel, apiErr)
 
 	retryTimes := config.RetryTimes
 	if done || !shouldRetry(c, apiErr, channel.Type) {
@@ -74,18 +92,22 @@ func RelaycClaudeOnly(c *gin.Context) {
 	for i := retryTimes; i > 0; i-- {
 		// 冻结通道
 		model.ChannelGroup.Cooldowns(channel.Id)
+		endAttempt = startRelayAttempt(c, retryTimes-i+1)
 		chatProvider, modelName, fail := GetClaudeChatInterface(c, originalModel)
 		if fail != nil {
+			endAttempt(common.StringErrorWrapperLocal(fail.Error(), "channel_error", http.StatusServiceUnavailable))
 			continue
 		}
 		request.Model = modelName
 		channel = chatProvider.GetChannel()
 		logger.LogError(c.Request.Context(), fmt.Sprintf("using channel #%d(%s) to retry (remain times %d)", channel.Id, channel.Name, i))
+		metrics.RecordRelayRetry(c.GetString("group"), originalModel)
 
 		if originaPreCostType != channel.PreCost {
 			originaPreCostType = channel.PreCost
-			promptTokens, tonkeErr = CountTokenMessages(request, originaPreCostType)
+			promptTokens, tonkeErr = countClaudeTokens(c, request, originaPreCostType)
 			if tonkeErr != nil {
+				endAttempt(common.ErrorWrapperLocal(tonkeErr, "token_error", http.StatusBadRequest))
 				common.AbortWithErr(c, http.StatusBadRequest, claude.ErrorToClaudeErr(tonkeErr))
 				return
 			}
@@ -93,11 +115,13 @@ func RelaycClaudeOnly(c *gin.Context) {
 
 		errWithCode, done = RelayClaudeHandler(c, promptTokens, chatProvider, cacheProps, request, originalModel)
 		if errWithCode == nil {
+			endAttempt(nil)
 			return
 		}
 
 		apiErr = errWithCode.ToOpenAiError()
-		go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+		endAttempt(apiErr)
+		go processChannelRelayError(c.Request.Context(), channel, apiErr)
 		if done || !shouldRetry(c, apiErr, channel.Type) {
 			break
 		}
@@ -111,6 +135,14 @@ func RelaycClaudeOnly(c *gin.Context) {
 	}
 }
 
+func countClaudeTokens(c *gin.Context, request *claude.ClaudeRequest, preCostType int) (int, error) {
+	_, span := tracing.Start(c.Request.Context(), "getPromptTokens")
+	promptTokens, err := CountTokenMessages(request, preCostType)
+	span.SetAttributes(attribute.Int("prompt_tokens", promptTokens))
+	tracing.End(span, err)
+	return promptTokens, err
+}
+
 func RelayClaudeHandler(c *gin.Context, promptTokens int, chatProvider claude.ClaudeChatInterface, cache *relay_util.ChatCacheProps, request *claude.ClaudeRequest, originalModel string) (errWithCode *claude.ClaudeErrorWithStatusCode, done bool) {
 
 	usage := &types.Usage{
@@ -124,7 +156,13 @@ func RelayClaudeHandler(c *gin.Context, promptTokens int, chatProvider claude.Cl
 		return claude.OpenaiErrToClaudeErr(err), true
 	}
 
+	startTime := time.Now()
 	errWithCode, done = SendClaude(c, chatProvider, cache, request)
+	if errWithCode != nil {
+		recordChannelResult(c, originalModel, startTime, errWithCode.ToOpenAiError())
+	} else {
+		recordChannelResult(c, originalModel, startTime, nil)
+	}
 
 	if errWithCode != nil {
 		quota.Undo(c)
@@ -209,15 +247,24 @@ func CountTokenMessages(request *claude.ClaudeRequest, preCostType int) (int, er
 					if !ok {
 						continue
 					}
+					imageData, ok := imageSource["data"].(string)
+					if !ok {
+						continue
+					}
 
-					width, height, err := image.GetImageSizeFromBase64(imageSource["data"].(string))
+					width, height, err := image.GetImageSizeFromBase64(imageData)
 					if err != nil {
 						return 0, err
 					}
 					tokenNum += int(math.Ceil((float64(width) * float64(height)) / 750))
 
 				case "tool_use":
-					tokenNum += common.CountTokenInput(content["input"].(string), request.Model)
+					input, ok := content["input"].(string)
+					if !ok {
+						inputBytes, _ := json.Marshal(content["input"])
+						input = string(inputBytes)
+					}
+					tokenNum += common.CountTokenInput(input, request.Model)
 				case "tool_result":
 					// 不算了  就只算他50吧
 					tokenNum += 50
@@ -235,10 +282,14 @@ func GetClaudeChatInterface(c *gin.Context, modelName string) (claude.ClaudeChat
 		return nil, "", claude.ErrorToClaudeErr(fail)
 	}
 
-	chatProvider, ok := provider.(claude.ClaudeChatInterface)
-	if !ok {
-		return nil, "", claude.ErrorToClaudeErr(errors.New("channel not implemented"))
+	if chatProvider, ok := provider.(claude.ClaudeChatInterface); ok {
+		return chatProvider, modelName, nil
+	}
+
+	// 渠道不支持 Claude 原生接口时，转换为 OpenAI 格式请求
+	if chatProvider, ok := provider.(providersBase.ChatInterface); ok {
+		return claude.NewOpenAIChatAdapter(chatProvider), modelName, nil
 	}
 
-	return chatProvider, modelName, nil
+	return nil, "", claude.ErrorToClaudeErr(errors.New("channel not implemented"))
 }
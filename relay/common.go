@@ -11,6 +11,7 @@ import (
 	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/common/requester"
+	"one-api/common/tracing"
 	"one-api/common/utils"
 	"one-api/controller"
 	"one-api/model"
@@ -19,8 +20,10 @@ import (
 	"one-api/relay/relay_util"
 	"one-api/types"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func Path2Relay(c *gin.Context, path string) RelayBaseInterface {
@@ -101,20 +104,34 @@ func fetchChannelById(channelId int) (*model.Channel, error) {
 	return channel, nil
 }
 
-func fetchChannelByModel(c *gin.Context, modelName string) (*model.Channel, error) {
-	group := c.GetString("group")
-	skipChannelId := c.GetInt("skip_channel_id")
-	skipOnlyChat := c.GetBool("skip_only_chat")
+func channelFilters(c *gin.Context) []model.ChannelsFilterFunc {
 	var filters []model.ChannelsFilterFunc
-	if skipOnlyChat {
+	if c.GetBool("skip_only_chat") {
 		filters = append(filters, model.FilterOnlyChat())
 	}
-	if skipChannelId > 0 {
+	if skipChannelId := c.GetInt("skip_channel_id"); skipChannelId > 0 {
 		filters = append(filters, model.FilterChannelId(skipChannelId))
 	}
+	return filters
+}
+
+func fetchChannelByModel(c *gin.Context, modelName string) (*model.Channel, error) {
+	group := c.GetString("group")
+	filters := channelFilters(c)
+
+	// 重试时先释放上一个渠道占用的并发
+	relay_util.ReleaseChannel(c)
 
-	channel, err := model.ChannelGroup.Next(group, modelName, filters...)
+	_, span := tracing.Start(c.Request.Context(), "ChannelGroup.Next", attribute.String("group", group), attribute.String("model", modelName))
+	channel, err := acquireChannel(c, group, modelName, filters)
+	if err == nil {
+		span.SetAttributes(attribute.Int("channel.id", channel.Id))
+	}
+	tracing.End(span, err)
 	if err != nil {
+		if errors.Is(err, model.ErrChannelSaturated) {
+			return nil, errors.New("当前分组上游负载已饱和，请稍后再试")
+		}
 		message := fmt.Sprintf("当前分组 %s 下对于模型 %s 无可用渠道", group, modelName)
 		if channel != nil {
 			logger.SysError(fmt.Sprintf("渠道不存在：%d", channel.Id))
@@ -126,6 +143,17 @@ func fetchChannelByModel(c *gin.Context, modelName string) (*model.Channel, erro
 	return channel, nil
 }
 
+// acquireChannel 选择渠道并占用一个并发，占用的并发在请求结束或者重试切换渠道时释放
+func acquireChannel(c *gin.Context, group, modelName string, filters []model.ChannelsFilterFunc) (*model.Channel, error) {
+	channel, release, err := relay_util.AcquireChannel(c, group, modelName, filters)
+	if err != nil {
+		return channel, err
+	}
+
+	relay_util.SetChannelRelease(c, release)
+	return channel, nil
+}
+
 func responseJsonClient(c *gin.Context, data interface{}) *types.OpenAIErrorWithStatusCode {
 	// 将data转换为 JSON
 	responseBody, err := json.Marshal(data)
@@ -145,6 +173,47 @@ func responseJsonClient(c *gin.Context, data interface{}) *types.OpenAIErrorWith
 
 type StreamEndHandler func() string
 
+func setFirstResponseTime(c *gin.Context) {
+	if t, ok := c.Get("first_response_time"); !ok || t.(time.Time).IsZero() {
+		c.Set("first_response_time", time.Now())
+	}
+}
+
+// startRelayAttempt 为每一次请求尝试（包括重试）创建 span，渠道选择、计费和上游请求都是它的子 span
+func startRelayAttempt(c *gin.Context, attempt int) func(apiErr *types.OpenAIErrorWithStatusCode) {
+	span, end := tracing.StartGin(c, "relay.attempt", attribute.Int("relay.attempt", attempt))
+	return func(apiErr *types.OpenAIErrorWithStatusCode) {
+		span.SetAttributes(attribute.Int("channel.id", c.GetInt("channel_id")))
+		if apiErr != nil {
+			span.SetAttributes(attribute.Int("http.status_code", apiErr.StatusCode))
+			end(apiErr)
+			return
+		}
+		end(nil)
+	}
+}
+
+// recordChannelResult 记录本次上游请求的结果，用于自适应负载均衡和渠道熔断
+func recordChannelResult(c *gin.Context, modelName string, startTime time.Time, apiErr *types.OpenAIErrorWithStatusCode) {
+	// 重置首字时间，避免影响重试的统计
+	defer c.Set("first_response_time", time.Time{})
+
+	// 本地错误与上游无关
+	if apiErr != nil && apiErr.LocalError {
+		return
+	}
+
+	latency := time.Since(startTime)
+	ttft := latency
+	if firstResponseTime, ok := c.Get("first_response_time"); ok {
+		if t, ok := firstResponseTime.(time.Time); ok && !t.IsZero() && t.After(startTime) {
+			ttft = t.Sub(startTime)
+		}
+	}
+
+	relay_util.RecordChannelStats(c.GetString("group"), c.GetInt("channel_id"), modelName, latency, ttft, apiErr)
+}
+
 func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface[string], cache *relay_util.ChatCacheProps, endHandler StreamEndHandler) (errWithOP *types.OpenAIErrorWithStatusCode) {
 	requester.SetEventStreamHeaders(c)
 	dataChan, errChan := stream.Recv()
@@ -153,6 +222,7 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 	c.Stream(func(w io.Writer) bool {
 		select {
 		case data := <-dataChan:
+			setFirstResponseTime(c)
 			streamData := "data: " + data + "\n\n"
 			fmt.Fprint(w, streamData)
 			cache.SetResponse(streamData)
@@ -191,6 +261,7 @@ func responseGeneralStreamClient(c *gin.Context, stream requester.StreamReaderIn
 	c.Stream(func(w io.Writer) bool {
 		select {
 		case data := <-dataChan:
+			setFirstResponseTime(c)
 			fmt.Fprint(w, data)
 			cache.SetResponse(data)
 			return true
@@ -309,11 +380,26 @@ func shouldRetry(c *gin.Context, apiErr *types.OpenAIErrorWithStatusCode, channe
 	return true
 }
 
-func processChannelRelayError(ctx context.Context, channelId int, channelName string, err *types.OpenAIErrorWithStatusCode, channelType int) {
-	logger.LogError(ctx, fmt.Sprintf("relay error (channel #%d(%s)): %s", channelId, channelName, err.Message))
-	if controller.ShouldDisableChannel(channelType, err) {
-		controller.DisableChannel(channelId, channelName, err.Message, true)
+func processChannelRelayError(ctx context.Context, channel *model.Channel, err *types.OpenAIErrorWithStatusCode) {
+	logger.LogError(ctx, fmt.Sprintf("relay error (channel #%d(%s)): %s", channel.Id, channel.Name, err.Message))
+	if !controller.ShouldDisableChannel(channel.Type, err) {
+		// 多密钥渠道被限流时，暂时跳过该密钥
+		if channel.IsMultiKey() && err.StatusCode == http.StatusTooManyRequests {
+			model.CooldownChannelKey(channel.Id, channel.Key, config.RetryCooldownSeconds)
+		}
+		return
+	}
+
+	// 多密钥渠道只禁用出错的密钥，所有密钥都不可用时才禁用渠道
+	if channel.IsMultiKey() && !controller.DisableChannelKey(channel, err) {
+		return
+	}
+
+	if config.CircuitBreakerEnabled {
+		controller.TripChannel(channel.Id, channel.Name, err.Message)
+		return
 	}
+	controller.DisableChannel(channel.Id, channel.Name, err.Message, true)
 }
 
 func relayResponseWithErr(c *gin.Context, err *types.OpenAIErrorWithStatusCode) {
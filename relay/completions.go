@@ -57,33 +57,46 @@ func (r *relayCompletions) getPromptTokens() (int, error) {
 }
 
 func (r *relayCompletions) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
-	provider, ok := r.provider.(providersBase.CompletionInterface)
+	response, err, done := r.sendRequest(r.provider, r.modelName)
+	if err != nil {
+		return
+	}
+
+	return r.writeResponse(response)
+}
+
+// sendRequest 向上游发送请求，不写入响应，流式请求返回流
+func (r *relayCompletions) sendRequest(provider providersBase.ProviderInterface, modelName string) (response any, err *types.OpenAIErrorWithStatusCode, done bool) {
+	completionProvider, ok := provider.(providersBase.CompletionInterface)
 	if !ok {
 		err = common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
 		done = true
 		return
 	}
 
-	r.request.Model = r.modelName
+	request := r.request
+	request.Model = modelName
 
-	if r.request.Stream {
-		var response requester.StreamReaderInterface[string]
-		response, err = provider.CreateCompletionStream(&r.request)
-		if err != nil {
-			return
-		}
+	if request.Stream {
+		response, err = completionProvider.CreateCompletionStream(&request)
+	} else {
+		response, err = completionProvider.CreateCompletion(&request)
+	}
+
+	return
+}
 
+func (r *relayCompletions) writeResponse(response any) (err *types.OpenAIErrorWithStatusCode, done bool) {
+	switch response := response.(type) {
+	case requester.StreamReaderInterface[string]:
 		doneStr := func() string {
 			return r.getUsageResponse()
 		}
 
-		err = responseStreamClient(r.c, response, r.cache, doneStr)
-	} else {
-		var response *types.CompletionResponse
-		response, err = provider.CreateCompletion(&r.request)
-		if err != nil {
-			return
-		}
+		err = responseStreamClient(r.c, withServedModel(r.c, r.redactor.RestoreCompletionStream(response)), r.cache, doneStr)
+	case *types.CompletionResponse:
+		r.redactor.RestoreCompletionResponse(response)
+		response.Model = getServedModel(r.c, response.Model)
 		err = responseJsonClient(r.c, response)
 		r.cache.SetResponse(response)
 	}
@@ -101,7 +114,7 @@ func (r *relayCompletions) getUsageResponse() string {
 			ID:      fmt.Sprintf("chatcmpl-%s", utils.GetUUID()),
 			Object:  "chat.completion.chunk",
 			Created: utils.GetTimestamp(),
-			Model:   r.request.Model,
+			Model:   getServedModel(r.c, r.modelName),
 			Choices: []types.CompletionChoice{},
 			Usage:   r.provider.GetUsage(),
 		}
@@ -0,0 +1,95 @@
+package relay
+
+import (
+	"encoding/json"
+	"one-api/providers/claude"
+	"one-api/providers/gemini"
+	"one-api/types"
+)
+
+// relayPolicyCheckable 需要检查内容策略的中继，返回请求中发送给模型的文本
+type relayPolicyCheckable interface {
+	getPolicyTexts() []string
+}
+
+func (r *relayChat) getPolicyTexts() []string {
+	return chatMessageTexts(r.chatRequest.Messages)
+}
+
+func (r *relayCompletions) getPolicyTexts() []string {
+	switch prompt := r.request.Prompt.(type) {
+	case string:
+		return []string{prompt}
+	case []any:
+		texts := make([]string, 0, len(prompt))
+		for _, item := range prompt {
+			if text, ok := item.(string); ok {
+				texts = append(texts, text)
+			}
+		}
+		return texts
+	}
+	return nil
+}
+
+func (r *relayImageGenerations) getPolicyTexts() []string {
+	return []string{r.request.Prompt}
+}
+
+func (r *relayImageEdits) getPolicyTexts() []string {
+	return []string{r.request.Prompt}
+}
+
+func chatMessageTexts(messages []types.ChatCompletionMessage) []string {
+	var texts []string
+	for _, message := range messages {
+		for _, part := range message.ParseContent() {
+			if part.Type == types.ContentTypeText && part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+	}
+	return texts
+}
+
+func claudeRequestTexts(request *claude.ClaudeRequest) []string {
+	texts := []string{request.System}
+	for _, message := range request.Messages {
+		if content, ok := message.Content.(string); ok {
+			texts = append(texts, content)
+			continue
+		}
+
+		data, err := json.Marshal(message.Content)
+		if err != nil {
+			continue
+		}
+		var contents []claude.MessageContent
+		if err := json.Unmarshal(data, &contents); err != nil {
+			continue
+		}
+		for _, content := range contents {
+			if content.Type == "text" {
+				texts = append(texts, content.Text)
+			}
+		}
+	}
+	return texts
+}
+
+func geminiRequestTexts(request *gemini.GeminiRelayRequest) []string {
+	contents := request.Contents
+	if request.SystemInstruction != nil {
+		contents = append([]gemini.GeminiChatContent{*request.SystemInstruction}, contents...)
+	}
+
+	var texts []string
+	for _, content := range contents {
+		for _, part := range content.Parts {
+			if part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+	}
+	return texts
+}
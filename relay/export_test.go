@@ -0,0 +1,33 @@
+package relay
+
+import (
+	"one-api/model"
+	"one-api/providers"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 导出内部函数供 relay_test 使用
+var (
+	PeekStream        = peekStream
+	GetModelFallbacks = getModelFallbacks
+	SetServedModel    = setServedModel
+	WithServedModel   = withServedModel
+	GetUploadPurpose  = getUploadPurpose
+	GetUploadFile     = getUploadFile
+)
+
+// CanFallback 使用请求路径对应的接口和指定类型的渠道判断是否可以降级
+func CanFallback(c *gin.Context, path string, channelType int, apiErr *types.OpenAIErrorWithStatusCode, done bool, fail error) bool {
+	relay := Path2Relay(c, path)
+	provider := providers.GetProvider(&model.Channel{Type: channelType, Key: "sk-test", Proxy: new(string)}, c)
+	switch relay := relay.(type) {
+	case *relayChat:
+		relay.provider = provider
+	case *relayCompletions:
+		relay.provider = provider
+	}
+
+	return canFallback(c, relay, apiErr, done, fail)
+}
@@ -0,0 +1,86 @@
+package relay
+
+import (
+	"encoding/json"
+	"one-api/common/requester"
+
+	"github.com/gin-gonic/gin"
+)
+
+const servedModelHeader = "X-Served-Model"
+
+// setServedModel 记录降级后实际提供服务的模型，响应中的 model 字段和消费日志都以它为准
+func setServedModel(c *gin.Context, requestModel, servedModel string) {
+	c.Set("fallback_from_model", requestModel)
+	c.Set("served_model", servedModel)
+	c.Header(servedModelHeader, servedModel)
+}
+
+// clearServedModel 降级全部失败时清除记录，避免错误响应带上未提供服务的模型
+func clearServedModel(c *gin.Context) {
+	c.Set("fallback_from_model", "")
+	c.Set("served_model", "")
+	c.Writer.Header().Del(servedModelHeader)
+}
+
+// getServedModel 获取降级后提供服务的模型，没有降级时返回 defaultModel
+func getServedModel(c *gin.Context, defaultModel string) string {
+	if servedModel := c.GetString("served_model"); servedModel != "" {
+		return servedModel
+	}
+
+	return defaultModel
+}
+
+type servedModelStream struct {
+	requester.StreamReaderInterface[string]
+	model string
+}
+
+func (s *servedModelStream) Recv() (<-chan string, <-chan error) {
+	innerData, innerErr := s.StreamReaderInterface.Recv()
+	dataChan := make(chan string)
+	errChan := make(chan error)
+
+	go func() {
+		for {
+			select {
+			case data := <-innerData:
+				dataChan <- s.replaceModel(data)
+			case err := <-innerErr:
+				errChan <- err
+				return
+			}
+		}
+	}()
+
+	return dataChan, errChan
+}
+
+func (s *servedModelStream) replaceModel(data string) string {
+	var chunk map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return data
+	}
+	if _, ok := chunk["model"]; !ok {
+		return data
+	}
+
+	chunk["model"], _ = json.Marshal(s.model)
+	replaced, err := json.Marshal(chunk)
+	if err != nil {
+		return data
+	}
+
+	return string(replaced)
+}
+
+// withServedModel 降级时把流式响应中每个数据块的 model 替换为实际提供服务的模型
+func withServedModel(c *gin.Context, stream requester.StreamReaderInterface[string]) requester.StreamReaderInterface[string] {
+	servedModel := getServedModel(c, "")
+	if servedModel == "" {
+		return stream
+	}
+
+	return &servedModelStream{StreamReaderInterface: stream, model: servedModel}
+}
@@ -0,0 +1,108 @@
+package relay_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/model"
+	"one-api/relay"
+	"one-api/types"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetModelFallbacks(t *testing.T) {
+	common.GroupModelFallback = map[string]map[string][]string{
+		"default": {
+			"gpt-4o": {"gpt-4o", "claude-3-5-sonnet", "", "deepseek-chat", "claude-3-5-sonnet"},
+		},
+	}
+	t.Cleanup(func() {
+		common.GroupModelFallback = map[string]map[string][]string{}
+	})
+
+	tests := []struct {
+		name         string
+		group        string
+		modelName    string
+		tokenSetting *model.TokenSetting
+		want         []string
+	}{
+		{"去掉自身、空值和重复的模型", "default", "gpt-4o", nil, []string{"claude-3-5-sonnet", "deepseek-chat"}},
+		{"没有配置降级链", "default", "gpt-4o-mini", nil, nil},
+		{"其他分组", "vip", "gpt-4o", nil, nil},
+		{"跳过令牌不允许的模型", "default", "gpt-4o", &model.TokenSetting{Models: []string{"gpt-4o", "deepseek-*"}}, []string{"deepseek-chat"}},
+		{"跳过令牌禁止的模型", "default", "gpt-4o", &model.TokenSetting{DeniedModels: []string{"claude-*"}}, []string{"deepseek-chat"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Set("group", tt.group)
+			if tt.tokenSetting != nil {
+				c.Set("token_setting", tt.tokenSetting)
+			}
+
+			assert.Equal(t, tt.want, relay.GetModelFallbacks(c, tt.modelName))
+		})
+	}
+}
+
+func TestCanFallback(t *testing.T) {
+	upstreamErr := func(statusCode int) *types.OpenAIErrorWithStatusCode {
+		return common.StringErrorWrapper("upstream error", "upstream_error", statusCode)
+	}
+
+	tests := []struct {
+		name            string
+		path            string
+		specificChannel bool
+		ignoreSpecific  bool
+		apiErr          *types.OpenAIErrorWithStatusCode
+		done            bool
+		fail            error
+		want            bool
+	}{
+		{"没有可用渠道", "/v1/chat/completions", false, false, nil, false, errors.New("no channel"), true},
+		{"上游 5xx", "/v1/chat/completions", false, false, upstreamErr(http.StatusInternalServerError), false, nil, true},
+		{"上游限流", "/v1/chat/completions", false, false, upstreamErr(http.StatusTooManyRequests), false, nil, true},
+		{"上游超时不降级", "/v1/chat/completions", false, false, upstreamErr(http.StatusGatewayTimeout), false, nil, false},
+		{"请求参数错误不降级", "/v1/chat/completions", false, false, upstreamErr(http.StatusBadRequest), false, nil, false},
+		{"本地错误不降级", "/v1/chat/completions", false, false, common.StringErrorWrapperLocal("quota", "insufficient_user_quota", http.StatusForbidden), false, nil, false},
+		{"已经写入响应不降级", "/v1/chat/completions", false, false, upstreamErr(http.StatusInternalServerError), true, nil, false},
+		{"指定渠道不降级", "/v1/chat/completions", true, false, nil, false, errors.New("no channel"), false},
+		{"指定渠道但允许忽略", "/v1/chat/completions", true, true, upstreamErr(http.StatusInternalServerError), false, nil, true},
+		{"补全接口", "/v1/completions", false, false, upstreamErr(http.StatusInternalServerError), false, nil, true},
+		{"其他接口不降级", "/v1/embeddings", false, false, upstreamErr(http.StatusInternalServerError), false, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			if tt.specificChannel {
+				c.Set("specific_channel_id", 1)
+				c.Set("specific_channel_id_ignore", tt.ignoreSpecific)
+			}
+
+			assert.Equal(t, tt.want, relay.CanFallback(c, tt.path, config.ChannelTypeOpenAI, tt.apiErr, tt.done, tt.fail))
+		})
+	}
+}
+
+func TestWithServedModel(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	stream := &fakeStream{data: []string{`{"id":"1","model":"gpt-4o"}`}, err: io.EOF}
+	assert.Same(t, stream, relay.WithServedModel(c, stream))
+
+	relay.SetServedModel(c, "gpt-4o", "deepseek-chat")
+	assert.Equal(t, "deepseek-chat", c.Writer.Header().Get("X-Served-Model"))
+
+	dataChan, errChan := relay.WithServedModel(c, stream).Recv()
+	assert.JSONEq(t, `{"id":"1","model":"deepseek-chat"}`, <-dataChan)
+	assert.ErrorIs(t, <-errChan, io.EOF)
+}
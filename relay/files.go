@@ -0,0 +1,255 @@
+package relay
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"one-api/common"
+	"one-api/common/storage"
+	"one-api/model"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	fileMaxSize          = 100 * 1024 * 1024
+	fileListDefaultLimit = 100
+	fileListMaxLimit     = 10000
+)
+
+// 网关的文件存储只保存批处理的文件，batch_output 由网关生成
+// 其他用途的文件需要在上游渠道中使用（assistants、vector_stores、fine_tuning），
+// 转发到上游渠道并记录文件所在的渠道，指定渠道时文件请求也转发到上游
+func isGatewayFilePurpose(purpose string) bool {
+	return purpose == model.FilePurposeBatch || purpose == model.FilePurposeBatchOutput
+}
+
+func FileModel2Object(file *model.File) *types.File {
+	return &types.File{
+		ID:        file.FileID,
+		Object:    "file",
+		Bytes:     file.Bytes,
+		CreatedAt: file.CreatedAt,
+		Filename:  file.Filename,
+		Purpose:   file.Purpose,
+		Status:    "processed",
+	}
+}
+
+// UploadFile 上传批处理文件到网关的文件存储，其他文件转发到上游渠道
+func UploadFile(c *gin.Context) {
+	// 超过网关限制的文件直接转发，避免读取整个请求体
+	if c.GetInt("specific_channel_id") > 0 || c.Request.ContentLength > fileMaxSize {
+		RelayObjectOnly(c)
+		return
+	}
+
+	// 分块上传时没有 Content-Length，读取时限制大小
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, fileMaxSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			common.AbortWithMessage(c, http.StatusRequestEntityTooLarge, "文件大小不能超过 100MB")
+			return
+		}
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	purpose := getUploadPurpose(c, body)
+	if !isGatewayFilePurpose(purpose) {
+		RelayObjectOnly(c)
+		return
+	}
+
+	if !storage.HasFileStorage() {
+		common.AbortWithMessage(c, http.StatusServiceUnavailable, storage.ErrNoFileStorage.Error())
+		return
+	}
+
+	if purpose != model.FilePurposeBatch {
+		common.AbortWithMessage(c, http.StatusBadRequest, "batch_output 文件由批处理生成，不能上传")
+		return
+	}
+
+	filename, data, err := getUploadFile(c, body)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, "请使用 multipart/form-data 上传文件")
+		return
+	}
+
+	file, err := model.SaveFile(c.GetInt("id"), filename, purpose, data)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, FileModel2Object(file))
+}
+
+// getUploadPurpose 读取上传文件的用途，跳过文件内容，无法解析时返回空交给上游处理
+func getUploadPurpose(c *gin.Context, body []byte) string {
+	purpose := ""
+	walkMultipart(c, body, func(part *multipart.Part) (bool, error) {
+		if part.FormName() != "purpose" {
+			return true, nil
+		}
+		value, err := io.ReadAll(io.LimitReader(part, 256))
+		purpose = string(value)
+		return false, err
+	})
+
+	return purpose
+}
+
+// getUploadFile 读取上传的文件名和文件内容
+func getUploadFile(c *gin.Context, body []byte) (filename string, data []byte, err error) {
+	err = walkMultipart(c, body, func(part *multipart.Part) (bool, error) {
+		if part.FormName() != "file" || part.FileName() == "" {
+			return true, nil
+		}
+		filename = part.FileName()
+		data, err = io.ReadAll(part)
+		return false, err
+	})
+	if err == nil && filename == "" {
+		err = http.ErrMissingFile
+	}
+
+	return filename, data, err
+}
+
+// walkMultipart 依次读取 multipart 表单的字段，handle 返回 false 时停止
+func walkMultipart(c *gin.Context, body []byte, handle func(part *multipart.Part) (bool, error)) error {
+	_, params, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil {
+		return err
+	}
+	if params["boundary"] == "" {
+		return http.ErrMissingBoundary
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		next, err := handle(part)
+		part.Close()
+		if err != nil || !next {
+			return err
+		}
+	}
+}
+
+// ListFiles 获取网关中的文件，指定渠道或者其他用途时获取上游渠道中的文件
+func ListFiles(c *gin.Context) {
+	if purpose := c.Query("purpose"); c.GetInt("specific_channel_id") > 0 || (purpose != "" && !isGatewayFilePurpose(purpose)) {
+		RelayObjectOnly(c)
+		return
+	}
+
+	var params model.FileListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if params.Limit <= 0 {
+		params.Limit = fileListDefaultLimit
+	}
+	params.Limit = min(params.Limit, fileListMaxLimit)
+
+	limit := params.Limit
+	params.Limit++
+	files, err := model.GetUserFiles(c.GetInt("id"), &params)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	list := &types.FileList{
+		Object:  "list",
+		Data:    make([]*types.File, 0, len(files)),
+		HasMore: len(files) > limit,
+	}
+	if list.HasMore {
+		files = files[:limit]
+	}
+	for _, file := range files {
+		list.Data = append(list.Data, FileModel2Object(file))
+	}
+	if len(list.Data) > 0 {
+		list.FirstID = &list.Data[0].ID
+		list.LastID = &list.Data[len(list.Data)-1].ID
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func getUserFile(c *gin.Context) *model.File {
+	file, err := model.GetUserFile(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+
+	// 网关中不存在的文件转发到记录的上游渠道
+	if file == nil {
+		RelayObjectOnly(c)
+		return nil
+	}
+
+	return file
+}
+
+func RetrieveFile(c *gin.Context) {
+	file := getUserFile(c)
+	if file == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, FileModel2Object(file))
+}
+
+func GetFileContent(c *gin.Context) {
+	file := getUserFile(c)
+	if file == nil {
+		return
+	}
+
+	content, err := file.Content()
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, "application/octet-stream", content)
+}
+
+func DeleteFile(c *gin.Context) {
+	file := getUserFile(c)
+	if file == nil {
+		return
+	}
+
+	if err := file.Delete(); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, &types.FileDeleted{
+		ID:      file.FileID,
+		Object:  "file",
+		Deleted: true,
+	})
+}
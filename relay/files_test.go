@@ -0,0 +1,39 @@
+package relay_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"one-api/relay"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetUploadFile(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "batch.jsonl")
+	part.Write([]byte(`{"custom_id":"1"}`))
+	// purpose 在文件之后
+	writer.WriteField("purpose", "batch")
+	writer.Close()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/files", nil)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	assert.Equal(t, "batch", relay.GetUploadPurpose(c, body.Bytes()))
+	filename, data, err := relay.GetUploadFile(c, body.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "batch.jsonl", filename)
+	assert.Equal(t, `{"custom_id":"1"}`, string(data))
+
+	// 不是 multipart 时交给上游处理
+	c.Request.Header.Set("Content-Type", "application/json")
+	assert.Equal(t, "", relay.GetUploadPurpose(c, []byte(`{}`)))
+	_, _, err = relay.GetUploadFile(c, []byte(`{}`))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,269 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/metrics"
+	"one-api/common/requester"
+	"one-api/common/tracing"
+	"one-api/model"
+	providersBase "one-api/providers/base"
+	"one-api/providers/gemini"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Gemini 按照每张图片固定 258 tokens 计费
+const geminiImageTokens = 258
+
+func RelayGeminiOnly(c *gin.Context) {
+	// 路径格式为 models/{model}:generateContent
+	modelName, action, _ := strings.Cut(c.Param("model"), ":")
+	stream := false
+	switch action {
+	case "generateContent":
+	case "streamGenerateContent":
+		stream = true
+	default:
+		common.AbortWithErr(c, http.StatusNotFound, gemini.ErrorToGeminiErr(fmt.Errorf("unsupported method: %s", action), http.StatusNotFound))
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		common.AbortWithErr(c, http.StatusBadRequest, gemini.ErrorToGeminiErr(err, http.StatusBadRequest))
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	request, err := gemini.NewGeminiRelayRequest(modelName, stream, body)
+	if err != nil {
+		common.AbortWithErr(c, http.StatusBadRequest, gemini.ErrorToGeminiErr(err, http.StatusBadRequest))
+		return
+	}
+
+	if apiErr := relay_util.CheckContentPolicy(c, modelName, geminiRequestTexts(request)); apiErr != nil {
+		common.AbortWithErr(c, apiErr.StatusCode, gemini.OpenaiErrToGeminiErr(apiErr))
+		return
+	}
+
+	cacheProps := relay_util.NewChatCacheProps(c, true)
+	// 模型名称和是否流式不在请求体中，需要一起计算缓存
+	cacheProps.SetHash(map[string]any{
+		"model":   modelName,
+		"stream":  stream,
+		"request": json.RawMessage(body),
+	})
+
+	cache := cacheProps.GetCache()
+
+	if cache != nil {
+		// 说明有缓存， 直接返回缓存内容
+		cacheProcessing(c, cache, stream)
+		return
+	}
+
+	endAttempt := startRelayAttempt(c, 0)
+	chatProvider, mappedModel, fail := GetGeminiChatInterface(c, modelName)
+	if fail != nil {
+		endAttempt(common.StringErrorWrapperLocal(fail.Error(), "channel_error", http.StatusServiceUnavailable))
+		common.AbortWithErr(c, http.StatusServiceUnavailable, gemini.ErrorToGeminiErr(fail, http.StatusServiceUnavailable))
+		return
+	}
+	request.Model = mappedModel
+
+	channel := chatProvider.GetChannel()
+	originaPreCostType := channel.PreCost
+
+	promptTokens := countGeminiTokens(c, request, originaPreCostType)
+
+	errWithCode, done := RelayGeminiHandler(c, promptTokens, chatProvider, cacheProps, request, modelName)
+	endAttempt(errWithCode)
+	if errWithCode == nil {
+		return
+	}
+
+	go processChannelRelayError(c.Request.Context(), channel, errWithCode)
+
+	retryTimes := config.RetryTimes
+	if done || !shouldRetry(c, errWithCode, channel.Type) {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("relay error happen, status code is %d, won't retry in this case", errWithCode.StatusCode))
+		retryTimes = 0
+	}
+
+	for i := retryTimes; i > 0; i-- {
+		// 冻结通道
+		model.ChannelGroup.Cooldowns(channel.Id)
+		endAttempt = startRelayAttempt(c, retryTimes-i+1)
+		chatProvider, mappedModel, fail := GetGeminiChatInterface(c, modelName)
+		if fail != nil {
+			endAttempt(common.StringErrorWrapperLocal(fail.Error(), "channel_error", http.StatusServiceUnavailable))
+			continue
+		}
+		request.Model = mappedModel
+		channel = chatProvider.GetChannel()
+		logger.LogError(c.Request.Context(), fmt.Sprintf("using channel #%d(%s) to retry (remain times %d)", channel.Id, channel.Name, i))
+		metrics.RecordRelayRetry(c.GetString("group"), modelName)
+
+		if originaPreCostType != channel.PreCost {
+			originaPreCostType = channel.PreCost
+			promptTokens = countGeminiTokens(c, request, originaPreCostType)
+		}
+
+		errWithCode, done = RelayGeminiHandler(c, promptTokens, chatProvider, cacheProps, request, modelName)
+		endAttempt(errWithCode)
+		if errWithCode == nil {
+			return
+		}
+
+		go processChannelRelayError(c.Request.Context(), channel, errWithCode)
+		if done || !shouldRetry(c, errWithCode, channel.Type) {
+			break
+		}
+	}
+
+	if errWithCode != nil {
+		if errWithCode.StatusCode == http.StatusTooManyRequests {
+			errWithCode.OpenAIError.Message = "当前分组上游负载已饱和，请稍后再试"
+		}
+		common.AbortWithErr(c, errWithCode.StatusCode, gemini.OpenaiErrToGeminiErr(errWithCode))
+	}
+}
+
+func countGeminiTokens(c *gin.Context, request *gemini.GeminiRelayRequest, preCostType int) int {
+	_, span := tracing.Start(c.Request.Context(), "getPromptTokens")
+	promptTokens := CountGeminiTokens(request, preCostType)
+	span.SetAttributes(attribute.Int("prompt_tokens", promptTokens))
+	tracing.End(span, nil)
+	return promptTokens
+}
+
+func RelayGeminiHandler(c *gin.Context, promptTokens int, chatProvider gemini.GeminiChatInterface, cache *relay_util.ChatCacheProps, request *gemini.GeminiRelayRequest, originalModel string) (errWithCode *types.OpenAIErrorWithStatusCode, done bool) {
+	usage := &types.Usage{
+		PromptTokens: promptTokens,
+	}
+	chatProvider.SetUsage(usage)
+
+	quota, errWithCode := relay_util.NewQuota(c, request.Model, promptTokens)
+	if errWithCode != nil {
+		return errWithCode, true
+	}
+
+	startTime := time.Now()
+	errWithCode, done = SendGemini(c, chatProvider, cache, request)
+	recordChannelResult(c, originalModel, startTime, errWithCode)
+
+	if errWithCode != nil {
+		quota.Undo(c)
+		return
+	}
+
+	quota.Consume(c, usage)
+	if usage.CompletionTokens > 0 {
+		go cache.StoreCache(c.GetInt("channel_id"), usage.PromptTokens, usage.CompletionTokens, originalModel)
+	}
+
+	return
+}
+
+func SendGemini(c *gin.Context, chatProvider gemini.GeminiChatInterface, cache *relay_util.ChatCacheProps, request *gemini.GeminiRelayRequest) (errWithCode *types.OpenAIErrorWithStatusCode, done bool) {
+	if request.Stream {
+		var response requester.StreamReaderInterface[string]
+		response, errWithCode = chatProvider.CreateGeminiChatStream(request)
+		if errWithCode != nil {
+			return
+		}
+
+		doneStr := func() string {
+			return ""
+		}
+		responseGeneralStreamClient(c, response, cache, doneStr)
+	} else {
+		var response *gemini.GeminiRelayResponse
+		response, errWithCode = chatProvider.CreateGeminiChat(request)
+		if errWithCode != nil {
+			return
+		}
+
+		errWithCode = responseJsonClient(c, response)
+		if errWithCode == nil && len(response.Candidates) > 0 {
+			cache.SetResponse(response)
+		}
+	}
+
+	if errWithCode != nil {
+		done = true
+	}
+
+	return
+}
+
+func CountGeminiTokens(request *gemini.GeminiRelayRequest, preCostType int) int {
+	if preCostType == config.PreContNotAll {
+		return 0
+	}
+
+	tokenEncoder := common.GetTokenEncoder(request.Model)
+	tokensPerMessage := 4
+	tokenNum := 0
+
+	contents := request.Contents
+	if request.SystemInstruction != nil {
+		contents = append([]gemini.GeminiChatContent{*request.SystemInstruction}, contents...)
+	}
+
+	for _, content := range contents {
+		tokenNum += tokensPerMessage
+		for _, part := range content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				args, _ := json.Marshal(part.FunctionCall.Args)
+				tokenNum += common.GetTokenNum(tokenEncoder, string(args))
+			case part.FunctionResponse != nil:
+				result, _ := json.Marshal(part.FunctionResponse.Response)
+				tokenNum += common.GetTokenNum(tokenEncoder, string(result))
+			case part.InlineData != nil, part.FileData != nil:
+				if preCostType == config.PreCostNotImage {
+					continue
+				}
+				tokenNum += geminiImageTokens
+			default:
+				tokenNum += common.GetTokenNum(tokenEncoder, part.Text)
+			}
+		}
+	}
+
+	return tokenNum
+}
+
+func GetGeminiChatInterface(c *gin.Context, modelName string) (gemini.GeminiChatInterface, string, error) {
+	provider, modelName, fail := GetProvider(c, modelName)
+	if fail != nil {
+		return nil, "", fail
+	}
+
+	// VertexAI 只有 gemini 系列模型支持原生接口
+	native := provider.GetChannel().Type != config.ChannelTypeVertexAI || strings.HasPrefix(modelName, "gemini")
+	if chatProvider, ok := provider.(gemini.GeminiChatInterface); ok && native {
+		return chatProvider, modelName, nil
+	}
+
+	// 渠道不支持 Gemini 原生接口时，转换为 OpenAI 格式请求
+	if chatProvider, ok := provider.(providersBase.ChatInterface); ok {
+		return gemini.NewOpenAIChatAdapter(chatProvider), modelName, nil
+	}
+
+	return nil, "", errors.New("channel not implemented")
+}
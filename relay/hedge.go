@@ -0,0 +1,360 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/common/metrics"
+	"one-api/common/requester"
+	"one-api/common/tracing"
+	"one-api/model"
+	"one-api/providers"
+	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// relayHedgeable 支持对冲请求的中继，向上游发送请求和写入响应需要分开处理
+type relayHedgeable interface {
+	RelayBaseInterface
+	sendRequest(provider providersBase.ProviderInterface, modelName string) (response any, err *types.OpenAIErrorWithStatusCode, done bool)
+	writeResponse(response any) (err *types.OpenAIErrorWithStatusCode, done bool)
+	useProvider(provider providersBase.ProviderInterface, modelName string)
+}
+
+type hedgeCandidate struct {
+	provider  providersBase.ProviderInterface
+	modelName string
+	release   func() // 对冲渠道占用的并发，首个渠道的并发保存在请求上下文中
+	cancel    context.CancelFunc
+	startTime time.Time
+}
+
+type hedgeResult struct {
+	candidate    *hedgeCandidate
+	response     any
+	err          *types.OpenAIErrorWithStatusCode
+	done         bool
+	responseTime time.Time
+}
+
+// getHedgeDelay 获取对冲请求的等待时间，令牌设置优先于分组设置，为 0 时不开启
+func getHedgeDelay(c *gin.Context) time.Duration {
+	// 指定渠道的请求不能发送到其他渠道
+	if c.GetInt("specific_channel_id") > 0 && !c.GetBool("specific_channel_id_ignore") {
+		return 0
+	}
+
+	delay := getTokenSetting(c).HedgeDelay
+	if delay <= 0 {
+		delay = common.GetGroupHedgeDelay(c.GetString("group"))
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	return time.Duration(delay) * time.Millisecond
+}
+
+func newHedgeCandidate(provider providersBase.ProviderInterface, modelName string, release func()) *hedgeCandidate {
+	candidate := &hedgeCandidate{
+		provider:  provider,
+		modelName: modelName,
+		release:   release,
+		cancel:    func() {},
+	}
+
+	// 上游请求使用单独的上下文，落败时取消
+	if httpRequester := provider.GetRequester(); httpRequester != nil {
+		var ctx context.Context
+		ctx, candidate.cancel = context.WithCancel(httpRequester.Context)
+		httpRequester.Context = ctx
+	}
+
+	return candidate
+}
+
+// nextHedgeCandidate 选择对冲请求的渠道，不排队等待，没有空闲渠道时不发送对冲请求
+func nextHedgeCandidate(c *gin.Context, relay relayHedgeable, skipChannelId int) *hedgeCandidate {
+	originalModel := relay.getOriginalModel()
+	filters := append(channelFilters(c), model.FilterChannelId(skipChannelId))
+	channel, err := model.ChannelGroup.Next(c.GetString("group"), originalModel, filters...)
+	if err != nil {
+		return nil
+	}
+
+	release, ok := model.ChannelConcurrencies.Acquire(channel)
+	if !ok {
+		return nil
+	}
+
+	provider := providers.GetProvider(channel, c)
+	if provider == nil {
+		release()
+		return nil
+	}
+	provider.SetOriginalModel(originalModel)
+
+	modelName, err := provider.ModelMappingHandler(originalModel)
+	if err != nil {
+		release()
+		return nil
+	}
+
+	return newHedgeCandidate(provider, modelName, release)
+}
+
+func (h *hedgeCandidate) send(relay relayHedgeable, promptTokens int, results chan<- *hedgeResult) {
+	h.provider.SetUsage(&types.Usage{
+		PromptTokens: promptTokens,
+	})
+	h.startTime = time.Now()
+
+	go func() {
+		result := &hedgeResult{candidate: h}
+		result.response, result.err, result.done = relay.sendRequest(h.provider, h.modelName)
+		if result.err == nil {
+			// 流式请求以收到第一个数据块作为响应
+			if stream, ok := result.response.(requester.StreamReaderInterface[string]); ok {
+				result.response, result.err = peekStream(stream)
+			}
+		}
+		result.responseTime = time.Now()
+		results <- result
+	}()
+}
+
+func (h *hedgeCandidate) channelId() int {
+	return h.provider.GetChannel().Id
+}
+
+// relayHedgeHandler 首个渠道在等待时间内没有返回响应或第一个数据块时，向下一个渠道发送相同的请求
+// 使用先响应的结果并取消另一个请求，只对胜出的渠道计费
+func relayHedgeHandler(relay relayHedgeable, delay time.Duration) (err *types.OpenAIErrorWithStatusCode, done bool) {
+	c := relay.getContext()
+
+	_, span := tracing.Start(c.Request.Context(), "getPromptTokens")
+	promptTokens, tokenErr := relay.getPromptTokens()
+	span.SetAttributes(attribute.Int("prompt_tokens", promptTokens))
+	tracing.End(span, tokenErr)
+	if tokenErr != nil {
+		err = common.ErrorWrapperLocal(tokenErr, "token_error", http.StatusBadRequest)
+		done = true
+		return
+	}
+
+	var quota *relay_util.Quota
+	quota, err = relay_util.NewQuota(c, relay.getModelName(), promptTokens)
+	if err != nil {
+		done = true
+		return
+	}
+
+	results := make(chan *hedgeResult, 2)
+	primary := newHedgeCandidate(relay.getProvider(), relay.getModelName(), nil)
+	primary.send(relay, promptTokens, results)
+	candidates := []*hedgeCandidate{primary}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var winner *hedgeResult
+	var failures []*hedgeResult
+	pending := 1
+	for winner == nil && pending > 0 {
+		select {
+		case <-timer.C:
+			hedge := nextHedgeCandidate(c, relay, primary.channelId())
+			if hedge == nil {
+				continue
+			}
+			logger.LogInfo(c.Request.Context(), fmt.Sprintf("channel #%d no response in %dms, hedging to channel #%d", primary.channelId(), delay.Milliseconds(), hedge.channelId()))
+			hedge.send(relay, promptTokens, results)
+			candidates = append(candidates, hedge)
+			pending++
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				winner = result
+			} else {
+				failures = append(failures, result)
+			}
+		}
+	}
+
+	// 取消落败的请求，并在后台清理尚未返回的结果
+	for _, candidate := range candidates {
+		if winner != nil && candidate == winner.candidate {
+			continue
+		}
+		candidate.cancel()
+		if candidate.release != nil {
+			candidate.release()
+		}
+	}
+	go func(pending int) {
+		for ; pending > 0; pending-- {
+			discardHedgeResponse((<-results).response)
+		}
+	}(pending)
+
+	hedged := len(candidates) > 1
+	for i, failure := range failures {
+		recordHedgeResult(c, relay.getOriginalModel(), failure)
+		// 全部失败时最后一个错误交给重试处理，其余的在这里处理
+		if winner != nil || i < len(failures)-1 {
+			go processChannelRelayError(c.Request.Context(), failure.candidate.provider.GetChannel(), failure.err)
+		}
+	}
+
+	if winner == nil {
+		if hedged {
+			metrics.RecordRelayHedge(c.GetString("group"), relay.getOriginalModel(), metrics.HedgeWinnerNone)
+		}
+		quota.Undo(c)
+
+		last := failures[len(failures)-1]
+		if last.candidate != primary {
+			relay.useProvider(last.candidate.provider, last.candidate.modelName)
+			c.Set("channel_id", last.candidate.channelId())
+		}
+		return last.err, last.done
+	}
+
+	candidate := winner.candidate
+	defer candidate.cancel()
+
+	if candidate != primary {
+		relay.useProvider(candidate.provider, candidate.modelName)
+		c.Set("channel_id", candidate.channelId())
+		// 释放首个渠道占用的并发，改为保存对冲渠道的并发
+		relay_util.SetChannelRelease(c, candidate.release)
+
+		// 按照胜出渠道重新预扣费
+		quota.Undo(c)
+		quota, err = relay_util.NewQuota(c, candidate.modelName, promptTokens)
+		if err != nil {
+			discardHedgeResponse(winner.response)
+			done = true
+			return
+		}
+	}
+
+	if hedged {
+		winnerLabel := metrics.HedgeWinnerPrimary
+		if candidate != primary {
+			winnerLabel = metrics.HedgeWinnerHedge
+		}
+		metrics.RecordRelayHedge(c.GetString("group"), relay.getOriginalModel(), winnerLabel)
+	}
+
+	if _, ok := winner.response.(*hedgeStream); ok {
+		c.Set("first_response_time", winner.responseTime)
+	}
+
+	err, done = relay.writeResponse(winner.response)
+	recordChannelResult(c, relay.getOriginalModel(), candidate.startTime, err)
+
+	if err != nil {
+		quota.Undo(c)
+		return
+	}
+
+	usage := candidate.provider.GetUsage()
+	quota.Consume(c, usage)
+	if usage.CompletionTokens > 0 {
+		cacheProps := relay.GetChatCache()
+		go cacheProps.StoreCache(c.GetInt("channel_id"), usage.PromptTokens, usage.CompletionTokens, relay.getModelName())
+	}
+
+	return
+}
+
+func recordHedgeResult(c *gin.Context, modelName string, result *hedgeResult) {
+	if result.err != nil && result.err.LocalError {
+		return
+	}
+
+	latency := result.responseTime.Sub(result.candidate.startTime)
+	relay_util.RecordChannelStats(c.GetString("group"), result.candidate.channelId(), modelName, latency, latency, result.err)
+}
+
+// discardHedgeResponse 关闭落败请求的流
+func discardHedgeResponse(response any) {
+	if stream, ok := response.(*hedgeStream); ok {
+		stream.discard()
+	}
+}
+
+// hedgeStream 已经读取了第一个数据块的流
+type hedgeStream struct {
+	requester.StreamReaderInterface[string]
+	dataChan <-chan string
+	errChan  <-chan error
+	first    string
+}
+
+// peekStream 等待流返回第一个数据块，在此之前结束的流（包括没有任何数据就正常结束的空流）视为请求失败
+func peekStream(stream requester.StreamReaderInterface[string]) (any, *types.OpenAIErrorWithStatusCode) {
+	dataChan, errChan := stream.Recv()
+	hedge := &hedgeStream{
+		StreamReaderInterface: stream,
+		dataChan:              dataChan,
+		errChan:               errChan,
+	}
+
+	select {
+	case hedge.first = <-dataChan:
+	case err := <-errChan:
+		stream.Close()
+		if errors.Is(err, io.EOF) {
+			return nil, common.StringErrorWrapper("上游在返回数据前结束了流", "empty_stream", http.StatusBadGateway)
+		}
+		return nil, common.ErrorWrapper(err, "stream_error", http.StatusInternalServerError)
+	}
+
+	return hedge, nil
+}
+
+func (s *hedgeStream) Recv() (<-chan string, <-chan error) {
+	dataChan := make(chan string)
+	errChan := make(chan error)
+
+	go func() {
+		dataChan <- s.first
+		for {
+			select {
+			case data := <-s.dataChan:
+				dataChan <- data
+			case err := <-s.errChan:
+				errChan <- err
+				return
+			}
+		}
+	}()
+
+	return dataChan, errChan
+}
+
+func (s *hedgeStream) discard() {
+	s.Close()
+
+	// 读取剩余的数据，避免读取流的协程阻塞
+	go func() {
+		for {
+			select {
+			case <-s.dataChan:
+			case <-s.errChan:
+				return
+			}
+		}
+	}()
+}
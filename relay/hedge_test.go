@@ -0,0 +1,55 @@
+package relay_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"one-api/relay"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStream struct {
+	data   []string
+	err    error
+	closed bool
+}
+
+func (s *fakeStream) Recv() (<-chan string, <-chan error) {
+	dataChan := make(chan string)
+	errChan := make(chan error)
+	go func() {
+		for _, data := range s.data {
+			dataChan <- data
+		}
+		errChan <- s.err
+	}()
+	return dataChan, errChan
+}
+
+func (s *fakeStream) Close() {
+	s.closed = true
+}
+
+func TestPeekStream(t *testing.T) {
+	stream := &fakeStream{data: []string{"first", "second"}, err: io.EOF}
+	hedge, errWithCode := relay.PeekStream(stream)
+	assert.Nil(t, errWithCode)
+	assert.NotNil(t, hedge)
+	assert.False(t, stream.closed)
+
+	// 没有任何数据就结束的流视为失败，不能赢得对冲
+	stream = &fakeStream{err: io.EOF}
+	hedge, errWithCode = relay.PeekStream(stream)
+	assert.Nil(t, hedge)
+	assert.NotNil(t, errWithCode)
+	assert.Equal(t, http.StatusBadGateway, errWithCode.StatusCode)
+	assert.True(t, stream.closed)
+
+	stream = &fakeStream{err: errors.New("connection reset")}
+	hedge, errWithCode = relay.PeekStream(stream)
+	assert.Nil(t, hedge)
+	assert.Equal(t, http.StatusInternalServerError, errWithCode.StatusCode)
+	assert.True(t, stream.closed)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"one-api/common"
 	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
 	"one-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -39,6 +40,11 @@ func (r *relayImageEdits) setRequest() error {
 	}
 
 	r.originalModel = r.request.Model
+	r.imagePricing = &relay_util.ImagePricing{
+		Size:    r.request.Size,
+		Quality: r.request.Quality,
+		N:       r.request.N,
+	}
 
 	return nil
 }
@@ -61,6 +67,7 @@ func (r *relayImageEdits) send() (err *types.OpenAIErrorWithStatusCode, done boo
 	if err != nil {
 		return
 	}
+	r.imagePricing.SetCount(len(response.Data))
 	err = responseJsonClient(r.c, response)
 
 	if err != nil {
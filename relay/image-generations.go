@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"one-api/common"
 	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
 	"one-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -42,6 +43,12 @@ func (r *relayImageGenerations) setRequest() error {
 	}
 
 	r.originalModel = r.request.Model
+	r.imagePricing = &relay_util.ImagePricing{
+		Size:    r.request.Size,
+		Quality: r.request.Quality,
+		Style:   r.request.Style,
+		N:       r.request.N,
+	}
 
 	return nil
 }
@@ -64,6 +71,7 @@ func (r *relayImageGenerations) send() (err *types.OpenAIErrorWithStatusCode, do
 	if err != nil {
 		return
 	}
+	r.imagePricing.SetCount(len(response.Data))
 	err = responseJsonClient(r.c, response)
 
 	if err != nil {
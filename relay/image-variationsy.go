@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"one-api/common"
 	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
 	"one-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -34,6 +35,11 @@ func (r *relayImageVariations) setRequest() error {
 	}
 
 	r.originalModel = r.request.Model
+	r.imagePricing = &relay_util.ImagePricing{
+		Size:    r.request.Size,
+		Quality: r.request.Quality,
+		N:       r.request.N,
+	}
 
 	return nil
 }
@@ -56,6 +62,7 @@ func (r *relayImageVariations) send() (err *types.OpenAIErrorWithStatusCode, don
 	if err != nil {
 		return
 	}
+	r.imagePricing.SetCount(len(response.Data))
 	err = responseJsonClient(r.c, response)
 
 	if err != nil {
@@ -6,12 +6,15 @@ import (
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/logger"
+	"one-api/common/metrics"
+	"one-api/common/tracing"
 	"one-api/model"
 	"one-api/relay/relay_util"
 	"one-api/types"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func Relay(c *gin.Context) {
@@ -26,9 +29,30 @@ func Relay(c *gin.Context) {
 		return
 	}
 
+	// 精确缓存使用脱敏前的请求计算，避免不同内容替换为相同的占位符后命中缓存
 	cacheProps := relay.GetChatCache()
 	cacheProps.SetHash(relay.getRequest())
 
+	// 先脱敏，内容审核和语义缓存的向量计算都会把请求内容发送到其他渠道
+	var redactor *relay_util.Redactor
+	if redactRelay, ok := relay.(relayRedactable); ok {
+		if redactor = relay_util.NewRedactor(c); redactor != nil {
+			redactRelay.redact(redactor)
+		}
+	}
+
+	if policyRelay, ok := relay.(relayPolicyCheckable); ok {
+		if apiErr := relay_util.CheckContentPolicy(c, relay.getOriginalModel(), policyRelay.getPolicyTexts()); apiErr != nil {
+			relayResponseWithErr(c, apiErr)
+			return
+		}
+	}
+
+	// 有内容被替换时不使用语义缓存，占位符相同的请求语义相近但原始内容不同
+	if chatRequest, ok := relay.getRequest().(*types.ChatCompletionRequest); ok && !redactor.Redacted() {
+		cacheProps.SetSemanticRequest(chatRequest)
+	}
+
 	// 获取缓存
 	cache := cacheProps.GetCache()
 
@@ -38,18 +62,72 @@ func Relay(c *gin.Context) {
 		return
 	}
 
-	if err := relay.setProvider(relay.getOriginalModel()); err != nil {
-		common.AbortWithMessage(c, http.StatusServiceUnavailable, err.Error())
+	apiErr, done, fail := relayWithRetry(relay)
+	if apiErr == nil && fail == nil {
 		return
 	}
 
-	apiErr, done := RelayHandler(relay)
+	// 请求的模型所有渠道都失败后，按照分组配置的降级链依次尝试其他模型
+	if canFallback(c, relay, apiErr, done, fail) {
+		requestModel := relay.getOriginalModel()
+		for _, fallbackModel := range getModelFallbacks(c, requestModel) {
+			logger.LogWarn(c.Request.Context(), fmt.Sprintf("model %s unavailable, fallback to %s", relay.getOriginalModel(), fallbackModel))
+			metrics.RecordRelayFallback(c.GetString("group"), requestModel, fallbackModel)
+
+			relay.setOriginalModel(fallbackModel)
+			setServedModel(c, requestModel, fallbackModel)
+			fallbackErr, fallbackDone, fallbackFail := relayWithRetry(relay)
+			if fallbackErr == nil && fallbackFail == nil {
+				return
+			}
+
+			// 没有可用渠道时保留之前的上游错误
+			if fallbackErr != nil || apiErr == nil {
+				apiErr, fail = fallbackErr, fallbackFail
+			}
+			if !canFallback(c, relay, fallbackErr, fallbackDone, fallbackFail) {
+				break
+			}
+		}
+	}
+
+	clearServedModel(c)
+	if apiErr == nil {
+		common.AbortWithMessage(c, http.StatusServiceUnavailable, fail.Error())
+		return
+	}
+
+	if apiErr.StatusCode == http.StatusTooManyRequests {
+		apiErr.OpenAIError.Message = "当前分组上游负载已饱和，请稍后再试"
+	}
+	relayResponseWithErr(c, apiErr)
+}
+
+// relayWithRetry 使用当前模型请求上游，失败后按照重试次数更换渠道
+// fail 不为空表示第一次选择渠道就失败了，此时还没有请求上游
+func relayWithRetry(relay RelayBaseInterface) (apiErr *types.OpenAIErrorWithStatusCode, done bool, fail error) {
+	c := relay.getContext()
+
+	endAttempt := startRelayAttempt(c, 0)
+	if err := relay.setProvider(relay.getOriginalModel()); err != nil {
+		endAttempt(common.StringErrorWrapperLocal(err.Error(), "channel_error", http.StatusServiceUnavailable))
+		return nil, false, err
+	}
+
+	// 对冲请求只用于第一次尝试，失败后按照原有方式重试
+	hedgeRelay, ok := relay.(relayHedgeable)
+	if delay := getHedgeDelay(c); ok && delay > 0 {
+		apiErr, done = relayHedgeHandler(hedgeRelay, delay)
+	} else {
+		apiErr, done = RelayHandler(relay)
+	}
+	endAttempt(apiErr)
 	if apiErr == nil {
 		return
 	}
 
 	channel := relay.getProvider().GetChannel()
-	go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+	go processChannelRelayError(c.Request.Context(), channel, apiErr)
 
 	retryTimes := config.RetryTimes
 	if done || !shouldRetry(c, apiErr, channel.Type) {
@@ -60,32 +138,68 @@ func Relay(c *gin.Context) {
 	for i := retryTimes; i > 0; i-- {
 		// 冻结通道
 		model.ChannelGroup.Cooldowns(channel.Id)
+		endAttempt = startRelayAttempt(c, retryTimes-i+1)
 		if err := relay.setProvider(relay.getOriginalModel()); err != nil {
+			endAttempt(common.StringErrorWrapperLocal(err.Error(), "channel_error", http.StatusServiceUnavailable))
 			continue
 		}
 
 		channel = relay.getProvider().GetChannel()
 		logger.LogError(c.Request.Context(), fmt.Sprintf("using channel #%d(%s) to retry (remain times %d)", channel.Id, channel.Name, i))
+		metrics.RecordRelayRetry(c.GetString("group"), relay.getOriginalModel())
 		apiErr, done = RelayHandler(relay)
+		endAttempt(apiErr)
 		if apiErr == nil {
 			return
 		}
-		go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+		go processChannelRelayError(c.Request.Context(), channel, apiErr)
 		if done || !shouldRetry(c, apiErr, channel.Type) {
 			break
 		}
 	}
 
-	if apiErr != nil {
-		if apiErr.StatusCode == http.StatusTooManyRequests {
-			apiErr.OpenAIError.Message = "当前分组上游负载已饱和，请稍后再试"
+	return
+}
+
+// canFallback 判断请求失败后是否可以使用降级模型，请求参数错误等不会因为更换模型而成功的错误不降级
+// 只有对话和补全接口会在响应中返回实际使用的模型，其他接口不降级
+func canFallback(c *gin.Context, relay RelayBaseInterface, apiErr *types.OpenAIErrorWithStatusCode, done bool, fail error) bool {
+	switch relay.(type) {
+	case *relayChat, *relayCompletions:
+	default:
+		return false
+	}
+
+	if c.GetInt("specific_channel_id") > 0 && !c.GetBool("specific_channel_id_ignore") {
+		return false
+	}
+
+	if fail != nil {
+		return true
+	}
+
+	return !done && shouldRetry(c, apiErr, relay.getProvider().GetChannel().Type)
+}
+
+// getModelFallbacks 获取分组中模型的降级链，跳过令牌不允许调用的模型
+func getModelFallbacks(c *gin.Context, modelName string) []string {
+	tokenSetting := getTokenSetting(c)
+
+	var fallbacks []string
+	for _, fallback := range common.GetModelFallbacks(c.GetString("group"), modelName) {
+		if tokenSetting.IsModelAllowed(fallback) {
+			fallbacks = append(fallbacks, fallback)
 		}
-		relayResponseWithErr(c, apiErr)
 	}
+
+	return fallbacks
 }
 
 func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCode, done bool) {
+	_, span := tracing.Start(relay.getContext().Request.Context(), "getPromptTokens")
 	promptTokens, tonkeErr := relay.getPromptTokens()
+	span.SetAttributes(attribute.Int("prompt_tokens", promptTokens))
+	tracing.End(span, tonkeErr)
 	if tonkeErr != nil {
 		err = common.ErrorWrapperLocal(tonkeErr, "token_error", http.StatusBadRequest)
 		done = true
@@ -99,13 +213,15 @@ func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCod
 	relay.getProvider().SetUsage(usage)
 
 	var quota *relay_util.Quota
-	quota, err = relay_util.NewQuota(relay.getContext(), relay.getModelName(), promptTokens)
+	quota, err = relay_util.NewImageQuota(relay.getContext(), relay.getModelName(), promptTokens, relay.getImagePricing())
 	if err != nil {
 		done = true
 		return
 	}
 
+	startTime := time.Now()
 	err, done = relay.send()
+	recordChannelResult(relay.getContext(), relay.getOriginalModel(), startTime, err)
 
 	if err != nil {
 		quota.Undo(relay.getContext())
@@ -136,5 +252,10 @@ func cacheProcessing(c *gin.Context, cacheProps *relay_util.ChatCacheProps, isSt
 		}
 	}
 
-	model.RecordConsumeLog(c.Request.Context(), cacheProps.UserId, cacheProps.ChannelID, cacheProps.PromptTokens, cacheProps.CompletionTokens, cacheProps.ModelName, tokenName, 0, "缓存", requestTime)
+	content := "缓存"
+	if cacheProps.Semantic {
+		content = "语义缓存"
+	}
+
+	model.RecordConsumeLog(c.Request.Context(), cacheProps.UserId, cacheProps.ChannelID, cacheProps.PromptTokens, cacheProps.CompletionTokens, cacheProps.ModelName, tokenName, 0, content, requestTime)
 }
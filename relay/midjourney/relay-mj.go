@@ -157,7 +157,7 @@ func RelaySwapFace(c *gin.Context) *provider.MidjourneyResponse {
 		return provider.MidjourneyErrorWrapper(provider.MjRequestError, "sour_base64_and_target_base64_is_required")
 	}
 
-	quotaInstance, errWithOA := getQuota(c, provider.MjActionSwapFace)
+	quotaInstance, errWithOA := getQuota(c, provider.MjActionSwapFace, nil)
 	if errWithOA != nil {
 		return &provider.MidjourneyResponse{
 			Code:        4,
@@ -176,7 +176,7 @@ func RelaySwapFace(c *gin.Context) *provider.MidjourneyResponse {
 		quotaInstance.Undo(c)
 	}
 
-	quota := int(quotaInstance.GetInputRatio() * 1000)
+	quota := quotaInstance.GetTimesQuota()
 
 	midjResponse := &mjResp.Response
 	midjourneyTask := &model.Midjourney{
@@ -339,6 +339,9 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *provider.MidjourneyRe
 		if midjRequest.Prompt == "" {
 			return provider.MidjourneyErrorWrapper(provider.MjRequestError, "prompt_is_required")
 		}
+		if apiErr := relay_util.CheckContentPolicy(c, CoverActionToModelName(provider.MjActionImagine), []string{midjRequest.Prompt}); apiErr != nil {
+			return provider.MidjourneyErrorWrapper(provider.MjRequestError, relay_util.ContentPolicyViolationCode)
+		}
 		midjRequest.Action = provider.MjActionImagine
 	} else if relayMode == provider.RelayModeMidjourneyDescribe { //按图生文任务，此类任务可重复
 		midjRequest.Action = provider.MjActionDescribe
@@ -407,7 +410,7 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *provider.MidjourneyRe
 
 	//midjRequest.NotifyHook = "http://127.0.0.1:3000/mj/notify"
 
-	quotaInstance, errWithOA := getQuota(c, midjRequest.Action)
+	quotaInstance, errWithOA := getQuota(c, midjRequest.Action, GetMjImagePricing(&midjRequest))
 	if errWithOA != nil {
 		return &provider.MidjourneyResponse{
 			Code:        4,
@@ -426,7 +429,7 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *provider.MidjourneyRe
 	} else {
 		quotaInstance.Undo(c)
 	}
-	quota := int(quotaInstance.GetInputRatio() * 1000)
+	quota := quotaInstance.GetTimesQuota()
 
 	midjResponse := &midjResponseWithStatus.Response
 
@@ -540,10 +543,13 @@ func getMjRequestPath(path string) string {
 	return requestURL
 }
 
-func getQuota(c *gin.Context, action string) (*relay_util.Quota, *types.OpenAIErrorWithStatusCode) {
+func getQuota(c *gin.Context, action string, imagePricing *relay_util.ImagePricing) (*relay_util.Quota, *types.OpenAIErrorWithStatusCode) {
 	modelName := CoverActionToModelName(action)
+	if errWithCode := relay_util.CheckTokenModel(c, modelName); errWithCode != nil {
+		return nil, errWithCode
+	}
 
-	return relay_util.NewQuota(c, modelName, 1000)
+	return relay_util.NewImageQuota(c, modelName, 1000, imagePricing)
 }
 
 func getMJProviderWithRequest(c *gin.Context, relayMode int, request *provider.MidjourneyRequest) (*provider.MidjourneyProvider, *provider.MidjourneyResponse) {
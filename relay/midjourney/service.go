@@ -5,6 +5,7 @@ package midjourney
 
 import (
 	mjProvider "one-api/providers/midjourney"
+	"one-api/relay/relay_util"
 	"strconv"
 	"strings"
 )
@@ -146,3 +147,37 @@ func ConvertSimpleChangeParams(content string) *mjProvider.MidjourneyRequest {
 	changeParams.Index = index
 	return changeParams
 }
+
+var mjDimensions = map[string]string{
+	"PORTRAIT":  "2:3",
+	"SQUARE":    "1:1",
+	"LANDSCAPE": "3:2",
+}
+
+// GetMjImagePricing 获取图片价格矩阵的匹配条件
+// 尺寸、质量和风格分别来自提示词中的 --ar、--q、--style 参数，混图的尺寸来自 dimensions
+func GetMjImagePricing(midjRequest *mjProvider.MidjourneyRequest) *relay_util.ImagePricing {
+	pricing := &relay_util.ImagePricing{
+		Size:    "1:1",
+		Quality: "1",
+		N:       1,
+	}
+
+	if size, ok := mjDimensions[strings.ToUpper(midjRequest.Dimensions)]; ok {
+		pricing.Size = size
+	}
+
+	fields := strings.Fields(midjRequest.Prompt)
+	for i := 0; i < len(fields)-1; i++ {
+		switch strings.ToLower(fields[i]) {
+		case "--ar", "--aspect":
+			pricing.Size = fields[i+1]
+		case "--q", "--quality":
+			pricing.Quality = fields[i+1]
+		case "--style":
+			pricing.Style = fields[i+1]
+		}
+	}
+
+	return pricing
+}
@@ -67,8 +67,12 @@ func ListModels(c *gin.Context) {
 	}
 	sort.Strings(models)
 
+	tokenSetting := getTokenSetting(c)
 	var groupOpenAIModels []*OpenAIModels
 	for _, modelName := range models {
+		if !tokenSetting.IsModelAllowed(modelName) {
+			continue
+		}
 		groupOpenAIModels = append(groupOpenAIModels, getOpenAIModelWithName(modelName))
 	}
 
@@ -123,7 +127,7 @@ func ListModelsForAdmin(c *gin.Context) {
 func RetrieveModel(c *gin.Context) {
 	modelName := c.Param("model")
 	openaiModel := getOpenAIModelWithName(modelName)
-	if *openaiModel.OwnedBy != relay_util.UnknownOwnedBy {
+	if *openaiModel.OwnedBy != relay_util.UnknownOwnedBy && getTokenSetting(c).IsModelAllowed(modelName) {
 		c.JSON(200, openaiModel)
 	} else {
 		openAIError := types.OpenAIError{
@@ -138,6 +142,16 @@ func RetrieveModel(c *gin.Context) {
 	}
 }
 
+func getTokenSetting(c *gin.Context) *model.TokenSetting {
+	if setting, ok := c.Get("token_setting"); ok {
+		if tokenSetting, ok := setting.(*model.TokenSetting); ok {
+			return tokenSetting
+		}
+	}
+
+	return &model.TokenSetting{}
+}
+
 func getModelOwnedBy(channelType int) (ownedBy *string) {
 	if ownedByName, ok := relay_util.ModelOwnedBy[channelType]; ok {
 		return &ownedByName
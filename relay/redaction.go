@@ -0,0 +1,20 @@
+package relay
+
+import (
+	"one-api/relay/relay_util"
+)
+
+// relayRedactable 支持脱敏的中继，替换请求中的敏感内容，并在响应中还原
+type relayRedactable interface {
+	redact(redactor *relay_util.Redactor)
+}
+
+func (r *relayChat) redact(redactor *relay_util.Redactor) {
+	r.redactor = redactor
+	redactor.RedactChatMessages(r.chatRequest.Messages)
+}
+
+func (r *relayCompletions) redact(redactor *relay_util.Redactor) {
+	r.redactor = redactor
+	r.request.Prompt = redactor.RedactPrompt(r.request.Prompt)
+}
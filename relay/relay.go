@@ -7,6 +7,7 @@ import (
 	"one-api/model"
 	"one-api/providers/azure"
 	"one-api/providers/openai"
+	"one-api/relay/relay_util"
 	"strings"
 	"time"
 
@@ -14,6 +15,10 @@ import (
 )
 
 func RelayOnly(c *gin.Context) {
+	if !checkRelayOnlyModel(c) {
+		return
+	}
+
 	provider, _, fail := GetProvider(c, "")
 	if fail != nil {
 		common.AbortWithMessage(c, http.StatusServiceUnavailable, fail.Error())
@@ -66,7 +71,11 @@ func RelayOnly(c *gin.Context) {
 		return
 	}
 
-	errWithCode = responseMultipart(c, response)
+	if collection, objectID := relay_util.ParseRelayObjectPath(path); collection != "" {
+		errWithCode = responseRelayObject(c, response, objectID)
+	} else {
+		errWithCode = responseMultipart(c, response)
+	}
 
 	if errWithCode != nil {
 		relayResponseWithErr(c, errWithCode)
@@ -84,3 +93,21 @@ func RelayOnly(c *gin.Context) {
 	model.RecordConsumeLog(c.Request.Context(), c.GetInt("id"), c.GetInt("channel_id"), 0, 0, "", c.GetString("token_name"), 0, "中继:"+path, requestTime)
 
 }
+
+// checkRelayOnlyModel 透传请求无法确定调用的模型时，例如 run 使用助手的模型，限制了模型的令牌不允许调用
+func checkRelayOnlyModel(c *gin.Context) bool {
+	// 上传文件不调用模型
+	if c.Request.Method != http.MethodPost || strings.HasPrefix(c.Request.URL.Path, "/v1/files") || !getTokenSetting(c).HasModelLimit() {
+		return true
+	}
+
+	var request struct {
+		Model string `json:"model"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &request); err == nil && request.Model != "" {
+		return true
+	}
+
+	common.AbortWithMessage(c, http.StatusForbidden, "该令牌限制了可用模型，无法确定请求使用的模型")
+	return false
+}
@@ -0,0 +1,175 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 创建对象时最多保留的响应长度，流式响应中 thread.created 等事件位于开头
+const relayObjectCaptureSize = 1024 * 1024
+
+// responseRelayObject 转发 assistants、threads、vector_stores、files 的响应并维护对象记录
+// 创建时记录渠道和创建者，删除时移除记录，获取列表时过滤其他用户的对象
+func responseRelayObject(c *gin.Context, resp *http.Response, objectID string) *types.OpenAIErrorWithStatusCode {
+	switch c.Request.Method {
+	case http.MethodGet:
+		if objectID == "" {
+			return responseRelayObjectList(c, resp)
+		}
+	case http.MethodPost:
+		capture := &captureBuffer{limit: relayObjectCaptureSize}
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(resp.Body, capture), resp.Body}
+
+		if errWithCode := responseMultipart(c, resp); errWithCode != nil {
+			return errWithCode
+		}
+		recordRelayObjects(c, capture.Bytes())
+		return nil
+	case http.MethodDelete:
+		if errWithCode := responseMultipart(c, resp); errWithCode != nil {
+			return errWithCode
+		}
+		// 只有删除对象本身时移除记录，删除 thread 中的消息等不处理
+		if objectID != "" && strings.HasSuffix(strings.TrimSuffix(c.Request.URL.Path, "/"), "/"+objectID) {
+			if err := model.DeleteRelayObject(objectID); err != nil {
+				logger.LogError(c.Request.Context(), fmt.Sprintf("failed to delete relay object %s: %s", objectID, err.Error()))
+			}
+		}
+		return nil
+	}
+
+	return responseMultipart(c, resp)
+}
+
+// RelayObjectOnly 确定对象所在的渠道后转发到上游，用于网关同时提供的 files、batches 接口
+func RelayObjectOnly(c *gin.Context) {
+	if statusCode, err := relay_util.SetRelayObjectChannel(c); err != nil {
+		common.AbortWithMessage(c, statusCode, err.Error())
+		return
+	}
+
+	RelayOnly(c)
+}
+
+func recordRelayObjects(c *gin.Context, body []byte) {
+	userId := c.GetInt("id")
+	channelId := c.GetInt("channel_id")
+	for _, ref := range relay_util.ParseRelayObjects(body) {
+		if err := model.InsertRelayObject(ref.ID, ref.Object, userId, channelId); err != nil {
+			logger.LogError(c.Request.Context(), fmt.Sprintf("failed to record relay object %s: %s", ref.ID, err.Error()))
+		}
+	}
+}
+
+func responseRelayObjectList(c *gin.Context, resp *http.Response) *types.OpenAIErrorWithStatusCode {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return common.ErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+	}
+
+	body, err = filterRelayObjectList(c.GetInt("id"), body, c.GetBool("relay_object_specified"))
+	if err != nil {
+		return common.ErrorWrapper(err, "filter_response_body_failed", http.StatusInternalServerError)
+	}
+
+	for k, v := range resp.Header {
+		if k == "Content-Length" {
+			continue
+		}
+		c.Writer.Header().Set(k, v[0])
+	}
+	c.Writer.WriteHeader(resp.StatusCode)
+
+	if _, err := c.Writer.Write(body); err != nil {
+		return common.ErrorWrapper(err, "write_response_body_failed", http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// filterRelayObjectList 只保留当前用户的对象，指定渠道时保留未记录的对象
+func filterRelayObjectList(userId int, body []byte, keepUnrecorded bool) ([]byte, error) {
+	var list map[string]json.RawMessage
+	if err := json.Unmarshal(body, &list); err != nil {
+		return body, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(list["data"], &items); err != nil {
+		return body, nil
+	}
+
+	ids := make([]string, len(items))
+	for i, item := range items {
+		var object struct {
+			ID string `json:"id"`
+		}
+		_ = json.Unmarshal(item, &object)
+		ids[i] = object.ID
+	}
+
+	objects, err := model.GetRelayObjects(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	others := make(map[string]bool)
+	for _, id := range ids {
+		object, ok := objects[id]
+		if (ok && object.UserId != userId) || (!ok && !keepUnrecorded) {
+			others[id] = true
+		}
+	}
+	if len(others) == 0 {
+		return body, nil
+	}
+
+	data := make([]json.RawMessage, 0, len(items))
+	var owned []string
+	for i, item := range items {
+		if others[ids[i]] {
+			continue
+		}
+		data = append(data, item)
+		owned = append(owned, ids[i])
+	}
+
+	list["data"], _ = json.Marshal(data)
+	if len(owned) > 0 {
+		list["first_id"], _ = json.Marshal(owned[0])
+		list["last_id"], _ = json.Marshal(owned[len(owned)-1])
+	} else {
+		list["first_id"], list["last_id"] = json.RawMessage("null"), json.RawMessage("null")
+	}
+
+	return json.Marshal(list)
+}
+
+// captureBuffer 最多保存 limit 字节，超出的部分直接丢弃
+type captureBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *captureBuffer) Write(p []byte) (int, error) {
+	if remain := b.limit - b.Len(); remain > 0 {
+		b.Buffer.Write(p[:min(len(p), remain)])
+	}
+	return len(p), nil
+}
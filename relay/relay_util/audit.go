@@ -0,0 +1,122 @@
+package relay_util
+
+import (
+	"encoding/json"
+	"one-api/types"
+	"strings"
+)
+
+// ReassembleStreamResponse 将 OpenAI 格式的流式响应合并为一个完整的响应，无法解析时返回 false
+func ReassembleStreamResponse(stream string) (string, bool) {
+	var response *types.ChatCompletionResponse
+	choices := make(map[int]*types.ChatCompletionChoice)
+	var choiceIndexes []int
+
+	for _, line := range strings.Split(stream, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+
+		var chunk types.ChatCompletionStreamResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", false
+		}
+
+		if response == nil {
+			response = &types.ChatCompletionResponse{
+				ID:      chunk.ID,
+				Object:  "chat.completion",
+				Created: chunk.Created,
+				Model:   chunk.Model,
+			}
+		}
+		if chunk.Usage != nil {
+			response.Usage = chunk.Usage
+		}
+
+		for _, streamChoice := range chunk.Choices {
+			choice, ok := choices[streamChoice.Index]
+			if !ok {
+				choice = &types.ChatCompletionChoice{
+					Index:   streamChoice.Index,
+					Message: types.ChatCompletionMessage{Role: types.ChatMessageRoleAssistant},
+				}
+				choices[streamChoice.Index] = choice
+				choiceIndexes = append(choiceIndexes, streamChoice.Index)
+			}
+			mergeStreamDelta(choice, &streamChoice)
+		}
+	}
+
+	if response == nil {
+		return "", false
+	}
+
+	for _, index := range choiceIndexes {
+		response.Choices = append(response.Choices, *choices[index])
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return "", false
+	}
+	return string(body), true
+}
+
+func mergeStreamDelta(choice *types.ChatCompletionChoice, streamChoice *types.ChatCompletionStreamChoice) {
+	delta := streamChoice.Delta
+	if delta.Role != "" {
+		choice.Message.Role = delta.Role
+	}
+	if delta.Content != "" {
+		content, _ := choice.Message.Content.(string)
+		choice.Message.Content = content + delta.Content
+	}
+	if streamChoice.FinishReason != nil {
+		choice.FinishReason = streamChoice.FinishReason
+	}
+
+	if delta.FunctionCall != nil {
+		if choice.Message.FunctionCall == nil {
+			choice.Message.FunctionCall = &types.ChatCompletionToolCallsFunction{}
+		}
+		if delta.FunctionCall.Name != "" {
+			choice.Message.FunctionCall.Name = delta.FunctionCall.Name
+		}
+		choice.Message.FunctionCall.Arguments += delta.FunctionCall.Arguments
+	}
+
+	for _, toolCall := range delta.ToolCalls {
+		var target *types.ChatCompletionToolCalls
+		for _, existing := range choice.Message.ToolCalls {
+			if existing.Index == toolCall.Index {
+				target = existing
+				break
+			}
+		}
+		if target == nil {
+			target = &types.ChatCompletionToolCalls{
+				Index:    toolCall.Index,
+				Function: &types.ChatCompletionToolCallsFunction{},
+			}
+			choice.Message.ToolCalls = append(choice.Message.ToolCalls, target)
+		}
+		if toolCall.Id != "" {
+			target.Id = toolCall.Id
+		}
+		if toolCall.Type != "" {
+			target.Type = toolCall.Type
+		}
+		if toolCall.Function != nil {
+			if toolCall.Function.Name != "" {
+				target.Function.Name = toolCall.Function.Name
+			}
+			target.Function.Arguments += toolCall.Function.Arguments
+		}
+	}
+}
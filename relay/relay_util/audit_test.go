@@ -0,0 +1,40 @@
+package relay_util_test
+
+import (
+	"encoding/json"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReassembleStreamResponse(t *testing.T) {
+	stream := `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\""}}]},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":":\"Paris\"}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":3,"completion_tokens":5,"total_tokens":8}}
+
+data: [DONE]
+
+`
+	body, ok := relay_util.ReassembleStreamResponse(stream)
+	assert.True(t, ok)
+
+	var response types.ChatCompletionResponse
+	assert.Nil(t, json.Unmarshal([]byte(body), &response))
+	assert.Equal(t, "chatcmpl-1", response.ID)
+	assert.Equal(t, "chat.completion", response.Object)
+	assert.Len(t, response.Choices, 1)
+	assert.Equal(t, "Hello", response.Choices[0].Message.Content)
+	assert.Equal(t, "tool_calls", response.Choices[0].FinishReason)
+	assert.Equal(t, "call_1", response.Choices[0].Message.ToolCalls[0].Id)
+	assert.Equal(t, `{"city":"Paris"}`, response.Choices[0].Message.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, 8, response.Usage.TotalTokens)
+
+	_, ok = relay_util.ReassembleStreamResponse("event: message_start\ndata: not json\n\n")
+	assert.False(t, ok)
+}
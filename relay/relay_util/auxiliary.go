@@ -0,0 +1,111 @@
+package relay_util
+
+import (
+	"errors"
+	"net/http"
+	"one-api/common/config"
+	"one-api/model"
+	"one-api/providers"
+	providersBase "one-api/providers/base"
+	"one-api/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auxiliaryRequest 网关为语义缓存、内容审核等功能发起的辅助请求
+// 与普通请求一样选择渠道并占用并发，结果计入渠道的健康统计和熔断，用量按照模型价格计费并记录日志
+type auxiliaryRequest struct {
+	c         *gin.Context
+	modelName string
+	channel   *model.Channel
+	provider  providersBase.ProviderInterface
+	release   func()
+	quota     *Quota
+	usage     *types.Usage
+	startTime time.Time
+}
+
+// newAuxiliaryRequest channelId 大于 0 时使用指定的渠道，否则在用户分组中按照模型选择渠道
+func newAuxiliaryRequest(c *gin.Context, channelId int, modelName string, promptTokens int) (*auxiliaryRequest, error) {
+	channel, release, err := acquireAuxiliaryChannel(c, channelId, modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &auxiliaryRequest{
+		c:         c,
+		modelName: modelName,
+		channel:   channel,
+		release:   release,
+		usage:     &types.Usage{PromptTokens: promptTokens},
+	}
+
+	request.provider = providers.GetProvider(channel, c)
+	if request.provider == nil {
+		release()
+		return nil, errors.New("channel not found")
+	}
+	request.provider.SetUsage(request.usage)
+
+	quota, errWithCode := NewQuota(c, modelName, promptTokens)
+	if errWithCode != nil {
+		release()
+		return nil, errors.New(errWithCode.Message)
+	}
+	quota.channelId = channel.Id
+	request.quota = quota
+	request.startTime = time.Now()
+
+	return request, nil
+}
+
+func acquireAuxiliaryChannel(c *gin.Context, channelId int, modelName string) (*model.Channel, func(), error) {
+	if channelId <= 0 {
+		return AcquireChannel(c, c.GetString("group"), modelName, nil)
+	}
+
+	channel := model.ChannelGroup.GetAvailableChannel(channelId)
+	if channel == nil {
+		return nil, nil, errors.New("指定的渠道不可用")
+	}
+	if !model.ChannelCircuitBreakers.Allow(channel.Id, modelName) || !channel.HasAvailableKey() {
+		return nil, nil, errors.New("指定的渠道暂时不可用")
+	}
+
+	release, ok := model.ChannelConcurrencies.Acquire(channel)
+	if !ok {
+		return nil, nil, model.ErrChannelSaturated
+	}
+
+	return channel, release, nil
+}
+
+// getModelName 渠道映射后的模型名称
+func (r *auxiliaryRequest) getModelName() (string, error) {
+	return r.provider.ModelMappingHandler(r.modelName)
+}
+
+// finish 释放并发、记录渠道统计，成功时按照用量计费，失败时退还预扣的额度
+func (r *auxiliaryRequest) finish(errWithCode *types.OpenAIErrorWithStatusCode) {
+	r.release()
+
+	if errWithCode == nil || !errWithCode.LocalError {
+		latency := time.Since(r.startTime)
+		RecordChannelStats(r.c.GetString("group"), r.channel.Id, r.modelName, latency, latency, errWithCode)
+	}
+
+	if errWithCode != nil {
+		r.quota.Undo(r.c)
+		// 多密钥渠道被限流时，暂时跳过该密钥
+		if keyChannel := r.provider.GetChannel(); keyChannel.IsMultiKey() && errWithCode.StatusCode == http.StatusTooManyRequests {
+			model.CooldownChannelKey(keyChannel.Id, keyChannel.Key, config.RetryCooldownSeconds)
+		}
+		return
+	}
+
+	if r.usage.TotalTokens == 0 {
+		r.usage.TotalTokens = r.usage.PromptTokens + r.usage.CompletionTokens
+	}
+	r.quota.Consume(r.c, r.usage)
+}
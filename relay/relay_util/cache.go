@@ -5,7 +5,9 @@ import (
 	"encoding/hex"
 	"fmt"
 	"one-api/common/config"
+	"one-api/common/metrics"
 	"one-api/common/utils"
+	"one-api/model"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,9 +21,16 @@ type ChatCacheProps struct {
 	ModelName        string `json:"model_name"`
 	Response         string `json:"response"`
 
-	Hash   string      `json:"-"`
-	Cache  bool        `json:"-"`
-	Driver CacheDriver `json:"-"`
+	Hash     string      `json:"-"`
+	Cache    bool        `json:"-"`
+	Driver   CacheDriver `json:"-"`
+	Semantic bool        `json:"-"` // 是否为语义缓存命中
+
+	c                 *gin.Context
+	semanticThreshold float64
+	semanticText      string
+	semanticScope     string
+	embedding         []float32
 }
 
 type CacheDriver interface {
@@ -50,6 +59,13 @@ func NewChatCacheProps(c *gin.Context, allow bool) *ChatCacheProps {
 
 	props.UserId = c.GetInt("id")
 	props.TokenId = c.GetInt("token_id")
+	props.c = c
+
+	if setting, ok := c.Get("token_setting"); ok {
+		if tokenSetting, ok := setting.(*model.TokenSetting); ok {
+			props.semanticThreshold = tokenSetting.SemanticCacheThreshold
+		}
+	}
 
 	return props
 }
@@ -94,7 +110,12 @@ func (p *ChatCacheProps) StoreCache(channelId, promptTokens, completionTokens in
 	p.CompletionTokens = completionTokens
 	p.ModelName = modelName
 
-	return p.Driver.Set(p.getHash(), p, int64(config.ChatCacheExpireMinute))
+	expire := int64(config.ChatCacheExpireMinute)
+	if err := p.Driver.Set(p.getHash(), p, expire); err != nil {
+		return err
+	}
+
+	return p.storeSemanticVector(expire)
 }
 
 func (p *ChatCacheProps) GetCache() *ChatCacheProps {
@@ -102,7 +123,15 @@ func (p *ChatCacheProps) GetCache() *ChatCacheProps {
 		return nil
 	}
 
-	return p.Driver.Get(p.getHash(), p.UserId)
+	cache := p.Driver.Get(p.getHash(), p.UserId)
+	if cache == nil {
+		cache = p.getSemanticCache()
+	}
+	if cache != nil {
+		metrics.RecordCacheHit(cache.ModelName)
+	}
+
+	return cache
 }
 
 func (p *ChatCacheProps) needCache() bool {
@@ -0,0 +1,86 @@
+package relay_util
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/metrics"
+	"one-api/model"
+	"one-api/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AcquireChannel 选择渠道并占用一个并发，返回释放并发的函数
+// 所有渠道的并发都已满时排队等待，直到有渠道释放或超时
+func AcquireChannel(c *gin.Context, group, modelName string, filters []model.ChannelsFilterFunc) (*model.Channel, func(), error) {
+	var timeout <-chan time.Time
+	queued := false
+
+	for {
+		changed := model.ChannelConcurrencies.Changed()
+		channel, err := model.ChannelGroup.Next(group, modelName, filters...)
+		if err == nil {
+			release, ok := model.ChannelConcurrencies.Acquire(channel)
+			if ok {
+				if queued {
+					logger.LogInfo(c.Request.Context(), fmt.Sprintf("channel #%d acquired after queueing", channel.Id))
+				}
+				return channel, release, nil
+			}
+			// 选中后被其他请求抢先占满，按照饱和处理
+			channel, err = nil, model.ErrChannelSaturated
+		}
+
+		if !errors.Is(err, model.ErrChannelSaturated) || config.ChannelQueueTimeoutSeconds <= 0 {
+			return channel, nil, err
+		}
+
+		if timeout == nil {
+			queued = true
+			timer := time.NewTimer(time.Duration(config.ChannelQueueTimeoutSeconds) * time.Second)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+
+		// 冷却、熔断的渠道恢复时不会通知，需要定时重新选择
+		select {
+		case <-changed:
+		case <-time.After(time.Second):
+		case <-timeout:
+			return nil, nil, model.ErrChannelSaturated
+		case <-c.Request.Context().Done():
+			return nil, nil, model.ErrChannelSaturated
+		}
+	}
+}
+
+// RecordChannelStats 记录渠道的健康统计、监控指标和熔断状态
+func RecordChannelStats(group string, channelId int, modelName string, latency, ttft time.Duration, apiErr *types.OpenAIErrorWithStatusCode) {
+	model.ChannelHealthStats.Record(channelId, apiErr == nil, latency, ttft)
+
+	statusCode := 0
+	if apiErr != nil {
+		statusCode = apiErr.StatusCode
+	}
+	metrics.RecordRelayRequest(group, modelName, channelId, statusCode, latency, ttft)
+
+	if apiErr == nil {
+		model.ChannelCircuitBreakers.RecordSuccess(channelId, modelName)
+	} else if IsCircuitBreakerFailure(apiErr) {
+		// 404 通常是渠道不支持该模型，只熔断该模型
+		model.ChannelCircuitBreakers.RecordFailure(channelId, modelName, apiErr.StatusCode != http.StatusNotFound)
+	}
+}
+
+// IsCircuitBreakerFailure 请求参数错误等由用户引起的错误不计入熔断
+func IsCircuitBreakerFailure(apiErr *types.OpenAIErrorWithStatusCode) bool {
+	switch apiErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return apiErr.StatusCode/100 == 5
+}
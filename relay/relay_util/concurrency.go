@@ -0,0 +1,26 @@
+package relay_util
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+const channelReleaseKey = "channel_release"
+
+// SetChannelRelease 保存当前请求占用的渠道并发，重试切换渠道时先释放之前占用的并发
+func SetChannelRelease(c *gin.Context, release func()) {
+	ReleaseChannel(c)
+	c.Set(channelReleaseKey, release)
+}
+
+// ReleaseChannel 释放当前请求占用的渠道并发
+func ReleaseChannel(c *gin.Context) {
+	value, ok := c.Get(channelReleaseKey)
+	if !ok {
+		return
+	}
+
+	if release, ok := value.(func()); ok && release != nil {
+		release()
+	}
+	c.Set(channelReleaseKey, nil)
+}
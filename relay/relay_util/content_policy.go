@@ -0,0 +1,146 @@
+package relay_util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/model"
+	providersBase "one-api/providers/base"
+	"one-api/types"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContentPolicyViolationCode = "content_policy_violation"
+
+type moderationResult struct {
+	Flagged    bool            `json:"flagged"`
+	Categories map[string]bool `json:"categories"`
+}
+
+// CheckContentPolicy 按照分组的内容策略检查请求中的文本，违规时记录日志并返回 content_policy_violation 错误
+func CheckContentPolicy(c *gin.Context, modelName string, texts []string) *types.OpenAIErrorWithStatusCode {
+	policy := common.GetGroupContentPolicy(c.GetString("group"))
+	if policy == nil {
+		return nil
+	}
+
+	text := strings.TrimSpace(strings.Join(texts, "\n"))
+	if text == "" {
+		return nil
+	}
+
+	reason := ""
+	if rule, ok := policy.MatchBlocklist(text); ok {
+		reason = "命中规则 " + rule
+	} else if policy.Moderation {
+		categories, err := moderateContent(c, policy, text)
+		if err != nil {
+			logger.LogError(c.Request.Context(), "content moderation failed: "+err.Error())
+			if !policy.FailClosed {
+				return nil
+			}
+			reason = "审核接口不可用"
+		} else if categories != nil {
+			reason = "审核未通过 " + strings.Join(categories, ",")
+		}
+	}
+
+	if reason == "" {
+		return nil
+	}
+
+	model.RecordLog(c.GetInt("id"), model.LogTypeViolation, fmt.Sprintf("请求内容违反内容策略，%s，模型 %s，令牌 %s", reason, modelName, c.GetString("token_name")))
+
+	return &types.OpenAIErrorWithStatusCode{
+		OpenAIError: types.OpenAIError{
+			Message: "请求内容违反内容策略",
+			Type:    "invalid_request_error",
+			Code:    ContentPolicyViolationCode,
+		},
+		StatusCode: http.StatusBadRequest,
+		LocalError: true,
+	}
+}
+
+// moderateContent 调用审核接口，返回命中的类别，未命中时返回 nil，按照审核模型的价格计费
+func moderateContent(c *gin.Context, policy *common.ContentPolicy, text string) ([]string, error) {
+	modelName := policy.GetModerationModel()
+	request, err := newAuxiliaryRequest(c, policy.ModerationChannelId, modelName, common.CountTokenInput(text, modelName))
+	if err != nil {
+		return nil, err
+	}
+
+	response, errWithCode := createModeration(request, text)
+	request.finish(errWithCode)
+	if errWithCode != nil {
+		return nil, errors.New(errWithCode.Message)
+	}
+
+	return ParseModerationResults(response.Results)
+}
+
+func createModeration(request *auxiliaryRequest, text string) (*types.ModerationResponse, *types.OpenAIErrorWithStatusCode) {
+	moderationProvider, ok := request.provider.(providersBase.ModerationInterface)
+	if !ok {
+		return nil, common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+	}
+
+	modelName, err := request.getModelName()
+	if err != nil {
+		return nil, common.ErrorWrapperLocal(err, "model_mapping_error", http.StatusInternalServerError)
+	}
+
+	return moderationProvider.CreateModeration(&types.ModerationRequest{
+		Model: modelName,
+		Input: text,
+	})
+}
+
+// ParseModerationResults 解析审核接口的结果，返回被标记的类别
+func ParseModerationResults(results any) ([]string, error) {
+	data, err := json.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []moderationResult
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+
+	flagged := false
+	categorySet := make(map[string]bool)
+	for _, item := range items {
+		if !item.Flagged {
+			continue
+		}
+		flagged = true
+		for category, hit := range item.Categories {
+			if hit {
+				categorySet[category] = true
+			}
+		}
+	}
+
+	if !flagged {
+		return nil, nil
+	}
+
+	categories := make([]string, 0, len(categorySet))
+	for category := range categorySet {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	if len(categories) == 0 {
+		categories = append(categories, "flagged")
+	}
+
+	return categories, nil
+}
@@ -0,0 +1,44 @@
+package relay_util_test
+
+import (
+	"one-api/common"
+	"one-api/relay/relay_util"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseModerationResults(t *testing.T) {
+	categories, err := relay_util.ParseModerationResults([]any{
+		map[string]any{"flagged": false, "categories": map[string]any{"hate": false}},
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, categories)
+
+	categories, err = relay_util.ParseModerationResults([]any{
+		map[string]any{"flagged": true, "categories": map[string]any{"violence": true, "hate": false, "harassment": true}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"harassment", "violence"}, categories)
+}
+
+func TestContentPolicyBlocklist(t *testing.T) {
+	err := common.UpdateGroupContentPolicyByJSONString(`{"default":{"keywords":["Secret Project"],"patterns":["\\b\\d{4}-\\d{4}\\b"]}}`)
+	assert.NoError(t, err)
+	defer common.UpdateGroupContentPolicyByJSONString(`{}`)
+
+	policy := common.GetGroupContentPolicy("default")
+	rule, ok := policy.MatchBlocklist("tell me about the secret project")
+	assert.True(t, ok)
+	assert.Equal(t, "Secret Project", rule)
+
+	_, ok = policy.MatchBlocklist("card 1234-5678")
+	assert.True(t, ok)
+
+	_, ok = policy.MatchBlocklist("hello world")
+	assert.False(t, ok)
+
+	// 无效的正则表达式不会覆盖已有配置
+	assert.Error(t, common.UpdateGroupContentPolicyByJSONString(`{"default":{"patterns":["("]}}`))
+	assert.NotNil(t, common.GetGroupContentPolicy("default"))
+}
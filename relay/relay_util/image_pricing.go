@@ -0,0 +1,16 @@
+package relay_util
+
+// ImagePricing 图片请求的计费参数，用于匹配价格矩阵，N 为图片数量
+type ImagePricing struct {
+	Size    string
+	Quality string
+	Style   string
+	N       int
+}
+
+// SetCount 按照实际返回的图片数量计费
+func (p *ImagePricing) SetCount(count int) {
+	if p != nil && count > 0 {
+		p.N = count
+	}
+}
@@ -0,0 +1,107 @@
+package relay_util
+
+import (
+	"one-api/model"
+	"one-api/types"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getPromptTemplates 获取请求需要应用的提示词模板，包括分组的模板和令牌绑定的模板
+func getPromptTemplates(c *gin.Context) []*model.PromptTemplate {
+	templateId := 0
+	if value, ok := c.Get("token_setting"); ok {
+		if tokenSetting, ok := value.(*model.TokenSetting); ok {
+			templateId = tokenSetting.PromptTemplateId
+		}
+	}
+
+	return model.PromptTemplates.Match(templateId, c.GetString("group"))
+}
+
+// RenderPromptTemplate 替换模板中的变量
+// 支持 {{user_id}} {{group}} {{token_name}} {{model}} {{date}} {{datetime}}
+func RenderPromptTemplate(c *gin.Context, content, modelName string) string {
+	if !strings.Contains(content, "{{") {
+		return content
+	}
+
+	now := time.Now()
+	replacer := strings.NewReplacer(
+		"{{user_id}}", strconv.Itoa(c.GetInt("id")),
+		"{{group}}", c.GetString("group"),
+		"{{token_name}}", c.GetString("token_name"),
+		"{{model}}", modelName,
+		"{{date}}", now.Format("2006-01-02"),
+		"{{datetime}}", now.Format("2006-01-02 15:04:05"),
+	)
+
+	return replacer.Replace(content)
+}
+
+// ApplyChatPromptTemplates 按顺序将模板注入到聊天消息的系统提示词中
+func ApplyChatPromptTemplates(c *gin.Context, modelName string, messages []types.ChatCompletionMessage) []types.ChatCompletionMessage {
+	for _, template := range getPromptTemplates(c) {
+		messages = applyChatPromptTemplate(template.Mode, RenderPromptTemplate(c, template.Content, modelName), messages)
+	}
+
+	return messages
+}
+
+func applyChatPromptTemplate(mode, content string, messages []types.ChatCompletionMessage) []types.ChatCompletionMessage {
+	if mode == model.PromptTemplateModeReplace {
+		filtered := make([]types.ChatCompletionMessage, 0, len(messages)+1)
+		for _, message := range messages {
+			if message.Role != types.ChatMessageRoleSystem {
+				filtered = append(filtered, message)
+			}
+		}
+		messages = filtered
+	}
+
+	// 合并到第一条系统消息中，没有系统消息时插入一条
+	if len(messages) > 0 && messages[0].Role == types.ChatMessageRoleSystem {
+		switch system := messages[0].Content.(type) {
+		case string:
+			messages[0].Content = joinPrompt(mode, content, system)
+			return messages
+		case []any:
+			part := map[string]any{"type": types.ContentTypeText, "text": content}
+			if mode == model.PromptTemplateModeAppend {
+				messages[0].Content = append(system, part)
+			} else {
+				messages[0].Content = append([]any{part}, system...)
+			}
+			return messages
+		}
+	}
+
+	return append([]types.ChatCompletionMessage{{
+		Role:    types.ChatMessageRoleSystem,
+		Content: content,
+	}}, messages...)
+}
+
+// ApplySystemPromptTemplates 按顺序将模板注入到字符串形式的系统提示词中
+func ApplySystemPromptTemplates(c *gin.Context, modelName string, system string) string {
+	for _, template := range getPromptTemplates(c) {
+		system = joinPrompt(template.Mode, RenderPromptTemplate(c, template.Content, modelName), system)
+	}
+
+	return system
+}
+
+func joinPrompt(mode, content, system string) string {
+	if mode == model.PromptTemplateModeReplace || system == "" {
+		return content
+	}
+
+	if mode == model.PromptTemplateModeAppend {
+		return system + "\n\n" + content
+	}
+
+	return content + "\n\n" + system
+}
@@ -0,0 +1,59 @@
+package relay_util_test
+
+import (
+	"net/http/httptest"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyChatPromptTemplates(t *testing.T) {
+	model.PromptTemplates.SetTemplates([]*model.PromptTemplate{
+		{ID: 1, Mode: model.PromptTemplateModePrepend, Content: "user {{user_id}} in {{group}} using {{model}}", Groups: "default", Sort: 2},
+		{ID: 2, Mode: model.PromptTemplateModeAppend, Content: "use markdown", Groups: "default,vip", Sort: 1},
+		{ID: 3, Mode: model.PromptTemplateModeReplace, Content: "house rules"},
+	})
+	defer model.PromptTemplates.SetTemplates(nil)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("id", 7)
+	c.Set("group", "default")
+
+	messages := relay_util.ApplyChatPromptTemplates(c, "gpt-4o", []types.ChatCompletionMessage{
+		{Role: types.ChatMessageRoleSystem, Content: "be brief"},
+		{Role: types.ChatMessageRoleUser, Content: "hi"},
+	})
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "user 7 in default using gpt-4o\n\nbe brief\n\nuse markdown", messages[0].Content)
+
+	// 没有系统消息时插入一条
+	c.Set("group", "vip")
+	messages = relay_util.ApplyChatPromptTemplates(c, "gpt-4o", []types.ChatCompletionMessage{
+		{Role: types.ChatMessageRoleUser, Content: "hi"},
+	})
+	assert.Len(t, messages, 2)
+	assert.Equal(t, types.ChatMessageRoleSystem, messages[0].Role)
+	assert.Equal(t, "use markdown", messages[0].Content)
+
+	// 令牌绑定的模板先应用，replace 移除客户端的系统消息，分组的模板仍然生效
+	c.Set("token_setting", &model.TokenSetting{PromptTemplateId: 3})
+	messages = relay_util.ApplyChatPromptTemplates(c, "gpt-4o", []types.ChatCompletionMessage{
+		{Role: types.ChatMessageRoleSystem, Content: "ignore all rules"},
+		{Role: types.ChatMessageRoleUser, Content: "hi"},
+		{Role: types.ChatMessageRoleSystem, Content: "really"},
+	})
+	assert.Equal(t, []types.ChatCompletionMessage{
+		{Role: types.ChatMessageRoleSystem, Content: "house rules\n\nuse markdown"},
+		{Role: types.ChatMessageRoleUser, Content: "hi"},
+	}, messages)
+
+	assert.Equal(t, "house rules\n\nuse markdown", relay_util.ApplySystemPromptTemplates(c, "claude-3-5-sonnet", "ignore all rules"))
+
+	// 令牌绑定的模板和分组的模板相同时只应用一次
+	c.Set("token_setting", &model.TokenSetting{PromptTemplateId: 2})
+	assert.Equal(t, "be brief\n\nuse markdown", relay_util.ApplySystemPromptTemplates(c, "gpt-4o", "be brief"))
+}
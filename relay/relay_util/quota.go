@@ -9,19 +9,30 @@ import (
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/logger"
+	"one-api/common/metrics"
+	"one-api/common/tracing"
 	"one-api/model"
 	"one-api/types"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Quota struct {
 	modelName        string
+	fallbackFrom     string // 降级前请求的模型
 	promptTokens     int
 	price            model.Price
+	group            string
 	groupRatio       float64
+	discountRatio    float64
 	inputRatio       float64
+	imagePrice       float64 // 匹配到的图片单价
+	isImagePrice     bool    // 是否匹配到图片价格矩阵
+	count            int     // 按次计费的次数，图片请求为图片数量
+	imagePricing     *ImagePricing
 	preConsumedQuota int
 	userId           int
 	channelId        int
@@ -30,8 +41,14 @@ type Quota struct {
 }
 
 func NewQuota(c *gin.Context, modelName string, promptTokens int) (*Quota, *types.OpenAIErrorWithStatusCode) {
+	return NewImageQuota(c, modelName, promptTokens, nil)
+}
+
+// NewImageQuota 图片请求按照价格矩阵和图片数量计费，imagePricing 为空时与 NewQuota 相同
+func NewImageQuota(c *gin.Context, modelName string, promptTokens int, imagePricing *ImagePricing) (*Quota, *types.OpenAIErrorWithStatusCode) {
 	quota := &Quota{
 		modelName:    modelName,
+		fallbackFrom: c.GetString("fallback_from_model"),
 		promptTokens: promptTokens,
 		userId:       c.GetInt("id"),
 		channelId:    c.GetInt("channel_id"),
@@ -39,24 +56,62 @@ func NewQuota(c *gin.Context, modelName string, promptTokens int) (*Quota, *type
 		HandelStatus: false,
 	}
 
+	_, span := tracing.Start(c.Request.Context(), "relay_util.NewQuota", attribute.String("model", modelName), attribute.Int("prompt_tokens", promptTokens))
+
 	quota.price = *PricingInstance.GetPrice(quota.modelName)
-	quota.groupRatio = common.GetGroupRatio(c.GetString("group"))
-	quota.inputRatio = quota.price.GetInput() * quota.groupRatio
+	quota.group = c.GetString("group")
+	quota.groupRatio = common.GetGroupRatio(quota.group)
+	quota.discountRatio = 1
+	// 批处理中的请求按照批处理倍率计费
+	if c.GetBool("batch_request") && config.BatchDiscountRatio >= 0 {
+		quota.discountRatio = config.BatchDiscountRatio
+	}
+	quota.inputRatio = quota.price.GetInput() * quota.groupRatio * quota.discountRatio
+	quota.count = 1
+	if imagePricing != nil {
+		quota.setImagePricing(imagePricing)
+	}
 
-	if quota.price.Type == model.TimesPriceType {
-		quota.preConsumedQuota = int(1000 * quota.inputRatio)
+	if quota.isTimes() {
+		quota.preConsumedQuota = quota.GetTimesQuota()
 	} else if quota.price.Input != 0 || quota.price.Output != 0 {
-		quota.preConsumedQuota = int(float64(quota.promptTokens)*quota.inputRatio) + config.PreConsumedQuota
+		tierInputRatio, _ := quota.price.GetTierRatio(quota.promptTokens)
+		quota.preConsumedQuota = int(float64(quota.promptTokens)*quota.inputRatio*tierInputRatio) + config.PreConsumedQuota
 	}
 
 	errWithCode := quota.preQuotaConsumption()
+	span.SetAttributes(attribute.Int("pre_consumed_quota", quota.preConsumedQuota))
 	if errWithCode != nil {
+		tracing.End(span, errWithCode)
 		return nil, errWithCode
 	}
+	tracing.End(span, nil)
 
 	return quota, nil
 }
 
+// setImagePricing 匹配到图片价格矩阵时按张计费，按次计费的模型同样乘以图片数量
+func (q *Quota) setImagePricing(pricing *ImagePricing) {
+	q.imagePricing = pricing
+	if imagePrice, ok := q.price.GetImagePrice(pricing.Size, pricing.Quality, pricing.Style); ok {
+		q.imagePrice, q.isImagePrice = imagePrice, true
+		q.inputRatio = imagePrice * q.groupRatio * q.discountRatio
+	}
+
+	if q.isTimes() {
+		q.count = max(pricing.N, 1)
+	}
+}
+
+func (q *Quota) isTimes() bool {
+	return q.isImagePrice || q.price.Type == model.TimesPriceType
+}
+
+// GetTimesQuota 按次计费的配额
+func (q *Quota) GetTimesQuota() int {
+	return int(1000 * q.inputRatio * float64(q.count))
+}
+
 func (q *Quota) preQuotaConsumption() *types.OpenAIErrorWithStatusCode {
 	if q.preConsumedQuota == 0 {
 		return nil
@@ -99,11 +154,10 @@ func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string,
 	promptTokens := usage.PromptTokens
 	completionTokens := usage.CompletionTokens
 
-	if q.price.Type == model.TimesPriceType {
-		quota = int(1000 * q.inputRatio)
+	if q.isTimes() {
+		quota = q.GetTimesQuota()
 	} else {
-		completionRatio := q.price.GetOutput() * q.groupRatio
-		quota = int(math.Ceil((float64(promptTokens) * q.inputRatio) + (float64(completionTokens) * completionRatio)))
+		quota = int(math.Ceil(GetTokensQuota(&q.price, usage, q.groupRatio*q.discountRatio)))
 	}
 
 	if q.inputRatio != 0 && quota <= 0 {
@@ -134,8 +188,13 @@ func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string,
 		}
 	}
 	var modelRatioStr string
-	if q.price.Type == model.TimesPriceType {
+	if q.isImagePrice {
+		modelRatioStr = fmt.Sprintf("$%s/张 × %d", decimal.NewFromFloat(q.imagePrice).Mul(decimal.NewFromFloat(model.DollarRate)).String(), q.count)
+	} else if q.price.Type == model.TimesPriceType {
 		modelRatioStr = fmt.Sprintf("$%s/次", q.price.FetchInputCurrencyPrice(model.DollarRate))
+		if q.count > 1 {
+			modelRatioStr += fmt.Sprintf(" × %d", q.count)
+		}
 	} else {
 		// 如果输入费率和输出费率一样，则只显示一个费率
 		if q.price.GetInput() == q.price.GetOutput() {
@@ -146,13 +205,85 @@ func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string,
 	}
 
 	logContent := fmt.Sprintf("模型费率 %s，分组倍率 %.2f", modelRatioStr, q.groupRatio)
+	if q.discountRatio != 1 {
+		logContent += fmt.Sprintf("，批处理倍率 %.2f", q.discountRatio)
+	}
+	if q.fallbackFrom != "" {
+		logContent += fmt.Sprintf("，由模型 %s 降级", q.fallbackFrom)
+	}
+	if !q.isTimes() {
+		logContent += usageDetailLog(&q.price, usage)
+	}
 	model.RecordConsumeLog(ctx, q.userId, q.channelId, promptTokens, completionTokens, q.modelName, tokenName, quota, logContent, requestTime)
 	model.UpdateUserUsedQuotaAndRequestCount(q.userId, quota)
 	model.UpdateChannelUsedQuota(q.channelId, quota)
+	metrics.RecordQuotaConsumed(q.group, q.modelName, quota)
 
 	return nil
 }
 
+// GetTokensQuota 按照用量分项计费，缓存读写、音频、图片输入以及思考的 token 使用各自的价格，
+// 剩余的 token 按照输入和输出价格计费，超过分级阈值时再乘以对应的倍率
+func GetTokensQuota(price *model.Price, usage *types.Usage, ratio float64) float64 {
+	var cachedTokens, cachedWriteTokens, audioInputTokens, imageInputTokens, reasoningTokens, audioOutputTokens int
+	if details := usage.PromptTokensDetails; details != nil {
+		cachedTokens, cachedWriteTokens = details.CachedTokens, details.CachedWriteTokens
+		audioInputTokens, imageInputTokens = details.AudioTokens, details.ImageTokens
+	}
+	if details := usage.CompletionTokensDetails; details != nil {
+		reasoningTokens, audioOutputTokens = details.ReasoningTokens, details.AudioTokens
+	}
+
+	textInputTokens := max(usage.PromptTokens-cachedTokens-cachedWriteTokens-audioInputTokens-imageInputTokens, 0)
+	textOutputTokens := max(usage.CompletionTokens-reasoningTokens-audioOutputTokens, 0)
+
+	input := float64(textInputTokens)*price.GetInput() +
+		float64(cachedTokens)*price.GetCachedInput() +
+		float64(cachedWriteTokens)*price.GetCachedWriteInput() +
+		float64(audioInputTokens)*price.GetAudioInput() +
+		float64(imageInputTokens)*price.GetImageInput()
+	output := float64(textOutputTokens)*price.GetOutput() +
+		float64(reasoningTokens)*price.GetReasoning() +
+		float64(audioOutputTokens)*price.GetAudioOutput()
+
+	inputTierRatio, outputTierRatio := price.GetTierRatio(usage.PromptTokens)
+
+	return (input*inputTierRatio + output*outputTierRatio) * ratio
+}
+
+func usageDetailLog(price *model.Price, usage *types.Usage) string {
+	var logContent string
+	if details := usage.PromptTokensDetails; details != nil {
+		if details.CachedTokens > 0 {
+			logContent += fmt.Sprintf("，缓存输入 %d tokens", details.CachedTokens)
+		}
+		if details.CachedWriteTokens > 0 {
+			logContent += fmt.Sprintf("，缓存写入 %d tokens", details.CachedWriteTokens)
+		}
+		if details.AudioTokens > 0 {
+			logContent += fmt.Sprintf("，音频输入 %d tokens", details.AudioTokens)
+		}
+		if details.ImageTokens > 0 {
+			logContent += fmt.Sprintf("，图片输入 %d tokens", details.ImageTokens)
+		}
+	}
+	if details := usage.CompletionTokensDetails; details != nil {
+		if details.ReasoningTokens > 0 {
+			logContent += fmt.Sprintf("，思考 %d tokens", details.ReasoningTokens)
+		}
+		if details.AudioTokens > 0 {
+			logContent += fmt.Sprintf("，音频输出 %d tokens", details.AudioTokens)
+		}
+	}
+
+	inputTierRatio, outputTierRatio := price.GetTierRatio(usage.PromptTokens)
+	if inputTierRatio != 1 || outputTierRatio != 1 {
+		logContent += fmt.Sprintf("，上下文分级倍率 %.2f (输入) | %.2f (输出)", inputTierRatio, outputTierRatio)
+	}
+
+	return logContent
+}
+
 func (q *Quota) Undo(c *gin.Context) {
 	tokenId := c.GetInt("token_id")
 	if q.HandelStatus {
@@ -168,6 +299,11 @@ func (q *Quota) Undo(c *gin.Context) {
 
 func (q *Quota) Consume(c *gin.Context, usage *types.Usage) {
 	tokenName := c.GetString("token_name")
+	// 图片请求按照实际返回的数量计费
+	if q.imagePricing != nil && q.isTimes() {
+		q.count = max(q.imagePricing.N, 1)
+	}
+	RecordRateLimitTokens(c, usage.PromptTokens+usage.CompletionTokens)
 	// 如果没有报错，则消费配额
 	go func(ctx context.Context) {
 		err := q.completedQuotaConsumption(usage, tokenName, ctx)
@@ -0,0 +1,49 @@
+package relay_util_test
+
+import (
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestGetTokensQuota(t *testing.T) {
+	price := &model.Price{Type: model.TokensPriceType, Input: 2, Output: 8}
+	usage := &types.Usage{PromptTokens: 1000, CompletionTokens: 500}
+	assert.Equal(t, 6000.0, relay_util.GetTokensQuota(price, usage, 1))
+	assert.Equal(t, 3000.0, relay_util.GetTokensQuota(price, usage, 0.5))
+
+	// 未设置分项价格时按照输入输出价格计费
+	usage.PromptTokensDetails = &types.PromptTokensDetails{CachedTokens: 400}
+	usage.CompletionTokensDetails = &types.CompletionTokensDetails{ReasoningTokens: 200}
+	assert.Equal(t, 6000.0, relay_util.GetTokensQuota(price, usage, 1))
+
+	price.CachedInput = 0.5
+	price.Reasoning = 4
+	// 600*2 + 400*0.5 + 300*8 + 200*4
+	assert.Equal(t, 4600.0, relay_util.GetTokensQuota(price, usage, 1))
+
+	// 写入缓存的 token 使用单独的价格，未设置时按照输入价格计费
+	usage.PromptTokensDetails.CachedWriteTokens = 300
+	// 300*2 + 400*0.5 + 300*2 + 300*8 + 200*4
+	assert.Equal(t, 4600.0, relay_util.GetTokensQuota(price, usage, 1))
+	price.CachedWriteInput = 2.5
+	// 300*2 + 400*0.5 + 300*2.5 + 300*8 + 200*4
+	assert.Equal(t, 4750.0, relay_util.GetTokensQuota(price, usage, 1))
+	usage.PromptTokensDetails.CachedWriteTokens = 0
+
+	tiers := datatypes.NewJSONType([]model.PriceTier{
+		{Tokens: 128000, InputRatio: 2, OutputRatio: 1.5},
+		{Tokens: 500, InputRatio: 3},
+	})
+	price.Tiers = &tiers
+	// 输入超过 500，匹配阈值最高的一级，输出倍率为 0 时按 1 计算
+	assert.Equal(t, 1400*3+3200.0, relay_util.GetTokensQuota(price, usage, 1))
+
+	usage.PromptTokens = 200000
+	usage.PromptTokensDetails = nil
+	assert.Equal(t, 200000*2*2+3200*1.5, relay_util.GetTokensQuota(price, usage, 1))
+}
@@ -0,0 +1,205 @@
+package relay_util
+
+import (
+	"context"
+	"fmt"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/redis"
+	"one-api/model"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const rateLimitDuration int64 = 60
+
+var relayRateLimiter common.InMemoryRateLimiter
+
+type RateLimitRule struct {
+	Key string
+	RPM int
+	TPM int
+}
+
+type RateLimitStatus struct {
+	LimitRequests     int
+	RemainingRequests int
+	ResetRequests     time.Duration
+	LimitTokens       int
+	RemainingTokens   int
+	ResetTokens       time.Duration
+	// 被限制的维度，requests 或 tokens，为空表示未超限
+	Exceeded string
+}
+
+// GetRateLimitRules 获取当前请求需要检查的令牌、用户、分组速率限制
+func GetRateLimitRules(c *gin.Context) []RateLimitRule {
+	var rules []RateLimitRule
+
+	if setting, ok := c.Get("token_setting"); ok {
+		if tokenSetting, ok := setting.(*model.TokenSetting); ok && (tokenSetting.RPM > 0 || tokenSetting.TPM > 0) {
+			rules = append(rules, RateLimitRule{
+				Key: fmt.Sprintf("token:%d", c.GetInt("token_id")),
+				RPM: tokenSetting.RPM,
+				TPM: tokenSetting.TPM,
+			})
+		}
+	}
+
+	group := c.GetString("group")
+	groupLimit := common.GetGroupRateLimit(group)
+	if groupLimit.UserRPM > 0 || groupLimit.UserTPM > 0 {
+		rules = append(rules, RateLimitRule{
+			Key: fmt.Sprintf("user:%d", c.GetInt("id")),
+			RPM: groupLimit.UserRPM,
+			TPM: groupLimit.UserTPM,
+		})
+	}
+	if groupLimit.RPM > 0 || groupLimit.TPM > 0 {
+		rules = append(rules, RateLimitRule{
+			Key: "group:" + group,
+			RPM: groupLimit.RPM,
+			TPM: groupLimit.TPM,
+		})
+	}
+
+	return rules
+}
+
+// CheckRateLimit 检查所有规则的 tokens 用量和请求数，请求数先原子累加再判断是否超限，
+// 被拒绝时回退本次累加，被拒绝的请求不占用额度；返回最紧张的一组限额用于响应头
+func CheckRateLimit(ctx context.Context, rules []RateLimitRule) *RateLimitStatus {
+	status := &RateLimitStatus{}
+
+	for _, rule := range rules {
+		if rule.TPM <= 0 {
+			continue
+		}
+		used, reset := rateLimitCount(ctx, rule.Key+":tpm")
+		remaining := rule.TPM - int(used)
+		status.mergeTokens(rule.TPM, remaining, reset)
+		if remaining <= 0 && status.Exceeded == "" {
+			status.Exceeded = "tokens"
+		}
+	}
+
+	if status.Exceeded != "" {
+		return status
+	}
+
+	type counted struct {
+		key         string
+		windowStart int64
+	}
+	var increased []counted
+	for _, rule := range rules {
+		if rule.RPM <= 0 {
+			continue
+		}
+		key := rule.Key + ":rpm"
+		used, windowStart := rateLimitIncr(ctx, key, 1)
+		increased = append(increased, counted{key, windowStart})
+		if int(used) > rule.RPM {
+			for _, item := range increased {
+				rateLimitRollback(ctx, item.key, item.windowStart)
+			}
+			status.LimitRequests = 0
+			status.mergeRequests(rule.RPM, 0, rateLimitReset(windowStart))
+			status.Exceeded = "requests"
+			return status
+		}
+		status.mergeRequests(rule.RPM, rule.RPM-int(used), rateLimitReset(windowStart))
+	}
+
+	return status
+}
+
+// RecordRateLimitTokens 请求完成后累加 tokens 用量
+func RecordRateLimitTokens(c *gin.Context, tokens int) {
+	if tokens <= 0 {
+		return
+	}
+
+	for _, rule := range GetRateLimitRules(c) {
+		if rule.TPM <= 0 {
+			continue
+		}
+		rateLimitIncr(c.Request.Context(), rule.Key+":tpm", int64(tokens))
+	}
+}
+
+func (s *RateLimitStatus) mergeRequests(limit, remaining int, reset time.Duration) {
+	if remaining < 0 {
+		remaining = 0
+	}
+	if s.LimitRequests == 0 || remaining < s.RemainingRequests {
+		s.LimitRequests = limit
+		s.RemainingRequests = remaining
+		s.ResetRequests = reset
+	}
+}
+
+func (s *RateLimitStatus) mergeTokens(limit, remaining int, reset time.Duration) {
+	if remaining < 0 {
+		remaining = 0
+	}
+	if s.LimitTokens == 0 || remaining < s.RemainingTokens {
+		s.LimitTokens = limit
+		s.RemainingTokens = remaining
+		s.ResetTokens = reset
+	}
+}
+
+// rateLimitIncr 累加当前窗口的计数，返回累加后的计数以及窗口开始时间
+func rateLimitIncr(ctx context.Context, key string, amount int64) (int64, int64) {
+	if !config.RedisEnabled {
+		relayRateLimiter.Init(config.RateLimitKeyExpirationDuration)
+		return relayRateLimiter.Incr(key, amount, rateLimitDuration)
+	}
+
+	windowStart := time.Now().Unix() / rateLimitDuration * rateLimitDuration
+	redisKey := fmt.Sprintf("relayRateLimit:%s:%d", key, windowStart)
+	pipe := redis.RDB.TxPipeline()
+	incr := pipe.IncrBy(ctx, redisKey, amount)
+	pipe.Expire(ctx, redisKey, time.Duration(rateLimitDuration)*time.Second)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.LogError(ctx, "rate limit incr failed: "+err.Error())
+		return 0, windowStart
+	}
+
+	return incr.Val(), windowStart
+}
+
+// rateLimitRollback 回退被拒绝请求的累加，窗口已经切换时无需回退
+func rateLimitRollback(ctx context.Context, key string, windowStart int64) {
+	if time.Now().Unix()/rateLimitDuration*rateLimitDuration != windowStart {
+		return
+	}
+	rateLimitIncr(ctx, key, -1)
+}
+
+func rateLimitCount(ctx context.Context, key string) (int64, time.Duration) {
+	if !config.RedisEnabled {
+		relayRateLimiter.Init(config.RateLimitKeyExpirationDuration)
+		count, windowStart := relayRateLimiter.Count(key, rateLimitDuration)
+		return count, rateLimitReset(windowStart)
+	}
+
+	windowStart := time.Now().Unix() / rateLimitDuration * rateLimitDuration
+	count, err := redis.RDB.Get(ctx, fmt.Sprintf("relayRateLimit:%s:%d", key, windowStart)).Int64()
+	if err != nil {
+		return 0, rateLimitReset(windowStart)
+	}
+
+	return count, rateLimitReset(windowStart)
+}
+
+func rateLimitReset(windowStart int64) time.Duration {
+	reset := windowStart + rateLimitDuration - time.Now().Unix()
+	if reset < 0 {
+		reset = 0
+	}
+	return time.Duration(reset) * time.Second
+}
@@ -0,0 +1,76 @@
+package relay_util_test
+
+import (
+	"context"
+	"one-api/relay/relay_util"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRateLimitRequests(t *testing.T) {
+	rules := []relay_util.RateLimitRule{{Key: "test:rpm", RPM: 2}}
+
+	status := relay_util.CheckRateLimit(context.Background(), rules)
+	assert.Empty(t, status.Exceeded)
+	assert.Equal(t, 2, status.LimitRequests)
+	assert.Equal(t, 1, status.RemainingRequests)
+
+	status = relay_util.CheckRateLimit(context.Background(), rules)
+	assert.Empty(t, status.Exceeded)
+	assert.Equal(t, 0, status.RemainingRequests)
+
+	status = relay_util.CheckRateLimit(context.Background(), rules)
+	assert.Equal(t, "requests", status.Exceeded)
+}
+
+func TestCheckRateLimitTightestRule(t *testing.T) {
+	rules := []relay_util.RateLimitRule{
+		{Key: "test:tight:token", RPM: 10},
+		{Key: "test:tight:group", RPM: 3},
+	}
+
+	status := relay_util.CheckRateLimit(context.Background(), rules)
+	assert.Empty(t, status.Exceeded)
+	assert.Equal(t, 3, status.LimitRequests)
+	assert.Equal(t, 2, status.RemainingRequests)
+}
+
+func TestCheckRateLimitRejectedRequestNotCounted(t *testing.T) {
+	token := relay_util.RateLimitRule{Key: "test:rejected:token", RPM: 10}
+	group := relay_util.RateLimitRule{Key: "test:rejected:group", RPM: 1}
+
+	status := relay_util.CheckRateLimit(context.Background(), []relay_util.RateLimitRule{token, group})
+	assert.Empty(t, status.Exceeded)
+
+	// 分组超限时不累加令牌的请求数
+	for i := 0; i < 3; i++ {
+		status = relay_util.CheckRateLimit(context.Background(), []relay_util.RateLimitRule{token, group})
+		assert.Equal(t, "requests", status.Exceeded)
+	}
+
+	status = relay_util.CheckRateLimit(context.Background(), []relay_util.RateLimitRule{token})
+	assert.Empty(t, status.Exceeded)
+	assert.Equal(t, 8, status.RemainingRequests)
+}
+
+func TestCheckRateLimitConcurrent(t *testing.T) {
+	rules := []relay_util.RateLimitRule{{Key: "test:concurrent", RPM: 10}}
+
+	var passed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if relay_util.CheckRateLimit(context.Background(), rules).Exceeded == "" {
+				passed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(10), passed.Load())
+}
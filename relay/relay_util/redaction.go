@@ -0,0 +1,401 @@
+package relay_util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"one-api/common"
+	"one-api/common/requester"
+	"one-api/model"
+	"one-api/types"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 占位符的最大长度，流式响应中超过该长度的 [ 不会再等待后续内容
+const redactionPlaceholderMaxLen = 64
+
+var redactionEntityPatterns = map[string]*regexp.Regexp{
+	common.RedactionAPIKey: regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_\-]{16,}|\bAKIA[0-9A-Z]{16}\b|\bAIza[0-9A-Za-z_\-]{35}|\bgh[pousr]_[A-Za-z0-9]{36,}`),
+	common.RedactionEmail:  regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	common.RedactionIdCard: regexp.MustCompile(`\b[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`),
+	common.RedactionPhone:  regexp.MustCompile(`(?:\+?86[\-\s]?)?\b1[3-9]\d{9}\b|\+[1-9]\d{0,2}[\-\s]?\d{6,14}\b`),
+}
+
+var redactionPlaceholderRegex = regexp.MustCompile(`\[[A-Z][A-Z0-9_]*_\d+\]`)
+
+type redactionRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// Redactor 将请求中的敏感内容替换为占位符，并在响应中还原
+// 同一个请求中相同的内容使用相同的占位符
+type Redactor struct {
+	rules        []redactionRule
+	restore      bool
+	originals    map[string]string // 占位符 -> 原始内容
+	placeholders map[string]string // 原始内容 -> 占位符
+	counts       map[string]int
+}
+
+// NewRedactor 根据分组和令牌合并后的脱敏设置创建，未开启时返回 nil
+func NewRedactor(c *gin.Context) *Redactor {
+	var tokenRedaction *common.RedactionSetting
+	if value, ok := c.Get("token_setting"); ok {
+		if tokenSetting, ok := value.(*model.TokenSetting); ok {
+			tokenRedaction = tokenSetting.Redaction
+		}
+	}
+	setting := common.MergeRedactionSetting(common.GetGroupRedaction(c.GetString("group")), tokenRedaction)
+
+	if !setting.Enabled() {
+		return nil
+	}
+
+	return NewRedactorWithSetting(setting)
+}
+
+func NewRedactorWithSetting(setting *common.RedactionSetting) *Redactor {
+	redactor := &Redactor{
+		restore:      setting.Restore,
+		originals:    make(map[string]string),
+		placeholders: make(map[string]string),
+		counts:       make(map[string]int),
+	}
+
+	// 按照固定顺序匹配内置类型，避免 API Key 中的数字被识别为手机号
+	for _, entity := range common.RedactionEntities {
+		for _, item := range setting.Entities {
+			if item == entity {
+				redactor.rules = append(redactor.rules, redactionRule{name: entity, re: redactionEntityPatterns[entity]})
+				break
+			}
+		}
+	}
+
+	for _, rule := range setting.Rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			continue
+		}
+		redactor.rules = append(redactor.rules, redactionRule{name: rule.Name, re: re})
+	}
+
+	return redactor
+}
+
+// Redact 替换文本中的敏感内容
+func (r *Redactor) Redact(text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+
+	for _, rule := range r.rules {
+		text = rule.re.ReplaceAllStringFunc(text, func(match string) string {
+			return r.placeholder(rule.name, match)
+		})
+	}
+
+	return text
+}
+
+func (r *Redactor) placeholder(name, value string) string {
+	if placeholder, ok := r.placeholders[value]; ok {
+		return placeholder
+	}
+
+	r.counts[name]++
+	placeholder := fmt.Sprintf("[%s_%d]", strings.ToUpper(name), r.counts[name])
+	r.placeholders[value] = placeholder
+	r.originals[placeholder] = value
+
+	return placeholder
+}
+
+// Redacted 请求中是否有内容被替换
+func (r *Redactor) Redacted() bool {
+	return r != nil && len(r.originals) > 0
+}
+
+// NeedRestore 是否需要在响应中还原占位符
+func (r *Redactor) NeedRestore() bool {
+	return r != nil && r.restore && len(r.originals) > 0
+}
+
+// Restore 将文本中的占位符还原为原始内容
+func (r *Redactor) Restore(text string) string {
+	if !r.NeedRestore() || !strings.Contains(text, "[") {
+		return text
+	}
+
+	return redactionPlaceholderRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if original, ok := r.originals[placeholder]; ok {
+			return original
+		}
+		return placeholder
+	})
+}
+
+// RedactChatMessages 替换消息中的文本内容，图片等其他内容保持不变
+func (r *Redactor) RedactChatMessages(messages []types.ChatCompletionMessage) {
+	if r == nil {
+		return
+	}
+
+	for i := range messages {
+		switch content := messages[i].Content.(type) {
+		case string:
+			messages[i].Content = r.Redact(content)
+		case []any:
+			for _, item := range content {
+				part, ok := item.(map[string]any)
+				if !ok || part["type"] != types.ContentTypeText {
+					continue
+				}
+				if text, ok := part["text"].(string); ok {
+					part["text"] = r.Redact(text)
+				}
+			}
+		}
+	}
+}
+
+// RedactPrompt 替换 completions 请求中的 prompt
+func (r *Redactor) RedactPrompt(prompt any) any {
+	if r == nil {
+		return prompt
+	}
+
+	switch prompt := prompt.(type) {
+	case string:
+		return r.Redact(prompt)
+	case []any:
+		for i, item := range prompt {
+			if text, ok := item.(string); ok {
+				prompt[i] = r.Redact(text)
+			}
+		}
+	}
+
+	return prompt
+}
+
+// RestoreChatResponse 还原非流式聊天响应中的占位符
+func (r *Redactor) RestoreChatResponse(response *types.ChatCompletionResponse) {
+	if !r.NeedRestore() {
+		return
+	}
+
+	for i := range response.Choices {
+		if content, ok := response.Choices[i].Message.Content.(string); ok {
+			response.Choices[i].Message.Content = r.Restore(content)
+		}
+	}
+}
+
+// RestoreCompletionResponse 还原非流式 completions 响应中的占位符
+func (r *Redactor) RestoreCompletionResponse(response *types.CompletionResponse) {
+	if !r.NeedRestore() {
+		return
+	}
+
+	for i := range response.Choices {
+		response.Choices[i].Text = r.Restore(response.Choices[i].Text)
+	}
+}
+
+// redactionBuffer 流式响应中占位符可能被拆分到多个数据块，未完整的部分等待后续内容
+type redactionBuffer struct {
+	redactor *Redactor
+	pending  string
+}
+
+func (b *redactionBuffer) write(text string) string {
+	text = b.pending + text
+	cut := len(text)
+	if index := strings.LastIndex(text, "["); index >= 0 && !strings.Contains(text[index:], "]") && len(text)-index < redactionPlaceholderMaxLen {
+		cut = index
+	}
+
+	b.pending = text[cut:]
+	return b.redactor.Restore(text[:cut])
+}
+
+func (b *redactionBuffer) flush() string {
+	text := b.pending
+	b.pending = ""
+	return b.redactor.Restore(text)
+}
+
+type redactionStream struct {
+	requester.StreamReaderInterface[string]
+	restore func(data string) string
+	flush   func() string
+}
+
+func (s *redactionStream) Recv() (<-chan string, <-chan error) {
+	innerData, innerErr := s.StreamReaderInterface.Recv()
+	dataChan := make(chan string)
+	errChan := make(chan error)
+
+	go func() {
+		for {
+			select {
+			case data := <-innerData:
+				dataChan <- s.restore(data)
+			case err := <-innerErr:
+				if errors.Is(err, io.EOF) {
+					if data := s.flush(); data != "" {
+						dataChan <- data
+					}
+				}
+				errChan <- err
+				return
+			}
+		}
+	}()
+
+	return dataChan, errChan
+}
+
+// RestoreChatStream 还原流式聊天响应中的占位符
+func (r *Redactor) RestoreChatStream(stream requester.StreamReaderInterface[string]) requester.StreamReaderInterface[string] {
+	if !r.NeedRestore() {
+		return stream
+	}
+
+	buffers := make(map[int]*redactionBuffer)
+	var last types.ChatCompletionStreamResponse
+
+	getBuffer := func(index int) *redactionBuffer {
+		if _, ok := buffers[index]; !ok {
+			buffers[index] = &redactionBuffer{redactor: r}
+		}
+		return buffers[index]
+	}
+
+	return &redactionStream{
+		StreamReaderInterface: stream,
+		restore: func(data string) string {
+			var response types.ChatCompletionStreamResponse
+			if err := json.Unmarshal([]byte(data), &response); err != nil {
+				return data
+			}
+			last = response
+
+			changed := false
+			for i := range response.Choices {
+				choice := &response.Choices[i]
+				buffer := getBuffer(choice.Index)
+				content := buffer.write(choice.Delta.Content)
+				if choice.FinishReason != nil {
+					content += buffer.flush()
+				}
+				if content != choice.Delta.Content {
+					choice.Delta.Content = content
+					changed = true
+				}
+			}
+
+			if !changed {
+				return data
+			}
+			return marshalRedactionChunk(response, data)
+		},
+		flush: func() string {
+			response := last
+			response.Choices = nil
+			response.Usage = nil
+			for index, buffer := range buffers {
+				if content := buffer.flush(); content != "" {
+					response.Choices = append(response.Choices, types.ChatCompletionStreamChoice{
+						Index: index,
+						Delta: types.ChatCompletionStreamChoiceDelta{Content: content},
+					})
+				}
+			}
+
+			if len(response.Choices) == 0 {
+				return ""
+			}
+			return marshalRedactionChunk(response, "")
+		},
+	}
+}
+
+// RestoreCompletionStream 还原流式 completions 响应中的占位符
+func (r *Redactor) RestoreCompletionStream(stream requester.StreamReaderInterface[string]) requester.StreamReaderInterface[string] {
+	if !r.NeedRestore() {
+		return stream
+	}
+
+	buffers := make(map[int]*redactionBuffer)
+	var last types.CompletionResponse
+
+	getBuffer := func(index int) *redactionBuffer {
+		if _, ok := buffers[index]; !ok {
+			buffers[index] = &redactionBuffer{redactor: r}
+		}
+		return buffers[index]
+	}
+
+	return &redactionStream{
+		StreamReaderInterface: stream,
+		restore: func(data string) string {
+			var response types.CompletionResponse
+			if err := json.Unmarshal([]byte(data), &response); err != nil {
+				return data
+			}
+			last = response
+
+			changed := false
+			for i := range response.Choices {
+				choice := &response.Choices[i]
+				buffer := getBuffer(choice.Index)
+				text := buffer.write(choice.Text)
+				if choice.FinishReason != "" {
+					text += buffer.flush()
+				}
+				if text != choice.Text {
+					choice.Text = text
+					changed = true
+				}
+			}
+
+			if !changed {
+				return data
+			}
+			return marshalRedactionChunk(response, data)
+		},
+		flush: func() string {
+			response := last
+			response.Choices = nil
+			response.Usage = nil
+			for index, buffer := range buffers {
+				if text := buffer.flush(); text != "" {
+					response.Choices = append(response.Choices, types.CompletionChoice{
+						Index: index,
+						Text:  text,
+					})
+				}
+			}
+
+			if len(response.Choices) == 0 {
+				return ""
+			}
+			return marshalRedactionChunk(response, "")
+		},
+	}
+}
+
+func marshalRedactionChunk(response any, fallback string) string {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fallback
+	}
+	return string(data)
+}
@@ -0,0 +1,121 @@
+package relay_util_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	redactor := relay_util.NewRedactorWithSetting(&common.RedactionSetting{
+		Entities: []string{common.RedactionEmail, common.RedactionPhone, common.RedactionIdCard, common.RedactionAPIKey},
+		Rules:    []common.RedactionRule{{Name: "order", Pattern: `ORD-\d{6}`}},
+		Restore:  true,
+	})
+
+	text := "mail a@example.com or a@example.com, call 13812345678, id 11010519491231002X, key sk-abcdefghijklmnopqrstuvwx, order ORD-123456"
+	redacted := redactor.Redact(text)
+	assert.Equal(t, "mail [EMAIL_1] or [EMAIL_1], call [PHONE_1], id [ID_CARD_1], key [API_KEY_1], order [ORDER_1]", redacted)
+	assert.Equal(t, text, redactor.Restore(redacted))
+
+	// 未开启还原时保留占位符
+	redactor = relay_util.NewRedactorWithSetting(&common.RedactionSetting{Entities: []string{common.RedactionEmail}})
+	redacted = redactor.Redact("a@example.com")
+	assert.False(t, redactor.NeedRestore())
+	assert.Equal(t, redacted, redactor.Restore(redacted))
+}
+
+func TestRedactorChatMessages(t *testing.T) {
+	redactor := relay_util.NewRedactorWithSetting(&common.RedactionSetting{Entities: []string{common.RedactionEmail}})
+	messages := []types.ChatCompletionMessage{
+		{Role: types.ChatMessageRoleSystem, Content: "reply to b@example.com"},
+		{Role: types.ChatMessageRoleUser, Content: []any{
+			map[string]any{"type": "text", "text": "from c@example.com"},
+			map[string]any{"type": "image_url", "image_url": map[string]any{"url": "https://example.com/a@b.png"}},
+		}},
+	}
+
+	redactor.RedactChatMessages(messages)
+	assert.Equal(t, "reply to [EMAIL_1]", messages[0].Content)
+	parts := messages[1].Content.([]any)
+	assert.Equal(t, "from [EMAIL_2]", parts[0].(map[string]any)["text"])
+	assert.Equal(t, "https://example.com/a@b.png", parts[1].(map[string]any)["image_url"].(map[string]any)["url"])
+}
+
+func TestNewRedactorMergesGroupSetting(t *testing.T) {
+	common.GroupRedaction = map[string]*common.RedactionSetting{
+		"default": {Entities: []string{common.RedactionPhone}, Rules: []common.RedactionRule{{Name: "order", Pattern: `ORD-\d{6}`}}},
+	}
+	t.Cleanup(func() {
+		common.GroupRedaction = map[string]*common.RedactionSetting{}
+	})
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("group", "default")
+	c.Set("token_setting", &model.TokenSetting{Redaction: &common.RedactionSetting{Entities: []string{common.RedactionEmail}, Restore: true}})
+
+	// 令牌的脱敏设置不能覆盖分组设置
+	redactor := relay_util.NewRedactor(c)
+	redacted := redactor.Redact("a@example.com 13812345678 ORD-123456")
+	assert.Equal(t, "[EMAIL_1] [PHONE_1] [ORDER_1]", redacted)
+	assert.True(t, redactor.Redacted())
+	assert.True(t, redactor.NeedRestore())
+}
+
+type chunkStream struct {
+	chunks []string
+}
+
+func (s *chunkStream) Recv() (<-chan string, <-chan error) {
+	dataChan := make(chan string)
+	errChan := make(chan error)
+	go func() {
+		for _, chunk := range s.chunks {
+			dataChan <- chunk
+		}
+		errChan <- io.EOF
+	}()
+	return dataChan, errChan
+}
+
+func (s *chunkStream) Close() {}
+
+func TestRedactorRestoreChatStream(t *testing.T) {
+	redactor := relay_util.NewRedactorWithSetting(&common.RedactionSetting{Entities: []string{common.RedactionEmail}, Restore: true})
+	assert.Equal(t, "[EMAIL_1]", redactor.Redact("a@example.com"))
+
+	chunk := func(content string) string {
+		data, _ := json.Marshal(types.ChatCompletionStreamResponse{
+			ID:      "chatcmpl-1",
+			Choices: []types.ChatCompletionStreamChoice{{Delta: types.ChatCompletionStreamChoiceDelta{Content: content}}},
+		})
+		return string(data)
+	}
+
+	// 占位符被拆分到多个数据块
+	stream := redactor.RestoreChatStream(&chunkStream{chunks: []string{chunk("send to [EMA"), chunk("IL_1] now ["), chunk("ok")}})
+
+	content := ""
+	dataChan, errChan := stream.Recv()
+	for done := false; !done; {
+		select {
+		case data := <-dataChan:
+			var response types.ChatCompletionStreamResponse
+			assert.NoError(t, json.Unmarshal([]byte(data), &response))
+			content += response.GetResponseText()
+		case err := <-errChan:
+			assert.ErrorIs(t, err, io.EOF)
+			done = true
+		}
+	}
+
+	assert.Equal(t, "send to a@example.com now [ok", content)
+}
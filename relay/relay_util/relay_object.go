@@ -0,0 +1,240 @@
+package relay_util
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common/config"
+	"one-api/model"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// 只存在于创建渠道中的对象，key 为接口名称，value 为对象 ID 的前缀
+var relayObjectPrefixes = map[string]string{
+	"assistants":    "asst_",
+	"threads":       "thread_",
+	"vector_stores": "vs_",
+	"files":         "file-",
+}
+
+// 接口名称对应的对象类型
+var relayObjectTypes = map[string]string{
+	"assistants":    model.RelayObjectAssistant,
+	"threads":       model.RelayObjectThread,
+	"vector_stores": model.RelayObjectVectorStore,
+	"files":         model.RelayObjectFile,
+}
+
+// 请求体中引用其他对象的字段
+var relayObjectRefKeys = map[string]bool{
+	"assistant_id":     true,
+	"thread_id":        true,
+	"file_id":          true,
+	"file_ids":         true,
+	"input_file_id":    true,
+	"vector_store_id":  true,
+	"vector_store_ids": true,
+}
+
+// 透传对象接口可以使用的渠道类型
+var relayObjectChannelTypes = []int{config.ChannelTypeOpenAI, config.ChannelTypeAzure}
+
+// ParseRelayObjectPath 解析 /v1/threads/thread_xxx/runs 这类路径，返回接口名称和对象 ID
+// 路径不属于上述接口时 collection 为空，请求的是对象列表或者创建对象时 objectID 为空
+func ParseRelayObjectPath(path string) (collection, objectID string) {
+	parts := strings.Split(strings.TrimPrefix(path, "/v1/"), "/")
+	prefix, ok := relayObjectPrefixes[parts[0]]
+	if !ok {
+		return "", ""
+	}
+
+	if len(parts) > 1 && strings.HasPrefix(parts[1], prefix) {
+		objectID = parts[1]
+	}
+
+	return parts[0], objectID
+}
+
+type RelayObjectRef struct {
+	ID     string
+	Object string
+}
+
+type relayObjectResponse struct {
+	ID       string `json:"id"`
+	Object   string `json:"object"`
+	ThreadID string `json:"thread_id"`
+}
+
+// ParseRelayObjects 从响应中找出新创建的对象，同时支持 JSON 和流式响应
+// 使用 /v1/threads/runs 创建的 thread 只会出现在 run 的 thread_id 中
+func ParseRelayObjects(body []byte) []*RelayObjectRef {
+	body = bytes.TrimSpace(body)
+	if json.Valid(body) {
+		return parseRelayObject(body)
+	}
+
+	var refs []*RelayObjectRef
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line, ok := bytes.CutPrefix(scanner.Bytes(), []byte("data:"))
+		if !ok {
+			continue
+		}
+		refs = append(refs, parseRelayObject(bytes.TrimSpace(line))...)
+	}
+
+	return refs
+}
+
+func parseRelayObject(data []byte) []*RelayObjectRef {
+	response := &relayObjectResponse{}
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil
+	}
+
+	switch response.Object {
+	case model.RelayObjectAssistant, model.RelayObjectThread, model.RelayObjectVectorStore, model.RelayObjectFile:
+		if response.ID != "" {
+			return []*RelayObjectRef{{ID: response.ID, Object: response.Object}}
+		}
+	case "thread.run":
+		if response.ThreadID != "" {
+			return []*RelayObjectRef{{ID: response.ThreadID, Object: model.RelayObjectThread}}
+		}
+	}
+
+	return nil
+}
+
+// ParseRelayObjectRefs 找出请求体中引用的对象 ID，例如 assistant_id、tool_resources 中的 vector_store_ids、attachments 中的 file_id
+func ParseRelayObjectRefs(body []byte) []string {
+	var data any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil
+	}
+
+	var ids []string
+	var walk func(value any)
+	walk = func(value any) {
+		switch value := value.(type) {
+		case map[string]any:
+			for key, item := range value {
+				if !relayObjectRefKeys[key] {
+					walk(item)
+					continue
+				}
+				switch item := item.(type) {
+				case string:
+					ids = append(ids, item)
+				case []any:
+					for _, id := range item {
+						if id, ok := id.(string); ok {
+							ids = append(ids, id)
+						}
+					}
+				}
+			}
+		case []any:
+			for _, item := range value {
+				walk(item)
+			}
+		}
+	}
+	walk(data)
+
+	return ids
+}
+
+// SetRelayObjectChannel 确定透传对象请求使用的渠道，出错时返回对应的状态码
+// 请求路径和请求体中引用的已记录对象必须属于当前用户并且位于同一个渠道，请求转发到该渠道；
+// 没有引用已记录的对象时，使用指定的渠道，获取列表时使用用户最近创建对象的渠道，否则从分组中选择一个渠道
+func SetRelayObjectChannel(c *gin.Context) (int, error) {
+	specificChannelId := c.GetInt("specific_channel_id")
+	c.Set("specific_channel_id_ignore", false)
+	c.Set("relay_object_specified", specificChannelId > 0)
+
+	collection, objectID := ParseRelayObjectPath(c.Request.URL.Path)
+	var ids []string
+	if objectID != "" {
+		ids = append(ids, objectID)
+	}
+
+	// 上游不一定按 Content-Type 解析请求体，除了文件上传以外都检查请求体中引用的对象
+	if c.ContentType() == binding.MIMEMultipartPOSTForm {
+		if collection != "files" {
+			return http.StatusUnsupportedMediaType, errors.New("请使用 application/json 格式的请求体")
+		}
+	} else if c.Request.Method != http.MethodGet {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		ids = append(ids, ParseRelayObjectRefs(body)...)
+	}
+
+	objects, err := model.GetRelayObjects(ids)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	channelId := 0
+	for _, id := range ids {
+		object, ok := objects[id]
+		if !ok {
+			continue
+		}
+		if object.UserId != c.GetInt("id") {
+			return http.StatusNotFound, fmt.Errorf("对象 %s 不存在", id)
+		}
+		if channelId > 0 && object.ChannelId != channelId {
+			return http.StatusBadRequest, errors.New("引用的对象不在同一个渠道中")
+		}
+		channelId = object.ChannelId
+	}
+
+	if channelId > 0 {
+		if specificChannelId > 0 && specificChannelId != channelId {
+			return http.StatusBadRequest, errors.New("引用的对象不在指定的渠道中")
+		}
+		c.Set("specific_channel_id", channelId)
+		return 0, nil
+	}
+
+	if specificChannelId > 0 {
+		return 0, nil
+	}
+
+	// 未记录的对象无法确定所在的渠道和创建者
+	if objectID != "" {
+		return http.StatusNotFound, fmt.Errorf("对象 %s 不存在", objectID)
+	}
+
+	if c.Request.Method == http.MethodGet {
+		object, err := model.GetUserLastRelayObject(c.GetInt("id"), relayObjectTypes[collection])
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if object != nil && model.ChannelGroup.GetAvailableChannel(object.ChannelId) != nil {
+			c.Set("specific_channel_id", object.ChannelId)
+			return 0, nil
+		}
+	}
+
+	channel, err := model.ChannelGroup.NextByTypes(c.GetString("group"), relayObjectChannelTypes)
+	if err != nil {
+		return http.StatusServiceUnavailable, fmt.Errorf("当前分组 %s 下没有可用的 OpenAI 渠道", c.GetString("group"))
+	}
+	c.Set("specific_channel_id", channel.Id)
+
+	return 0, nil
+}
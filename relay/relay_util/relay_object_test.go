@@ -0,0 +1,138 @@
+package relay_util_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/config"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestParseRelayObjectPath(t *testing.T) {
+	collection, objectID := relay_util.ParseRelayObjectPath("/v1/threads/thread_abc/runs/run_abc")
+	assert.Equal(t, "threads", collection)
+	assert.Equal(t, "thread_abc", objectID)
+
+	collection, objectID = relay_util.ParseRelayObjectPath("/v1/threads/runs")
+	assert.Equal(t, "threads", collection)
+	assert.Equal(t, "", objectID)
+
+	collection, objectID = relay_util.ParseRelayObjectPath("/v1/assistants")
+	assert.Equal(t, "assistants", collection)
+	assert.Equal(t, "", objectID)
+
+	collection, _ = relay_util.ParseRelayObjectPath("/v1/fine_tuning/jobs")
+	assert.Equal(t, "", collection)
+}
+
+func TestParseRelayObjects(t *testing.T) {
+	refs := relay_util.ParseRelayObjects([]byte(`{"id":"asst_abc","object":"assistant","model":"gpt-4o"}`))
+	assert.Len(t, refs, 1)
+	assert.Equal(t, "asst_abc", refs[0].ID)
+	assert.Equal(t, "assistant", refs[0].Object)
+
+	stream := "event: thread.created\ndata: {\"id\":\"thread_abc\",\"object\":\"thread\"}\n\n" +
+		"event: thread.run.created\ndata: {\"id\":\"run_abc\",\"object\":\"thread.run\",\"thread_id\":\"thread_abc\"}\n\n" +
+		"event: done\ndata: [DONE]\n\n"
+	refs = relay_util.ParseRelayObjects([]byte(stream))
+	assert.Len(t, refs, 2)
+	assert.Equal(t, "thread_abc", refs[0].ID)
+	assert.Equal(t, "thread_abc", refs[1].ID)
+	assert.Equal(t, "thread", refs[1].Object)
+
+	assert.Empty(t, relay_util.ParseRelayObjects([]byte(`{"id":"msg_abc","object":"thread.message"}`)))
+}
+
+func TestParseRelayObjectRefs(t *testing.T) {
+	ids := relay_util.ParseRelayObjectRefs([]byte(`{
+		"assistant_id": "asst_abc",
+		"tool_resources": {"file_search": {"vector_store_ids": ["vs_abc"]}, "code_interpreter": {"file_ids": ["file-abc"]}},
+		"messages": [{"role": "user", "content": "hi", "attachments": [{"file_id": "file-def"}]}]
+	}`))
+	assert.ElementsMatch(t, []string{"asst_abc", "vs_abc", "file-abc", "file-def"}, ids)
+	assert.Empty(t, relay_util.ParseRelayObjectRefs([]byte(`not json`)))
+}
+
+func TestSetRelayObjectChannel(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.Nil(t, err)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	assert.Nil(t, db.AutoMigrate(&model.RelayObject{}))
+	originDB := model.DB
+	model.DB = db
+	defer func() { model.DB = originDB }()
+
+	assert.Nil(t, model.InsertRelayObject("asst_a", model.RelayObjectAssistant, 1, 11))
+	assert.Nil(t, model.InsertRelayObject("vs_a", model.RelayObjectVectorStore, 1, 12))
+	assert.Nil(t, model.InsertRelayObject("vs_b", model.RelayObjectVectorStore, 1, 11))
+	assert.Nil(t, model.InsertRelayObject("asst_other", model.RelayObjectAssistant, 2, 11))
+
+	weight := uint(1)
+	originChannels, originRule := model.ChannelGroup.Channels, model.ChannelGroup.Rule
+	model.ChannelGroup.Channels = map[int]*model.ChannelChoice{
+		11: {Channel: &model.Channel{Id: 11, Type: config.ChannelTypeOpenAI, Key: "sk-c", Weight: &weight}},
+		13: {Channel: &model.Channel{Id: 13, Type: config.ChannelTypeAnthropic, Key: "sk-a", Weight: &weight}},
+		14: {Channel: &model.Channel{Id: 14, Type: config.ChannelTypeOpenAI, Key: "sk-b", Weight: &weight}},
+	}
+	model.ChannelGroup.Rule = map[string]map[string][][]int{
+		"default": {"claude-3-5-sonnet": {{13}}, "gpt-4o": {{14}}},
+	}
+	defer func() {
+		model.ChannelGroup.Channels, model.ChannelGroup.Rule = originChannels, originRule
+	}()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		statusCode int
+		channelId  int
+		// 默认为 application/json
+		contentType string
+	}{
+		{"路径中的对象", http.MethodGet, "/v1/assistants/asst_a", "", 0, 11, ""},
+		{"其他用户的对象", http.MethodGet, "/v1/assistants/asst_other", "", http.StatusNotFound, 0, ""},
+		{"未记录的对象", http.MethodGet, "/v1/assistants/asst_unknown", "", http.StatusNotFound, 0, ""},
+		{"创建时选择分组中的 OpenAI 渠道", http.MethodPost, "/v1/vector_stores", `{"name":"docs"}`, 0, 14, ""},
+		{"请求体引用的对象", http.MethodPost, "/v1/threads", `{"tool_resources":{"file_search":{"vector_store_ids":["vs_a"]}}}`, 0, 12, ""},
+		{"请求体引用其他用户的对象", http.MethodPost, "/v1/threads/runs", `{"assistant_id":"asst_other"}`, http.StatusNotFound, 0, ""},
+		{"引用不同渠道的对象", http.MethodPost, "/v1/threads/runs", `{"assistant_id":"asst_a","tool_resources":{"file_search":{"vector_store_ids":["vs_a"]}}}`, http.StatusBadRequest, 0, ""},
+		{"获取列表时使用最近创建对象的渠道", http.MethodGet, "/v1/vector_stores", "", 0, 11, ""},
+		{"请求体不是 JSON 时同样检查引用的对象", http.MethodPost, "/v1/threads/runs", `{"assistant_id":"asst_other"}`, http.StatusNotFound, 0, "text/plain"},
+		{"对象接口不接受表单", http.MethodPost, "/v1/threads/runs", "assistant_id=asst_other", http.StatusUnsupportedMediaType, 0, "multipart/form-data; boundary=x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				c.Request.Header.Set("Content-Type", tt.contentType)
+			} else if tt.body != "" {
+				c.Request.Header.Set("Content-Type", "application/json")
+			}
+			c.Set("id", 1)
+			c.Set("group", "default")
+
+			statusCode, err := relay_util.SetRelayObjectChannel(c)
+			assert.Equal(t, tt.statusCode, statusCode)
+			assert.Equal(t, tt.statusCode == 0, err == nil)
+			assert.Equal(t, tt.channelId, c.GetInt("specific_channel_id"))
+			if tt.body != "" {
+				// 请求体可以继续转发到上游
+				body, _ := io.ReadAll(c.Request.Body)
+				assert.Equal(t, tt.body, string(body))
+			}
+		})
+	}
+}
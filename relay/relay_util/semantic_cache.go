@@ -0,0 +1,262 @@
+package relay_util
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	providersBase "one-api/providers/base"
+	"one-api/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 每次查找最多比较的向量数量
+const semanticCacheScanLimit = 500
+
+// SetSemanticRequest 设置语义缓存使用的文本，只对最后一条为纯文本用户消息的请求生效
+func (p *ChatCacheProps) SetSemanticRequest(request *types.ChatCompletionRequest) {
+	if !p.needSemanticCache() || len(request.Messages) == 0 {
+		return
+	}
+
+	lastMessage := request.Messages[len(request.Messages)-1]
+	if lastMessage.Role != types.ChatMessageRoleUser {
+		return
+	}
+
+	text, ok := SemanticMessageText(&lastMessage)
+	if !ok || text == "" {
+		return
+	}
+
+	scopeRequest := *request
+	scopeRequest.Messages = request.Messages[:len(request.Messages)-1]
+
+	p.semanticText = text
+	p.semanticScope = semanticScope(p.UserId, p.TokenId, &scopeRequest)
+}
+
+// SemanticMessageText 获取消息中的文本，包含图片等非文本内容时返回 false
+func SemanticMessageText(message *types.ChatCompletionMessage) (string, bool) {
+	if content, ok := message.Content.(string); ok {
+		return content, true
+	}
+
+	text := ""
+	for _, part := range message.ParseContent() {
+		if part.Type != types.ContentTypeText {
+			return "", false
+		}
+		text += part.Text
+	}
+
+	return text, true
+}
+
+func semanticScope(userId, tokenId int, request *types.ChatCompletionRequest) string {
+	hash := md5.Sum([]byte(fmt.Sprintf("%d-%d-%s", userId, tokenId, utils.Marshal(request))))
+	return hex.EncodeToString(hash[:])
+}
+
+func (p *ChatCacheProps) needSemanticCache() bool {
+	return p.needCache() && config.ChatCacheSemanticEnabled && config.ChatCacheEmbeddingModel != "" && p.semanticThreshold > 0
+}
+
+func (p *ChatCacheProps) getSemanticCache() *ChatCacheProps {
+	if !p.needSemanticCache() || p.semanticText == "" {
+		return nil
+	}
+
+	vector, err := getSemanticEmbedding(p.c, p.semanticText)
+	if err != nil {
+		logger.LogError(p.c.Request.Context(), "semantic cache embedding failed: "+err.Error())
+		return nil
+	}
+	p.embedding = vector
+
+	vectors, err := model.GetChatCacheVectors(p.UserId, p.semanticScope, semanticCacheScanLimit)
+	if err != nil {
+		logger.LogError(p.c.Request.Context(), "semantic cache search failed: "+err.Error())
+		return nil
+	}
+
+	bestHash := ""
+	bestScore := p.semanticThreshold
+	for _, item := range vectors {
+		itemVector, err := DecodeVector(item.Vector)
+		if err != nil {
+			continue
+		}
+		if score := CosineSimilarity(vector, itemVector); score >= bestScore {
+			bestHash = item.Hash
+			bestScore = score
+		}
+	}
+
+	if bestHash == "" {
+		return nil
+	}
+
+	cache := p.Driver.Get(bestHash, p.UserId)
+	if cache == nil {
+		return nil
+	}
+	cache.Semantic = true
+	logger.LogInfo(p.c.Request.Context(), fmt.Sprintf("semantic cache hit, similarity %.4f", bestScore))
+
+	return cache
+}
+
+func (p *ChatCacheProps) storeSemanticVector(expire int64) error {
+	if p.embedding == nil || p.semanticScope == "" {
+		return nil
+	}
+
+	vector := &model.ChatCacheVector{
+		Hash:       p.getHash(),
+		UserId:     p.UserId,
+		Scope:      p.semanticScope,
+		Vector:     EncodeVector(p.embedding),
+		Expiration: time.Now().Unix() + expire*60,
+	}
+
+	return vector.Insert()
+}
+
+// getSemanticEmbedding 使用配置的向量模型计算文本的向量，按照向量模型的价格计费
+func getSemanticEmbedding(c *gin.Context, text string) ([]float32, error) {
+	modelName := config.ChatCacheEmbeddingModel
+	request, err := newAuxiliaryRequest(c, config.ChatCacheEmbeddingChannelId, modelName, common.CountTokenInput(text, modelName))
+	if err != nil {
+		return nil, err
+	}
+
+	response, errWithCode := createSemanticEmbedding(request, text)
+	request.finish(errWithCode)
+	if errWithCode != nil {
+		return nil, errors.New(errWithCode.Message)
+	}
+
+	if len(response.Data) == 0 {
+		return nil, errors.New("empty embedding response")
+	}
+
+	return ParseEmbedding(response.Data[0].Embedding)
+}
+
+func createSemanticEmbedding(request *auxiliaryRequest, text string) (*types.EmbeddingResponse, *types.OpenAIErrorWithStatusCode) {
+	embeddingsProvider, ok := request.provider.(providersBase.EmbeddingsInterface)
+	if !ok {
+		return nil, common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+	}
+
+	modelName, err := request.getModelName()
+	if err != nil {
+		return nil, common.ErrorWrapperLocal(err, "model_mapping_error", http.StatusInternalServerError)
+	}
+
+	return embeddingsProvider.CreateEmbeddings(&types.EmbeddingRequest{
+		Model: modelName,
+		Input: text,
+	})
+}
+
+// ParseEmbedding 解析向量接口返回的 float 数组或 base64 字符串，并归一化
+func ParseEmbedding(embedding any) ([]float32, error) {
+	var vector []float32
+
+	switch v := embedding.(type) {
+	case string:
+		data, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, err
+		}
+		vector = bytesToVector(data)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &vector); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(vector) == 0 {
+		return nil, errors.New("empty embedding")
+	}
+
+	return normalizeVector(vector), nil
+}
+
+func normalizeVector(vector []float32) []float32 {
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	norm = math.Sqrt(norm)
+	if norm == 0 {
+		return vector
+	}
+
+	for i := range vector {
+		vector[i] = float32(float64(vector[i]) / norm)
+	}
+	return vector
+}
+
+// CosineSimilarity 计算两个向量的余弦相似度，维度不同时返回 0
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// EncodeVector 将向量按照小端 float32 编码为 base64 字符串
+func EncodeVector(vector []float32) string {
+	data := make([]byte, len(vector)*4)
+	for i, value := range vector {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(value))
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func DecodeVector(encoded string) ([]float32, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return bytesToVector(data), nil
+}
+
+func bytesToVector(data []byte) []float32 {
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return vector
+}
@@ -0,0 +1,57 @@
+package relay_util_test
+
+import (
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEmbedding(t *testing.T) {
+	vector, err := relay_util.ParseEmbedding([]any{3.0, 4.0})
+	assert.NoError(t, err)
+	assert.InDeltaSlice(t, []float32{0.6, 0.8}, vector, 1e-6)
+
+	// base64 格式与浮点数组解析结果一致
+	encoded := relay_util.EncodeVector([]float32{3, 4})
+	vector, err = relay_util.ParseEmbedding(encoded)
+	assert.NoError(t, err)
+	assert.InDeltaSlice(t, []float32{0.6, 0.8}, vector, 1e-6)
+
+	_, err = relay_util.ParseEmbedding([]any{})
+	assert.Error(t, err)
+}
+
+func TestVectorEncoding(t *testing.T) {
+	vector := []float32{0.1, -0.2, 0.3}
+	decoded, err := relay_util.DecodeVector(relay_util.EncodeVector(vector))
+	assert.NoError(t, err)
+	assert.Equal(t, vector, decoded)
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1.0, relay_util.CosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	assert.InDelta(t, 0.0, relay_util.CosineSimilarity([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	assert.Equal(t, 0.0, relay_util.CosineSimilarity([]float32{1, 0}, []float32{1, 0, 0}))
+}
+
+func TestSemanticMessageText(t *testing.T) {
+	text, ok := relay_util.SemanticMessageText(&types.ChatCompletionMessage{Content: "hello"})
+	assert.True(t, ok)
+	assert.Equal(t, "hello", text)
+
+	text, ok = relay_util.SemanticMessageText(&types.ChatCompletionMessage{Content: []any{
+		map[string]any{"type": "text", "text": "hello "},
+		map[string]any{"type": "text", "text": "world"},
+	}})
+	assert.True(t, ok)
+	assert.Equal(t, "hello world", text)
+
+	// 包含图片的消息不使用语义缓存
+	_, ok = relay_util.SemanticMessageText(&types.ChatCompletionMessage{Content: []any{
+		map[string]any{"type": "text", "text": "what is this"},
+		map[string]any{"type": "image_url", "image_url": map[string]any{"url": "https://example.com/a.png"}},
+	}})
+	assert.False(t, ok)
+}
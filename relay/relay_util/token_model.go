@@ -0,0 +1,26 @@
+package relay_util
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CheckTokenModel 检查令牌是否允许调用该模型
+// 用于模型在处理请求时才能确定的接口，例如 Midjourney 的 action 和 Suno 的 mv，认证时无法从请求中获取模型
+func CheckTokenModel(c *gin.Context, modelName string) *types.OpenAIErrorWithStatusCode {
+	setting, ok := c.Get("token_setting")
+	if !ok {
+		return nil
+	}
+	tokenSetting, ok := setting.(*model.TokenSetting)
+	if !ok || tokenSetting.IsModelAllowed(modelName) {
+		return nil
+	}
+
+	return common.StringErrorWrapperLocal(fmt.Sprintf("该令牌无权使用模型 %s", modelName), "model_not_allowed", http.StatusForbidden)
+}
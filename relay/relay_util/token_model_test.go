@@ -0,0 +1,27 @@
+package relay_util_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckTokenModel(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Nil(t, relay_util.CheckTokenModel(c, "mj_imagine"))
+
+	c.Set("token_setting", &model.TokenSetting{Models: []string{"gpt-4o"}})
+	errWithCode := relay_util.CheckTokenModel(c, "mj_imagine")
+	assert.NotNil(t, errWithCode)
+	assert.Equal(t, http.StatusForbidden, errWithCode.StatusCode)
+	assert.Nil(t, relay_util.CheckTokenModel(c, "gpt-4o"))
+
+	c.Set("token_setting", &model.TokenSetting{Models: []string{"mj_*"}, DeniedModels: []string{"mj_blend"}})
+	assert.Nil(t, relay_util.CheckTokenModel(c, "mj_imagine"))
+	assert.NotNil(t, relay_util.CheckTokenModel(c, "mj_blend"))
+}
@@ -28,6 +28,7 @@ type TaskInterface interface {
 	HandleError(err *TaskError)
 	ShouldRetry(err *TaskError) bool
 	GetModelName() string
+	GetOriginalModel() string
 	GetTask() *model.Task
 	SetProvider() *TaskError
 	GetProvider() base.ProviderInterface
@@ -50,6 +51,10 @@ func (t *TaskBase) GetModelName() string {
 	return t.ModelName
 }
 
+func (t *TaskBase) GetOriginalModel() string {
+	return t.OriginalModel
+}
+
 func (t *TaskBase) GetTask() *model.Task {
 	return t.Task
 }
@@ -0,0 +1,261 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/relay"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	batchListDefaultLimit = 20
+	batchListMaxLimit     = 100
+)
+
+func TaskModel2Batch(task *model.Task) *Batch {
+	properties, data, err := parseBatchTask(task)
+	if err != nil {
+		properties, data = &BatchProperties{}, &BatchData{}
+	}
+
+	batch := &Batch{
+		ID:               task.TaskID,
+		Object:           "batch",
+		Endpoint:         task.Action,
+		InputFileID:      properties.InputFileID,
+		CompletionWindow: properties.CompletionWindow,
+		CreatedAt:        task.SubmitTime,
+		ExpiresAt:        task.SubmitTime + batchWindowSeconds,
+		Metadata:         properties.Metadata,
+		RequestCounts: BatchRequestCounts{
+			Total: len(properties.Requests),
+		},
+	}
+
+	for _, result := range data.Results {
+		if result.IsSuccess() {
+			batch.RequestCounts.Completed++
+		} else {
+			batch.RequestCounts.Failed++
+		}
+	}
+
+	if task.StartTime > 0 {
+		batch.InProgressAt = &task.StartTime
+	}
+	if data.OutputFileID != "" {
+		batch.OutputFileID = &data.OutputFileID
+	}
+	if data.ErrorFileID != "" {
+		batch.ErrorFileID = &data.ErrorFileID
+	}
+
+	switch task.Status {
+	case model.TaskStatusQueued:
+		batch.Status = "validating"
+	case model.TaskStatusInProgress:
+		batch.Status = "in_progress"
+	case model.TaskStatusCancelling:
+		batch.Status = "cancelling"
+	case model.TaskStatusCancelled:
+		batch.Status = "cancelled"
+		batch.CancelledAt = &task.FinishTime
+	case model.TaskStatusSuccess:
+		batch.Status = "completed"
+		batch.CompletedAt = &task.FinishTime
+	case model.TaskStatusExpired:
+		batch.Status = "expired"
+		batch.ExpiredAt = &task.FinishTime
+	case model.TaskStatusFailure:
+		batch.Status = "failed"
+		batch.FailedAt = &task.FinishTime
+		batch.Errors = &BatchErrors{
+			Object: "list",
+			Data:   []*BatchError{{Code: "batch_failed", Message: task.FailReason}},
+		}
+	default:
+		batch.Status = "validating"
+	}
+
+	return batch
+}
+
+// RelaySpecifiedChannel 指定渠道时批处理请求转发到上游渠道的 Batch API
+func RelaySpecifiedChannel(c *gin.Context) bool {
+	if c.GetInt("specific_channel_id") <= 0 {
+		return false
+	}
+
+	relay.RelayObjectOnly(c)
+	return true
+}
+
+func getUserBatch(c *gin.Context) *model.Task {
+	task, err := model.GetTaskByTaskId(model.TaskPlatformBatch, c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+
+	if task == nil {
+		common.AbortWithMessage(c, http.StatusNotFound, "批处理任务不存在")
+		return nil
+	}
+
+	return task
+}
+
+func GetBatch(c *gin.Context) {
+	if RelaySpecifiedChannel(c) {
+		return
+	}
+
+	task := getUserBatch(c)
+	if task == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, TaskModel2Batch(task))
+}
+
+func ListBatches(c *gin.Context) {
+	if RelaySpecifiedChannel(c) {
+		return
+	}
+
+	userId := c.GetInt("id")
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 {
+		limit = batchListDefaultLimit
+	}
+	limit = min(limit, batchListMaxLimit)
+
+	var afterId int64
+	if after := c.Query("after"); after != "" {
+		task, err := model.GetTaskByTaskId(model.TaskPlatformBatch, userId, after)
+		if err != nil {
+			common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if task == nil {
+			common.AbortWithMessage(c, http.StatusNotFound, "批处理任务不存在")
+			return
+		}
+		afterId = task.ID
+	}
+
+	tasks, err := model.GetUserTasks(model.TaskPlatformBatch, userId, afterId, limit+1)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	list := &BatchList{
+		Object:  "list",
+		Data:    make([]*Batch, 0, len(tasks)),
+		HasMore: len(tasks) > limit,
+	}
+	if list.HasMore {
+		tasks = tasks[:limit]
+	}
+	for _, task := range tasks {
+		list.Data = append(list.Data, TaskModel2Batch(task))
+	}
+	if len(list.Data) > 0 {
+		list.FirstID = &list.Data[0].ID
+		list.LastID = &list.Data[len(list.Data)-1].ID
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// CancelBatch 取消批处理，已经开始执行的请求会继续完成
+func CancelBatch(c *gin.Context) {
+	if RelaySpecifiedChannel(c) {
+		return
+	}
+
+	task := getUserBatch(c)
+	if task == nil {
+		return
+	}
+
+	if task.Progress == 100 {
+		common.AbortWithMessage(c, http.StatusBadRequest, "批处理任务已结束，无法取消")
+		return
+	}
+
+	_, err := model.UpdateTaskIfStatus(task.ID, []model.TaskStatus{model.TaskStatusQueued, model.TaskStatusInProgress}, map[string]any{
+		"status": model.TaskStatusCancelling,
+	})
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	task = getUserBatch(c)
+	if task == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, TaskModel2Batch(task))
+}
+
+// GetBatchOutput 下载执行成功的结果，格式与 OpenAI 的输出文件相同
+func GetBatchOutput(c *gin.Context) {
+	writeBatchResults(c, true)
+}
+
+// GetBatchErrors 下载执行失败的结果
+func GetBatchErrors(c *gin.Context) {
+	writeBatchResults(c, false)
+}
+
+func writeBatchResults(c *gin.Context, success bool) {
+	if RelaySpecifiedChannel(c) {
+		return
+	}
+
+	task := getUserBatch(c)
+	if task == nil {
+		return
+	}
+
+	_, data, err := parseBatchTask(task)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	content, err := MarshalBatchResults(data.Results, success)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, "application/jsonl", content)
+}
+
+// MarshalBatchResults 将执行成功或失败的结果转换为 JSONL
+func MarshalBatchResults(results []*BatchResult, success bool) ([]byte, error) {
+	var buffer bytes.Buffer
+	for _, result := range results {
+		if result.IsSuccess() != success {
+			continue
+		}
+
+		line, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(line)
+		buffer.WriteByte('\n')
+	}
+
+	return buffer.Bytes(), nil
+}
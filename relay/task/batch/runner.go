@@ -0,0 +1,389 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/storage"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/relay"
+	"one-api/relay/relay_util"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+const (
+	batchConcurrency        = 5                // 单个批处理同时执行的请求数
+	batchCheckpointInterval = 10 * time.Second // 保存进度并检查是否取消的间隔
+)
+
+// 本节点正在执行的批处理
+var runningBatches sync.Map
+
+// UpdateTaskStatus 启动尚未执行的批处理，批处理只在主节点执行，避免重复请求和计费
+func (t *BatchTask) UpdateTaskStatus(ctx context.Context, taskChannelM map[int][]string, taskM map[string]*model.Task) error {
+	if !config.IsMasterNode {
+		return nil
+	}
+
+	for _, task := range taskM {
+		if _, running := runningBatches.LoadOrStore(task.ID, true); running {
+			continue
+		}
+
+		common.SafeGoroutine(func() {
+			defer runningBatches.Delete(task.ID)
+			newBatchRunner(ctx, task).run()
+		})
+	}
+
+	return nil
+}
+
+type batchRunner struct {
+	ctx        context.Context
+	task       *model.Task
+	properties *BatchProperties
+	data       *BatchData
+
+	sync.Mutex
+	cancelled bool
+	expired   bool
+}
+
+func newBatchRunner(ctx context.Context, task *model.Task) *batchRunner {
+	return &batchRunner{
+		ctx:  ctx,
+		task: task,
+	}
+}
+
+func (r *batchRunner) run() {
+	properties, data, err := parseBatchTask(r.task)
+	if err != nil {
+		r.finish(model.TaskStatusFailure, "批处理数据解析失败："+err.Error())
+		return
+	}
+	r.properties, r.data = properties, data
+
+	if r.task.Status == model.TaskStatusCancelling {
+		r.finish(model.TaskStatusCancelled, "")
+		return
+	}
+
+	if r.task.Status == model.TaskStatusQueued {
+		r.task.StartTime = time.Now().Unix()
+		started, err := model.UpdateTaskIfStatus(r.task.ID, []model.TaskStatus{model.TaskStatusQueued}, map[string]any{
+			"status":     model.TaskStatusInProgress,
+			"start_time": r.task.StartTime,
+		})
+		if err != nil {
+			logger.LogError(r.ctx, fmt.Sprintf("batch %s start failed: %s", r.task.TaskID, err.Error()))
+			return
+		}
+		// 开始执行前已被取消
+		if !started {
+			r.finish(model.TaskStatusCancelled, "")
+			return
+		}
+	}
+
+	token, errMessage := r.getToken()
+	if errMessage != "" {
+		r.finish(model.TaskStatusFailure, errMessage)
+		return
+	}
+
+	logger.LogInfo(r.ctx, fmt.Sprintf("batch %s running, %d/%d finished", r.task.TaskID, len(r.data.Results), len(r.properties.Requests)))
+	r.execute(token)
+
+	switch {
+	case r.cancelled:
+		r.finish(model.TaskStatusCancelled, "")
+	case r.expired:
+		r.finish(model.TaskStatusExpired, "批处理未在 24h 内完成")
+	default:
+		r.finish(model.TaskStatusSuccess, "")
+	}
+}
+
+// getToken 重新校验提交批处理时使用的令牌，令牌失效后批处理失败
+func (r *batchRunner) getToken() (*model.Token, string) {
+	token, err := model.GetTokenByIds(r.properties.TokenId, r.task.UserId)
+	if err != nil {
+		return nil, "提交批处理的令牌不存在"
+	}
+
+	if _, err := model.ValidateUserToken(token.Key); err != nil {
+		return nil, "提交批处理的令牌不可用：" + err.Error()
+	}
+
+	userEnabled, err := model.CacheIsUserEnabled(r.task.UserId)
+	if err != nil || !userEnabled {
+		return nil, "用户已被封禁"
+	}
+
+	return token, ""
+}
+
+// execute 执行尚未完成的请求，定期保存进度并检查是否已取消
+func (r *batchRunner) execute(token *model.Token) {
+	finished := make(map[string]bool, len(r.data.Results))
+	for _, result := range r.data.Results {
+		finished[result.CustomID] = true
+	}
+
+	expiresAt := r.task.SubmitTime + batchWindowSeconds
+	requests := make(chan *BatchRequest)
+	var wg sync.WaitGroup
+	for i := 0; i < batchConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for request := range requests {
+				result := r.send(token, request)
+				r.Lock()
+				if result != nil {
+					r.data.Results = append(r.data.Results, result)
+				} else {
+					r.expired = true
+				}
+				r.Unlock()
+			}
+		}()
+	}
+
+	checkpoint := time.Now()
+	for _, request := range r.properties.Requests {
+		if finished[request.CustomID] {
+			continue
+		}
+
+		if time.Since(checkpoint) >= batchCheckpointInterval {
+			checkpoint = time.Now()
+			r.save()
+			if status, err := model.GetTaskStatus(r.task.ID); err == nil && status == model.TaskStatusCancelling {
+				r.cancelled = true
+				break
+			}
+		}
+
+		if time.Now().Unix() >= expiresAt {
+			r.Lock()
+			r.expired = true
+			r.Unlock()
+			break
+		}
+
+		requests <- request
+	}
+	close(requests)
+	wg.Wait()
+}
+
+// send 按照普通请求的流程限速、选择渠道、重试和计费，等待限速时批处理过期返回 nil
+func (r *batchRunner) send(token *model.Token, request *BatchRequest) *BatchResult {
+	requestId := utils.GetTimeString() + utils.GetRandomString(8)
+	result := &BatchResult{
+		ID:       "batch_req_" + utils.GetRandomString(24),
+		CustomID: request.CustomID,
+	}
+
+	ctx := context.WithValue(context.Background(), logger.RequestIdKey, requestId)
+	ctx = context.WithValue(ctx, "requestStartTime", time.Now())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, request.URL, bytes.NewReader(request.Body))
+	if err != nil {
+		result.Error = &BatchError{Code: "invalid_request", Message: err.Error()}
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	group, err := model.CacheGetUserGroup(r.task.UserId)
+	if err != nil {
+		result.Error = &BatchError{Code: "get_user_group_failed", Message: err.Error()}
+		return result
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(logger.RequestIdKey, requestId)
+	c.Set("id", r.task.UserId)
+	c.Set("token_id", token.Id)
+	c.Set("token_name", token.Name)
+	c.Set("token_setting", token.GetSetting())
+	c.Set("group", group)
+	c.Set("batch_request", true)
+
+	if !r.waitRateLimit(c) {
+		return nil
+	}
+
+	func() {
+		defer relay_util.ReleaseChannel(c)
+		defer func() {
+			if err := recover(); err != nil {
+				logger.LogError(ctx, fmt.Sprintf("batch request panic: %v", err))
+				result.Error = &BatchError{Code: "internal_error", Message: "批处理请求执行失败"}
+			}
+		}()
+		relay.Relay(c)
+	}()
+
+	if result.Error != nil {
+		return result
+	}
+
+	body := w.Body.Bytes()
+	if !json.Valid(body) {
+		result.Error = &BatchError{Code: "invalid_response", Message: string(body)}
+		return result
+	}
+
+	result.Response = &BatchResponse{
+		StatusCode: w.Code,
+		RequestID:  requestId,
+		Body:       json.RawMessage(body),
+	}
+
+	return result
+}
+
+// waitRateLimit 遵守令牌、用户和分组的速率限制，超限时等待到下一个窗口，等待时批处理过期返回 false
+func (r *batchRunner) waitRateLimit(c *gin.Context) bool {
+	rules := relay_util.GetRateLimitRules(c)
+	if len(rules) == 0 {
+		return true
+	}
+
+	expiresAt := r.task.SubmitTime + batchWindowSeconds
+	for {
+		status := relay_util.CheckRateLimit(c.Request.Context(), rules)
+		if status.Exceeded == "" {
+			return true
+		}
+
+		wait := status.ResetRequests
+		if status.Exceeded == "tokens" {
+			wait = status.ResetTokens
+		}
+		wait = max(wait, time.Second)
+		if time.Now().Add(wait).Unix() >= expiresAt {
+			return false
+		}
+		time.Sleep(wait)
+	}
+}
+
+func (r *batchRunner) progress() int {
+	total := len(r.properties.Requests)
+	if total == 0 {
+		return 0
+	}
+	// 100 表示任务已结束，执行中的进度最多为 99
+	return min(len(r.data.Results)*100/total, 99)
+}
+
+func (r *batchRunner) marshalData() (datatypes.JSON, error) {
+	r.Lock()
+	defer r.Unlock()
+	data, err := json.Marshal(r.data)
+	return datatypes.JSON(data), err
+}
+
+func (r *batchRunner) save() {
+	data, err := r.marshalData()
+	if err != nil {
+		logger.LogError(r.ctx, fmt.Sprintf("batch %s marshal failed: %s", r.task.TaskID, err.Error()))
+		return
+	}
+
+	r.Lock()
+	progress := r.progress()
+	r.Unlock()
+
+	err = model.TaskBulkUpdateByID([]int64{r.task.ID}, map[string]any{
+		"progress": progress,
+		"data":     data,
+	})
+	if err != nil {
+		logger.LogError(r.ctx, fmt.Sprintf("batch %s save failed: %s", r.task.TaskID, err.Error()))
+	}
+}
+
+func (r *batchRunner) finish(status model.TaskStatus, failReason string) {
+	params := map[string]any{
+		"status":      status,
+		"progress":    100,
+		"fail_reason": failReason,
+		"finish_time": time.Now().Unix(),
+	}
+
+	if r.data != nil {
+		r.saveResultFiles()
+		data, err := r.marshalData()
+		if err != nil {
+			logger.LogError(r.ctx, fmt.Sprintf("batch %s marshal failed: %s", r.task.TaskID, err.Error()))
+		} else {
+			params["data"] = data
+		}
+	}
+
+	if err := model.TaskBulkUpdateByID([]int64{r.task.ID}, params); err != nil {
+		logger.LogError(r.ctx, fmt.Sprintf("batch %s finish failed: %s", r.task.TaskID, err.Error()))
+		return
+	}
+
+	logger.LogInfo(r.ctx, fmt.Sprintf("batch %s finished with status %s", r.task.TaskID, status))
+}
+
+// saveResultFiles 配置了文件存储时，将结果保存为 batch_output 文件，可以通过 /v1/files 下载
+func (r *batchRunner) saveResultFiles() {
+	if !storage.HasFileStorage() {
+		return
+	}
+
+	r.data.OutputFileID = r.saveResultFile("output", true)
+	r.data.ErrorFileID = r.saveResultFile("error", false)
+}
+
+func (r *batchRunner) saveResultFile(name string, success bool) string {
+	content, err := MarshalBatchResults(r.data.Results, success)
+	if err != nil || len(content) == 0 {
+		return ""
+	}
+
+	file, err := model.SaveFile(r.task.UserId, r.task.TaskID+"_"+name+".jsonl", model.FilePurposeBatchOutput, content)
+	if err != nil {
+		logger.LogError(r.ctx, fmt.Sprintf("batch %s save %s file failed: %s", r.task.TaskID, name, err.Error()))
+		return ""
+	}
+
+	return file.FileID
+}
+
+func parseBatchTask(task *model.Task) (*BatchProperties, *BatchData, error) {
+	properties := &BatchProperties{}
+	if err := json.Unmarshal(task.Properties, properties); err != nil {
+		return nil, nil, err
+	}
+
+	data := &BatchData{}
+	if len(task.Data) > 0 {
+		if err := json.Unmarshal(task.Data, data); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return properties, data, nil
+}
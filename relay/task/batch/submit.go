@@ -0,0 +1,280 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common/config"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/relay/task/base"
+	"one-api/types"
+	"strings"
+
+	"gorm.io/datatypes"
+)
+
+// 输入文件单行的最大长度
+const batchMaxLineSize = 10 * 1024 * 1024
+
+type BatchTask struct {
+	base.TaskBase
+	Properties *BatchProperties
+}
+
+func (t *BatchTask) HandleError(err *base.TaskError) {
+	errType := "invalid_request_error"
+	if err.StatusCode >= http.StatusInternalServerError {
+		errType = "one_hub_error"
+	}
+
+	t.C.JSON(err.StatusCode, types.OpenAIErrorResponse{
+		Error: types.OpenAIError{
+			Code:    err.Code,
+			Message: err.Message,
+			Type:    errType,
+		},
+	})
+}
+
+// Init 解析并校验输入文件，支持 input_file_id 引用已上传的文件，或者直接使用 multipart/form-data 上传
+// 批处理中的请求在后台执行，提交时不选择渠道
+func (t *BatchTask) Init() *base.TaskError {
+	request, taskErr := t.parseCreateRequest()
+	if taskErr != nil {
+		return taskErr
+	}
+
+	if !batchEndpoints[request.Endpoint] {
+		return base.StringTaskError(http.StatusBadRequest, "invalid_endpoint", "endpoint 仅支持 /v1/chat/completions、/v1/completions、/v1/embeddings", true)
+	}
+
+	if request.CompletionWindow == "" {
+		request.CompletionWindow = BatchCompletionWindow
+	}
+	if request.CompletionWindow != BatchCompletionWindow {
+		return base.StringTaskError(http.StatusBadRequest, "invalid_completion_window", "completion_window 仅支持 24h", true)
+	}
+
+	content, taskErr := t.readInputFile(request.InputFileID)
+	if taskErr != nil {
+		return taskErr
+	}
+
+	requests, err := ParseBatchRequests(content, request.Endpoint)
+	if err != nil {
+		return base.StringTaskError(http.StatusBadRequest, "invalid_file", err.Error(), true)
+	}
+
+	if err := t.checkModels(requests); err != nil {
+		return base.StringTaskError(http.StatusForbidden, "model_not_allowed", err.Error(), true)
+	}
+
+	t.Properties = &BatchProperties{
+		InputFileID:      request.InputFileID,
+		Endpoint:         request.Endpoint,
+		CompletionWindow: request.CompletionWindow,
+		Metadata:         request.Metadata,
+		TokenId:          t.C.GetInt("token_id"),
+		Requests:         requests,
+	}
+
+	return nil
+}
+
+func (t *BatchTask) parseCreateRequest() (*BatchCreateRequest, *base.TaskError) {
+	request := &BatchCreateRequest{}
+	if !strings.HasPrefix(t.C.ContentType(), "multipart/form-data") {
+		if err := t.C.ShouldBindJSON(request); err != nil {
+			return nil, base.StringTaskError(http.StatusBadRequest, "invalid_request", "请求格式错误", true)
+		}
+		if request.InputFileID == "" {
+			return nil, base.StringTaskError(http.StatusBadRequest, "invalid_request", "缺少 input_file_id", true)
+		}
+		return request, nil
+	}
+
+	request.Endpoint = t.C.PostForm("endpoint")
+	request.CompletionWindow = t.C.PostForm("completion_window")
+	request.InputFileID = t.C.PostForm("input_file_id")
+	if value := t.C.PostForm("metadata"); value != "" {
+		if err := json.Unmarshal([]byte(value), &request.Metadata); err != nil {
+			return nil, base.StringTaskError(http.StatusBadRequest, "invalid_metadata", "metadata 格式错误", true)
+		}
+	}
+
+	return request, nil
+}
+
+// readInputFile 读取输入文件，未指定 input_file_id 时读取上传的文件
+func (t *BatchTask) readInputFile(inputFileID string) (io.Reader, *base.TaskError) {
+	if inputFileID != "" {
+		file, err := model.GetUserFile(t.C.GetInt("id"), inputFileID)
+		if err != nil {
+			return nil, base.StringTaskError(http.StatusInternalServerError, "get_file_failed", err.Error(), true)
+		}
+		if file == nil {
+			return nil, base.StringTaskError(http.StatusNotFound, "file_not_found", "文件不存在", true)
+		}
+		if file.Purpose != model.FilePurposeBatch {
+			return nil, base.StringTaskError(http.StatusBadRequest, "invalid_file", "文件的 purpose 必须为 batch", true)
+		}
+
+		content, err := file.Content()
+		if err != nil {
+			return nil, base.StringTaskError(http.StatusInternalServerError, "get_file_failed", err.Error(), true)
+		}
+		return bytes.NewReader(content), nil
+	}
+
+	fileHeader, err := t.C.FormFile("file")
+	if err != nil {
+		return nil, base.StringTaskError(http.StatusBadRequest, "invalid_file", "请指定 input_file_id 或者使用 multipart/form-data 上传 JSONL 文件", true)
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, base.StringTaskError(http.StatusBadRequest, "invalid_file", err.Error(), true)
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, base.StringTaskError(http.StatusBadRequest, "invalid_file", err.Error(), true)
+	}
+	return bytes.NewReader(content), nil
+}
+
+// checkModels 检查令牌是否允许调用批处理中的模型
+func (t *BatchTask) checkModels(requests []*BatchRequest) error {
+	value, ok := t.C.Get("token_setting")
+	if !ok {
+		return nil
+	}
+	tokenSetting, ok := value.(*model.TokenSetting)
+	if !ok || !tokenSetting.HasModelLimit() {
+		return nil
+	}
+
+	for _, request := range requests {
+		modelName := getRequestModel(request.Body)
+		if !tokenSetting.IsModelAllowed(modelName) {
+			return fmt.Errorf("该令牌无权使用模型 %s", modelName)
+		}
+	}
+
+	return nil
+}
+
+func (t *BatchTask) SetProvider() *base.TaskError {
+	return nil
+}
+
+func (t *BatchTask) ShouldRetry(err *base.TaskError) bool {
+	return false
+}
+
+// Relay 保存批处理任务并返回 batch 对象，之后由任务队列在后台执行
+func (t *BatchTask) Relay() *base.TaskError {
+	properties, err := json.Marshal(t.Properties)
+	if err != nil {
+		return base.StringTaskError(http.StatusInternalServerError, "marshal_failed", err.Error(), true)
+	}
+	data, err := json.Marshal(&BatchData{Results: []*BatchResult{}})
+	if err != nil {
+		return base.StringTaskError(http.StatusInternalServerError, "marshal_failed", err.Error(), true)
+	}
+
+	t.InitTask()
+	t.Task.TaskID = "batch_" + utils.GetRandomString(24)
+	t.Task.Action = t.Properties.Endpoint
+	t.Task.Status = model.TaskStatusQueued
+	t.Task.Properties = datatypes.JSON(properties)
+	t.Task.Data = datatypes.JSON(data)
+
+	if err := t.Task.Insert(); err != nil {
+		return base.StringTaskError(http.StatusInternalServerError, "insert_failed", err.Error(), true)
+	}
+
+	t.C.JSON(http.StatusOK, TaskModel2Batch(t.Task))
+	return nil
+}
+
+// ParseBatchRequests 解析 JSONL 格式的输入文件，所有请求都需要使用同一个接口
+func ParseBatchRequests(reader io.Reader, endpoint string) ([]*BatchRequest, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), batchMaxLineSize)
+
+	requests := make([]*BatchRequest, 0)
+	customIDs := make(map[string]bool)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		request := &BatchRequest{}
+		if err := json.Unmarshal(text, request); err != nil {
+			return nil, fmt.Errorf("第 %d 行不是有效的 JSON", line)
+		}
+		if err := checkBatchRequest(request, endpoint); err != nil {
+			return nil, fmt.Errorf("第 %d 行%s", line, err.Error())
+		}
+		if customIDs[request.CustomID] {
+			return nil, fmt.Errorf("第 %d 行的 custom_id %s 重复", line, request.CustomID)
+		}
+		customIDs[request.CustomID] = true
+
+		requests = append(requests, request)
+		if len(requests) > config.BatchMaxRequests {
+			return nil, fmt.Errorf("单个批处理最多包含 %d 个请求", config.BatchMaxRequests)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(requests) == 0 {
+		return nil, errors.New("文件中没有请求")
+	}
+
+	return requests, nil
+}
+
+func checkBatchRequest(request *BatchRequest, endpoint string) error {
+	if request.CustomID == "" {
+		return errors.New("缺少 custom_id")
+	}
+	if request.Method != http.MethodPost {
+		return errors.New("的 method 只支持 POST")
+	}
+	if request.URL != endpoint {
+		return fmt.Errorf("的 url 与 endpoint %s 不一致", endpoint)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(request.Body, &body); err != nil || body == nil {
+		return errors.New("的 body 不是有效的 JSON 对象")
+	}
+	if modelName, _ := body["model"].(string); modelName == "" {
+		return errors.New("缺少 model")
+	}
+	if stream, _ := body["stream"].(bool); stream {
+		return errors.New("不支持流式请求")
+	}
+
+	return nil
+}
+
+func getRequestModel(body json.RawMessage) string {
+	var request struct {
+		Model string `json:"model"`
+	}
+	_ = json.Unmarshal(body, &request)
+	return strings.TrimSpace(request.Model)
+}
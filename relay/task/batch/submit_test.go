@@ -0,0 +1,69 @@
+package batch_test
+
+import (
+	"encoding/json"
+	"one-api/model"
+	"one-api/relay/task/batch"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBatchRequests(t *testing.T) {
+	input := `{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}}
+
+{"custom_id":"b","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini","messages":[]}}
+`
+	requests, err := batch.ParseBatchRequests(strings.NewReader(input), "/v1/chat/completions")
+	assert.Nil(t, err)
+	assert.Len(t, requests, 2)
+	assert.Equal(t, "b", requests[1].CustomID)
+
+	cases := map[string]string{
+		"重复":     `{"custom_id":"a","method":"POST","url":"/v1/embeddings","body":{"model":"m"}}` + "\n" + `{"custom_id":"a","method":"POST","url":"/v1/embeddings","body":{"model":"m"}}`,
+		"url":    `{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"m"}}`,
+		"流式":     `{"custom_id":"a","method":"POST","url":"/v1/embeddings","body":{"model":"m","stream":true}}`,
+		"model":  `{"custom_id":"a","method":"POST","url":"/v1/embeddings","body":{}}`,
+		"JSON":   `not json`,
+		"没有请求":   "\n\n",
+		"method": `{"custom_id":"a","method":"GET","url":"/v1/embeddings","body":{"model":"m"}}`,
+	}
+	for want, input := range cases {
+		_, err := batch.ParseBatchRequests(strings.NewReader(input), "/v1/embeddings")
+		if assert.Error(t, err, want) {
+			assert.Contains(t, err.Error(), want)
+		}
+	}
+}
+
+func TestMarshalBatchResults(t *testing.T) {
+	results := []*batch.BatchResult{
+		{ID: "1", CustomID: "a", Response: &batch.BatchResponse{StatusCode: 200, Body: json.RawMessage(`{"ok":true}`)}},
+		{ID: "2", CustomID: "b", Response: &batch.BatchResponse{StatusCode: 429, Body: json.RawMessage(`{"error":{}}`)}},
+		{ID: "3", CustomID: "c", Error: &batch.BatchError{Code: "internal_error"}},
+	}
+
+	output, err := batch.MarshalBatchResults(results, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, strings.Count(string(output), "\n"))
+	assert.Contains(t, string(output), `"custom_id":"a"`)
+
+	errors, err := batch.MarshalBatchResults(results, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(errors), "\n"))
+}
+
+func TestTaskModel2BatchStatus(t *testing.T) {
+	task := &model.Task{TaskID: "batch_1", Status: model.TaskStatusExpired, SubmitTime: 100, FinishTime: 200}
+	result := batch.TaskModel2Batch(task)
+	assert.Equal(t, "expired", result.Status)
+	assert.Equal(t, int64(200), *result.ExpiredAt)
+	assert.Nil(t, result.FailedAt)
+
+	task.Status = model.TaskStatusFailure
+	task.FailReason = "提交批处理的令牌不存在"
+	result = batch.TaskModel2Batch(task)
+	assert.Equal(t, "failed", result.Status)
+	assert.Nil(t, result.ExpiredAt)
+}
@@ -0,0 +1,117 @@
+package batch
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	BatchCompletionWindow = "24h"
+	batchWindowSeconds    = 24 * 3600
+)
+
+// 支持批处理的接口
+var batchEndpoints = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	"/v1/embeddings":       true,
+}
+
+// BatchRequest 输入文件中的一行
+type BatchRequest struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// BatchProperties 保存在任务的 properties 中，提交后不再修改
+type BatchProperties struct {
+	InputFileID      string            `json:"input_file_id,omitempty"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+	TokenId          int               `json:"token_id"`
+	Requests         []*BatchRequest   `json:"requests"`
+}
+
+// BatchData 保存在任务的 data 中，记录已经完成的请求
+type BatchData struct {
+	Results      []*BatchResult `json:"results"`
+	OutputFileID string         `json:"output_file_id,omitempty"`
+	ErrorFileID  string         `json:"error_file_id,omitempty"`
+}
+
+// BatchCreateRequest 使用 input_file_id 创建批处理
+type BatchCreateRequest struct {
+	InputFileID      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// BatchResult 输出文件中的一行
+type BatchResult struct {
+	ID       string         `json:"id"`
+	CustomID string         `json:"custom_id"`
+	Response *BatchResponse `json:"response"`
+	Error    *BatchError    `json:"error"`
+}
+
+type BatchResponse struct {
+	StatusCode int             `json:"status_code"`
+	RequestID  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type BatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+func (r *BatchResult) IsSuccess() bool {
+	return r.Error == nil && r.Response != nil && r.Response.StatusCode == http.StatusOK
+}
+
+// Batch 与 OpenAI 的 batch 对象保持一致
+type Batch struct {
+	ID               string             `json:"id"`
+	Object           string             `json:"object"`
+	Endpoint         string             `json:"endpoint"`
+	Errors           *BatchErrors       `json:"errors"`
+	InputFileID      string             `json:"input_file_id"`
+	CompletionWindow string             `json:"completion_window"`
+	Status           string             `json:"status"`
+	OutputFileID     *string            `json:"output_file_id"`
+	ErrorFileID      *string            `json:"error_file_id"`
+	CreatedAt        int64              `json:"created_at"`
+	InProgressAt     *int64             `json:"in_progress_at"`
+	ExpiresAt        int64              `json:"expires_at"`
+	CompletedAt      *int64             `json:"completed_at"`
+	FailedAt         *int64             `json:"failed_at"`
+	ExpiredAt        *int64             `json:"expired_at"`
+	CancelledAt      *int64             `json:"cancelled_at"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	Metadata         map[string]string  `json:"metadata"`
+}
+
+type BatchErrors struct {
+	Object string        `json:"object"`
+	Data   []*BatchError `json:"data"`
+}
+
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+type BatchList struct {
+	Object  string   `json:"object"`
+	Data    []*Batch `json:"data"`
+	FirstID *string  `json:"first_id"`
+	LastID  *string  `json:"last_id"`
+	HasMore bool     `json:"has_more"`
+}
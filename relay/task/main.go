@@ -3,11 +3,13 @@ package task
 import (
 	"fmt"
 	"net/http"
+	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/model"
 	"one-api/relay/relay_util"
 	"one-api/relay/task/base"
+	"one-api/relay/task/batch"
 	"one-api/types"
 	"strings"
 
@@ -29,6 +31,14 @@ func RelayTaskSubmit(c *gin.Context) {
 		return
 	}
 
+	// 任务的模型在解析请求后才能确定
+	if originalModel := taskAdaptor.GetOriginalModel(); originalModel != "" {
+		if errWithCode := relay_util.CheckTokenModel(c, originalModel); errWithCode != nil {
+			taskAdaptor.HandleError(base.OpenAIErrToTaskErr(errWithCode))
+			return
+		}
+	}
+
 	taskErr = taskAdaptor.SetProvider()
 	if taskErr != nil {
 		taskAdaptor.HandleError(taskErr)
@@ -87,11 +97,36 @@ func RelayTaskSubmit(c *gin.Context) {
 
 }
 
+// RelayBatchSubmit 创建批处理任务，请求在后台按照普通请求的流程执行和计费
+func RelayBatchSubmit(c *gin.Context) {
+	if batch.RelaySpecifiedChannel(c) {
+		return
+	}
+
+	taskAdaptor, err := GetTaskAdaptor(GetRelayMode(c), c)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, "adaptor not found")
+		return
+	}
+
+	if taskErr := taskAdaptor.Init(); taskErr != nil {
+		taskAdaptor.HandleError(taskErr)
+		return
+	}
+
+	if taskErr := taskAdaptor.Relay(); taskErr != nil {
+		taskAdaptor.HandleError(taskErr)
+		return
+	}
+
+	ActivateUpdateTaskBulk()
+}
+
 func CompletedTask(quotaInstance *relay_util.Quota, taskAdaptor base.TaskInterface, c *gin.Context) {
 	quotaInstance.Consume(c, &types.Usage{CompletionTokens: 0, PromptTokens: 1, TotalTokens: 1})
 
 	task := taskAdaptor.GetTask()
-	task.Quota = int(quotaInstance.GetInputRatio() * 1000)
+	task.Quota = quotaInstance.GetTimesQuota()
 
 	err := task.Insert()
 	if err != nil {
@@ -107,6 +142,8 @@ func GetRelayMode(c *gin.Context) int {
 	path := c.Request.URL.Path
 	if strings.HasPrefix(path, "/suno") {
 		relayMode = config.RelayModeSuno
+	} else if strings.HasPrefix(path, "/v1/batches") {
+		relayMode = config.RelayModeBatch
 	}
 
 	return relayMode
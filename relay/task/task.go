@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"one-api/common"
+	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/model"
 	"sync"
@@ -11,6 +12,9 @@ import (
 	"time"
 )
 
+// 主节点检查未完成任务的间隔
+const taskPollInterval = 30 * time.Second
+
 var (
 	taskActive int32 = 0
 	lock       sync.Mutex
@@ -22,6 +26,17 @@ func InitTask() {
 		Task()
 	})
 
+	// 批处理只在主节点执行，从节点提交的批处理只能唤醒从节点自己的任务循环，
+	// 主节点需要定期检查，发现未完成的任务后再启动
+	if config.IsMasterNode {
+		common.SafeGoroutine(func() {
+			for {
+				time.Sleep(taskPollInterval)
+				ActivateUpdateTaskBulk()
+			}
+		})
+	}
+
 	ActivateUpdateTaskBulk()
 }
 
@@ -53,6 +53,8 @@ func SetApiRouter(router *gin.Engine) {
 				selfRoute.GET("/payment", controller.GetUserPaymentList)
 				selfRoute.POST("/order", controller.CreateOrder)
 				selfRoute.GET("/order/status", controller.CheckOrderStatus)
+				selfRoute.GET("/subscription", controller.GetSelfSubscription)
+				selfRoute.GET("/subscription/plans", controller.GetUserSubscriptionPlanList)
 			}
 
 			adminRoute := userRoute.Group("/")
@@ -85,6 +87,10 @@ func SetApiRouter(router *gin.Engine) {
 			channelRoute.GET("/models", relay.ListModelsForAdmin)
 			channelRoute.POST("/provider_models_list", controller.GetModelList)
 			channelRoute.GET("/:id", controller.GetChannel)
+			channelRoute.GET("/:id/keys", controller.GetChannelKeys)
+			channelRoute.PUT("/:id/keys/reset", controller.ResetChannelKeys)
+			channelRoute.GET("/circuit_breakers", controller.GetCircuitBreakers)
+			channelRoute.DELETE("/circuit_breakers/:id", controller.ResetCircuitBreaker)
 			channelRoute.GET("/test", controller.TestAllChannels)
 			channelRoute.GET("/test/:id", controller.TestChannel)
 			channelRoute.GET("/update_balance", controller.UpdateAllChannelsBalance)
@@ -131,6 +137,7 @@ func SetApiRouter(router *gin.Engine) {
 		logRoute.GET("/", middleware.AdminAuth(), controller.GetLogsList)
 		logRoute.DELETE("/", middleware.AdminAuth(), controller.DeleteHistoryLogs)
 		logRoute.GET("/stat", middleware.AdminAuth(), controller.GetLogsStat)
+		logRoute.GET("/audit/:request_id", middleware.AdminAuth(), controller.GetAuditLogs)
 		logRoute.GET("/self/stat", middleware.UserAuth(), controller.GetLogsSelfStat)
 		// logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogsList)
@@ -176,6 +183,26 @@ func SetApiRouter(router *gin.Engine) {
 			paymentRoute.DELETE("/:id", controller.DeletePayment)
 		}
 
+		subscriptionRoute := apiRouter.Group("/subscription")
+		subscriptionRoute.Use(middleware.AdminAuth())
+		{
+			subscriptionRoute.GET("/", controller.GetSubscriptionPlanList)
+			subscriptionRoute.GET("/:id", controller.GetSubscriptionPlan)
+			subscriptionRoute.POST("/", controller.AddSubscriptionPlan)
+			subscriptionRoute.PUT("/", controller.UpdateSubscriptionPlan)
+			subscriptionRoute.DELETE("/:id", controller.DeleteSubscriptionPlan)
+		}
+
+		promptTemplateRoute := apiRouter.Group("/prompt_template")
+		promptTemplateRoute.Use(middleware.AdminAuth())
+		{
+			promptTemplateRoute.GET("/", controller.GetPromptTemplateList)
+			promptTemplateRoute.GET("/:id", controller.GetPromptTemplate)
+			promptTemplateRoute.POST("/", controller.AddPromptTemplate)
+			promptTemplateRoute.PUT("/", controller.UpdatePromptTemplate)
+			promptTemplateRoute.DELETE("/:id", controller.DeletePromptTemplate)
+		}
+
 		mjRoute := apiRouter.Group("/mj")
 		mjRoute.GET("/self", middleware.UserAuth(), controller.GetUserMidjourney)
 		mjRoute.GET("/", middleware.AdminAuth(), controller.GetAllMidjourney)
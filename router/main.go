@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"one-api/common/config"
 	"one-api/common/logger"
+	"one-api/common/metrics"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +17,9 @@ func SetRouter(router *gin.Engine, buildFS embed.FS, indexPage []byte) {
 	SetApiRouter(router)
 	SetDashboardRouter(router)
 	SetRelayRouter(router)
+	if viper.GetBool("metrics.enabled") {
+		router.GET("/metrics", metrics.Handler())
+	}
 	frontendBaseUrl := viper.GetString("frontend_base_url")
 	if config.IsMasterNode && frontendBaseUrl != "" {
 		frontendBaseUrl = ""
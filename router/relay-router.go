@@ -5,6 +5,7 @@ import (
 	"one-api/relay"
 	"one-api/relay/midjourney"
 	"one-api/relay/task"
+	"one-api/relay/task/batch"
 	"one-api/relay/task/suno"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +18,7 @@ func SetRelayRouter(router *gin.Engine) {
 	setMJRouter(router)
 	setSunoRouter(router)
 	setClaudeRouter(router)
+	setGeminiRouter(router)
 }
 
 func setOpenAIRouter(router *gin.Engine) {
@@ -27,7 +29,7 @@ func setOpenAIRouter(router *gin.Engine) {
 		modelsRouter.GET("/:model", relay.RetrieveModel)
 	}
 	relayV1Router := router.Group("/v1")
-	relayV1Router.Use(middleware.RelayPanicRecover(), middleware.OpenaiAuth(), middleware.Distribute())
+	relayV1Router.Use(middleware.RelayPanicRecover(), middleware.OpenaiAuth(), middleware.Distribute(), middleware.RelayRateLimit(), middleware.Audit())
 	{
 		relayV1Router.POST("/completions", relay.Relay)
 		relayV1Router.POST("/chat/completions", relay.Relay)
@@ -42,17 +44,35 @@ func setOpenAIRouter(router *gin.Engine) {
 		relayV1Router.POST("/audio/speech", relay.Relay)
 		relayV1Router.POST("/moderations", relay.Relay)
 
+		// 批处理文件保存在网关中，其他文件以及指定渠道的请求转发到上游渠道
+		relayV1Router.POST("/files", relay.UploadFile)
+		relayV1Router.GET("/files", relay.ListFiles)
+		relayV1Router.GET("/files/:id", relay.RetrieveFile)
+		relayV1Router.GET("/files/:id/content", relay.GetFileContent)
+		relayV1Router.DELETE("/files/:id", relay.DeleteFile)
+
+		// 批处理由网关执行，指定渠道时转发到上游渠道
+		relayV1Router.POST("/batches", task.RelayBatchSubmit)
+		relayV1Router.GET("/batches", batch.ListBatches)
+		relayV1Router.GET("/batches/:id", batch.GetBatch)
+		relayV1Router.POST("/batches/:id/cancel", batch.CancelBatch)
+		relayV1Router.GET("/batches/:id/output", batch.GetBatchOutput)
+		relayV1Router.GET("/batches/:id/errors", batch.GetBatchErrors)
+
+		relayObjectRouter := relayV1Router.Group("")
+		relayObjectRouter.Use(middleware.RelayObjectChannel())
+		{
+			relayObjectRouter.Any("/assistants", relay.RelayOnly)
+			relayObjectRouter.Any("/assistants/*any", relay.RelayOnly)
+			relayObjectRouter.Any("/threads", relay.RelayOnly)
+			relayObjectRouter.Any("/threads/*any", relay.RelayOnly)
+			relayObjectRouter.Any("/vector_stores", relay.RelayOnly)
+			relayObjectRouter.Any("/vector_stores/*any", relay.RelayOnly)
+		}
+
 		relayV1Router.Use(middleware.SpecifiedChannel())
 		{
-			relayV1Router.Any("/files", relay.RelayOnly)
-			relayV1Router.Any("/files/*any", relay.RelayOnly)
 			relayV1Router.Any("/fine_tuning/*any", relay.RelayOnly)
-			relayV1Router.Any("/assistants", relay.RelayOnly)
-			relayV1Router.Any("/assistants/*any", relay.RelayOnly)
-			relayV1Router.Any("/threads", relay.RelayOnly)
-			relayV1Router.Any("/threads/*any", relay.RelayOnly)
-			relayV1Router.Any("/batches/*any", relay.RelayOnly)
-			relayV1Router.Any("/vector_stores/*any", relay.RelayOnly)
 			relayV1Router.DELETE("/models/:model", relay.RelayOnly)
 		}
 	}
@@ -71,7 +91,7 @@ func setMJRouter(router *gin.Engine) {
 // Path: router/relay-router.go
 func registerMjRouterGroup(relayMjRouter *gin.RouterGroup) {
 	relayMjRouter.GET("/image/:id", midjourney.RelayMidjourneyImage)
-	relayMjRouter.Use(middleware.MjAuth(), middleware.Distribute())
+	relayMjRouter.Use(middleware.MjAuth(), middleware.Distribute(), middleware.RelayRateLimit(), middleware.Audit())
 	{
 		relayMjRouter.POST("/submit/action", midjourney.RelayMidjourney)
 		relayMjRouter.POST("/submit/shorten", midjourney.RelayMidjourney)
@@ -91,7 +111,7 @@ func registerMjRouterGroup(relayMjRouter *gin.RouterGroup) {
 
 func setSunoRouter(router *gin.Engine) {
 	relaySunoRouter := router.Group("/suno")
-	relaySunoRouter.Use(middleware.OpenaiAuth(), middleware.Distribute())
+	relaySunoRouter.Use(middleware.OpenaiAuth(), middleware.Distribute(), middleware.RelayRateLimit(), middleware.Audit())
 	{
 		relaySunoRouter.POST("/submit/:action", task.RelayTaskSubmit)
 		relaySunoRouter.POST("/fetch", suno.GetFetch)
@@ -102,8 +122,17 @@ func setSunoRouter(router *gin.Engine) {
 func setClaudeRouter(router *gin.Engine) {
 	relayClaudeRouter := router.Group("/claude")
 	relayV1Router := relayClaudeRouter.Group("/v1")
-	relayV1Router.Use(middleware.ClaudeAuth(), middleware.Distribute())
+	relayV1Router.Use(middleware.ClaudeAuth(), middleware.Distribute(), middleware.RelayRateLimit(), middleware.Audit())
 	{
 		relayV1Router.POST("/messages", relay.RelaycClaudeOnly)
 	}
 }
+
+func setGeminiRouter(router *gin.Engine) {
+	relayGeminiRouter := router.Group("/gemini")
+	relayV1Router := relayGeminiRouter.Group("/v1beta")
+	relayV1Router.Use(middleware.GeminiAuth(), middleware.Distribute(), middleware.RelayRateLimit(), middleware.Audit())
+	{
+		relayV1Router.POST("/models/:model", relay.RelayGeminiOnly)
+	}
+}
@@ -3,9 +3,32 @@ package types
 import "encoding/json"
 
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens            int                      `json:"prompt_tokens"`
+	CompletionTokens        int                      `json:"completion_tokens"`
+	TotalTokens             int                      `json:"total_tokens"`
+	PromptTokensDetails     *PromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+}
+
+// PromptTokensDetails 输入 token 明细，均已包含在 PromptTokens 中
+type PromptTokensDetails struct {
+	CachedTokens      int `json:"cached_tokens"`
+	CachedWriteTokens int `json:"cached_write_tokens,omitempty"` // 写入缓存的 token，例如 Claude 的 cache_creation_input_tokens
+	AudioTokens       int `json:"audio_tokens"`
+	ImageTokens       int `json:"image_tokens,omitempty"`
+}
+
+// CompletionTokensDetails 输出 token 明细，均已包含在 CompletionTokens 中
+type CompletionTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+	AudioTokens     int `json:"audio_tokens"`
+}
+
+func (u *Usage) GetReasoningTokens() int {
+	if u.CompletionTokensDetails == nil {
+		return 0
+	}
+	return u.CompletionTokensDetails.ReasoningTokens
 }
 
 type OpenAIError struct {
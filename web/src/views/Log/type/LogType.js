@@ -3,7 +3,8 @@ const LOG_TYPE = {
   1: { value: '1', text: '充值', color: 'primary' },
   2: { value: '2', text: '消费', color: 'orange' },
   3: { value: '3', text: '管理', color: 'default' },
-  4: { value: '4', text: '系统', color: 'secondary' }
+  4: { value: '4', text: '系统', color: 'secondary' },
+  5: { value: '5', text: '违规', color: 'error' }
 };
 
 export default LOG_TYPE;
@@ -1,7 +1,8 @@
 const PaymentType = {
   epay: '易支付',
   alipay: '支付宝',
-  wxpay: '微信支付'
+  wxpay: '微信支付',
+  stripe: 'Stripe'
 };
 
 const CurrencyType = {
@@ -153,6 +154,26 @@ const PaymentConfig = {
         }
       ]
     }
+  },
+  stripe: {
+    secret_key: {
+      name: 'Secret Key',
+      description: 'Stripe API 密钥，以 sk_ 开头 详见https://dashboard.stripe.com/apikeys',
+      type: 'text',
+      value: ''
+    },
+    webhook_secret: {
+      name: 'Webhook 签名密钥',
+      description: 'Webhook 签名密钥，以 whsec_ 开头。请在 Stripe 后台添加 Webhook 端点为本网关的回调地址，并订阅 checkout.session.completed 事件',
+      type: 'text',
+      value: ''
+    },
+    api_base: {
+      name: 'API 地址',
+      description: '可选，默认为 https://api.stripe.com',
+      type: 'text',
+      value: ''
+    }
   }
 };
 
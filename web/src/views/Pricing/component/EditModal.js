@@ -44,15 +44,37 @@ const getValidationSchema = (t) =>
     models: Yup.array().min(1, t('pricing_edit.requiredModels'))
   });
 
+// 分项价格，为 0 时按照输入或输出价格计费
+const componentFields = ['cached_input', 'cached_write_input', 'audio_input', 'image_input', 'audio_output', 'reasoning'];
+
 const originInputs = {
   is_edit: false,
   type: 'tokens',
   channel_type: 1,
   input: 0,
   output: 0,
+  cached_input: 0,
+  cached_write_input: 0,
+  audio_input: 0,
+  image_input: 0,
+  audio_output: 0,
+  reasoning: 0,
+  tiers: '',
+  image_prices: '',
   models: []
 };
 
+const parseJSONArray = (text) => {
+  if (!text || text.trim() === '') {
+    return null;
+  }
+  const value = JSON.parse(text);
+  if (!Array.isArray(value)) {
+    throw new Error('value must be an array');
+  }
+  return value;
+};
+
 const EditModal = ({ open, pricesItem, onCancel, onOk, ownedby, noPriceModel }) => {
   const { t } = useTranslation();
   const theme = useTheme();
@@ -62,17 +84,39 @@ const EditModal = ({ open, pricesItem, onCancel, onOk, ownedby, noPriceModel })
   const submit = async (values, { setErrors, setStatus, setSubmitting }) => {
     setSubmitting(true);
     values.models = trims(values.models);
+    let tiers;
+    try {
+      tiers = parseJSONArray(values.tiers);
+    } catch (error) {
+      setSubmitting(false);
+      setErrors({ tiers: t('pricing_edit.tiersErr') });
+      return;
+    }
+    let imagePrices;
+    try {
+      imagePrices = parseJSONArray(values.image_prices);
+    } catch (error) {
+      setSubmitting(false);
+      setErrors({ image_prices: t('pricing_edit.imagePricesErr') });
+      return;
+    }
     try {
+      const price = {
+        model: 'batch',
+        type: values.type,
+        channel_type: values.channel_type,
+        input: values.input,
+        output: values.output,
+        tiers: tiers,
+        image_prices: imagePrices
+      };
+      componentFields.forEach((field) => {
+        price[field] = Number(values[field]) || 0;
+      });
       const res = await API.post(`/api/prices/multiple`, {
         original_models: inputs.models,
         models: values.models,
-        price: {
-          model: 'batch',
-          type: values.type,
-          channel_type: values.channel_type,
-          input: values.input,
-          output: values.output
-        }
+        price: price
       });
       const { success, message } = res.data;
       if (success) {
@@ -106,7 +150,12 @@ const EditModal = ({ open, pricesItem, onCancel, onOk, ownedby, noPriceModel })
 
   useEffect(() => {
     if (pricesItem) {
-      setInputs(pricesItem);
+      setInputs({
+        ...originInputs,
+        ...pricesItem,
+        tiers: pricesItem.tiers ? JSON.stringify(pricesItem.tiers) : '',
+        image_prices: pricesItem.image_prices ? JSON.stringify(pricesItem.image_prices) : ''
+      });
     } else {
       setInputs(originInputs);
     }
@@ -229,6 +278,61 @@ const EditModal = ({ open, pricesItem, onCancel, onOk, ownedby, noPriceModel })
                 )}
               </FormControl>
 
+              {values.type === 'tokens' && (
+                <>
+                  {componentFields.map((field) => (
+                    <FormControl key={field} fullWidth sx={{ ...theme.typography.otherInput }}>
+                      <InputLabel htmlFor={`channel-${field}-label`}>{t(`pricing_edit.${field}`)}</InputLabel>
+                      <OutlinedInput
+                        id={`channel-${field}-label`}
+                        label={t(`pricing_edit.${field}`)}
+                        type="number"
+                        value={values[field]}
+                        name={field}
+                        endAdornment={<InputAdornment position="end">{values[field] > 0 ? ValueFormatter(values[field]) : ''}</InputAdornment>}
+                        onBlur={handleBlur}
+                        onChange={handleChange}
+                      />
+                    </FormControl>
+                  ))}
+                  <FormHelperText>{t('pricing_edit.componentTip')}</FormHelperText>
+
+                  <FormControl fullWidth error={Boolean(errors.tiers)} sx={{ ...theme.typography.otherInput }}>
+                    <TextField
+                      multiline
+                      minRows={2}
+                      id="channel-tiers-label"
+                      label={t('pricing_edit.tiers')}
+                      value={values.tiers}
+                      name="tiers"
+                      error={Boolean(errors.tiers)}
+                      onBlur={handleBlur}
+                      onChange={handleChange}
+                      placeholder='[{"tokens": 128000, "input_ratio": 2, "output_ratio": 2}]'
+                    />
+                    <FormHelperText error={Boolean(errors.tiers)}>{errors.tiers || t('pricing_edit.tiersTip')}</FormHelperText>
+                  </FormControl>
+                </>
+              )}
+
+              <FormControl fullWidth error={Boolean(errors.image_prices)} sx={{ ...theme.typography.otherInput }}>
+                <TextField
+                  multiline
+                  minRows={2}
+                  id="channel-image_prices-label"
+                  label={t('pricing_edit.imagePrices')}
+                  value={values.image_prices}
+                  name="image_prices"
+                  error={Boolean(errors.image_prices)}
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  placeholder='[{"size": "1024x1024", "quality": "hd", "price": 40}]'
+                />
+                <FormHelperText error={Boolean(errors.image_prices)}>
+                  {errors.image_prices || t('pricing_edit.imagePricesTip')}
+                </FormHelperText>
+              </FormControl>
+
               <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
                 <Autocomplete
                   multiple
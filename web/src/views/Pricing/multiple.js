@@ -25,7 +25,20 @@ export default function Multiple({ prices, reloadData, handleOpenModal, ownedby
 
   useEffect(() => {
     const grouped = prices.reduce((acc, item, index) => {
-      const key = `${item.type}-${item.channel_type}-${item.input}-${item.output}`;
+      const key = [
+        item.type,
+        item.channel_type,
+        item.input,
+        item.output,
+        item.cached_input,
+        item.cached_write_input,
+        item.audio_input,
+        item.image_input,
+        item.audio_output,
+        item.reasoning,
+        JSON.stringify(item.tiers || null),
+        JSON.stringify(item.image_prices || null)
+      ].join('-');
 
       if (!acc[key]) {
         acc[key] = {
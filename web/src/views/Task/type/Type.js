@@ -5,5 +5,8 @@ export const STATUS_TYPE = {
   IN_PROGRESS: { value: 'IN_PROGRESS', text: '执行中', color: 'primary' },
   FAILURE: { value: 'FAILURE', text: '失败', color: 'orange' },
   QUEUED: { value: 'QUEUED', text: '排队中', color: 'default' },
+  CANCELLING: { value: 'CANCELLING', text: '取消中', color: 'default' },
+  CANCELLED: { value: 'CANCELLED', text: '已取消', color: 'default' },
+  EXPIRED: { value: 'EXPIRED', text: '已过期', color: 'orange' },
   UNKNOWN: { value: 'UNKNOWN', text: '未知', color: 'default' }
 };